@@ -13,6 +13,18 @@ require (
 
 require github.com/rs/zerolog v1.34.0
 
+require github.com/go-fed/httpsig v1.1.0
+
+require github.com/microcosm-cc/bluemonday v1.0.27
+
+require github.com/yuin/goldmark v1.8.6
+
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
+	golang.org/x/text v0.30.0 // indirect
+)
+
 require (
 	code.superseriousbusiness.org/activity v1.17.0
 	codeberg.org/gruf/go-mempool v0.0.0-20251003110531-b54adae66253 // indirect
@@ -28,7 +40,7 @@ require (
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/natefinch/atomic v1.0.1 // indirect
 	golang.org/x/mod v0.28.0 // indirect
-	golang.org/x/net v0.45.0 // indirect
+	golang.org/x/net v0.45.0
 	golang.org/x/sync v0.17.0 // indirect
 	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/tools v0.37.0 // indirect
@@ -36,6 +36,21 @@ type Configuration struct {
 	// being able to edit and create articles. Both InvitationRequired and AutoPublish cannot be true. Will be removed;
 	// if the wiki does not require an invitation, it will automatically ask for a reason.
 	ApprovalRequired bool
+	// Allowlist, if true, restricts federation to the instances an admin explicitly allowed; the others are
+	// treated as suspended.
+	Allowlist bool
+	// AuthorizedFetch, if true, requires the ActivityPub documents of the wiki, such as its actors, articles and
+	// collections, to be fetched with requests signed by an actor of a server we federate with. The instance
+	// actor is still served to anyone, so that other servers can verify our own signed fetches.
+	AuthorizedFetch bool
+	// PrefetchInterwiki, if true, fetches the articles of other wikis linked to by the interwiki links of a local
+	// article when it is saved, so that our copies of them are ready when its readers follow, or hover over, the
+	// links. Only the articles of known, unsuspended instances are fetched.
+	PrefetchInterwiki bool
+	// AllowPrivateAddresses, if true, lets the wiki fetch from and deliver to servers on loopback, private and
+	// link-local addresses, as when testing several instances on one machine. It must be false in production, or
+	// anyone who can name a server could make the wiki send requests to the services of its own network.
+	AllowPrivateAddresses bool
 	// RsaKeySize specifies the size of the RSA keys to be used by the wiki in signing its outgoing activities.
 	RsaKeySize int
 	// Debug, if true, will make the application log all HTTP requests and other events.
@@ -0,0 +1,150 @@
+package conversions
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+var (
+	ErrNotActor        = errors.New("object is not an actor")
+	ErrMissingProperty = errors.New("missing required property")
+)
+
+// Actor is the set of properties shared by the ActivityStreams actor types, that is, Person, Service, Application,
+// Group and Organization.
+type Actor interface {
+	vocab.Type
+	GetActivityStreamsPreferredUsername() vocab.ActivityStreamsPreferredUsernameProperty
+	GetActivityStreamsName() vocab.ActivityStreamsNameProperty
+	GetActivityStreamsSummary() vocab.ActivityStreamsSummaryProperty
+	GetActivityStreamsUrl() vocab.ActivityStreamsUrlProperty
+	GetActivityStreamsInbox() vocab.ActivityStreamsInboxProperty
+	GetActivityStreamsOutbox() vocab.ActivityStreamsOutboxProperty
+	GetActivityStreamsFollowers() vocab.ActivityStreamsFollowersProperty
+	GetActivityStreamsEndpoints() vocab.ActivityStreamsEndpointsProperty
+	GetActivityStreamsPublished() vocab.ActivityStreamsPublishedProperty
+	GetActivityStreamsUpdated() vocab.ActivityStreamsUpdatedProperty
+	GetW3IDSecurityV1PublicKey() vocab.W3IDSecurityV1PublicKeyProperty
+}
+
+// ActorToUser converts a foreign actor into a user. The actor must have an ID, an inbox and a public key.
+func ActorToUser(t vocab.Type) (user domain.UserFed, err error) {
+	a, ok := t.(Actor)
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrNotActor, t.GetTypeName())
+		return
+	}
+
+	user.ApId = GetId(a)
+	if user.ApId == nil {
+		err = fmt.Errorf("%w: id", ErrMissingProperty)
+		return
+	}
+	user.Domain = user.ApId.Host
+	user.Bot = a.GetTypeName() != "Person"
+
+	if p := a.GetActivityStreamsPreferredUsername(); p != nil && p.IsXMLSchemaString() {
+		user.Username = p.GetXMLSchemaString()
+	}
+
+	if p := a.GetActivityStreamsName(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsXMLSchemaString() {
+				user.Name = it.GetXMLSchemaString()
+				break
+			}
+		}
+	}
+
+	if user.Username == "" {
+		user.Username = user.Name
+	}
+	if user.Username == "" {
+		err = fmt.Errorf("%w: preferredUsername", ErrMissingProperty)
+		return
+	}
+
+	if p := a.GetActivityStreamsSummary(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsXMLSchemaString() {
+				user.Summary = it.GetXMLSchemaString()
+				break
+			}
+		}
+	}
+
+	if p := a.GetActivityStreamsUrl(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsIRI() {
+				user.URL = it.GetIRI()
+				break
+			}
+		}
+	}
+
+	if p := a.GetActivityStreamsInbox(); p != nil {
+		user.Inbox = p.GetIRI()
+	}
+	if user.Inbox == nil {
+		err = fmt.Errorf("%w: inbox", ErrMissingProperty)
+		return
+	}
+
+	if p := a.GetActivityStreamsOutbox(); p != nil {
+		user.Outbox = p.GetIRI()
+	}
+
+	if p := a.GetActivityStreamsFollowers(); p != nil {
+		user.Followers = p.GetIRI()
+	}
+
+	if p := a.GetActivityStreamsEndpoints(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if !it.IsActivityStreamsEndpoints() {
+				continue
+			}
+			if shared := it.Get().GetActivityStreamsSharedInbox(); shared != nil && shared.IsIRI() {
+				user.SharedInbox = shared.GetIRI()
+				break
+			}
+		}
+	}
+
+	if p := a.GetActivityStreamsPublished(); p != nil && p.IsXMLSchemaDateTime() {
+		user.Created = p.Get()
+	}
+
+	if p := a.GetActivityStreamsUpdated(); p != nil && p.IsXMLSchemaDateTime() {
+		user.LastUpdated = p.Get()
+	}
+
+	if p := a.GetW3IDSecurityV1PublicKey(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if !it.IsW3IDSecurityV1PublicKey() {
+				continue
+			}
+			if pem := it.Get().GetW3IDSecurityV1PublicKeyPem(); pem != nil {
+				user.PublicKey = pem.Get()
+				break
+			}
+		}
+	}
+	if user.PublicKey == "" {
+		err = fmt.Errorf("%w: publicKey", ErrMissingProperty)
+	}
+
+	return
+}
+
+// GetId returns the ID of t, or nil if it has none.
+func GetId(t vocab.Type) *url.URL {
+	id := t.GetJSONLDId()
+	if id == nil {
+		return nil
+	}
+	return id.Get()
+}
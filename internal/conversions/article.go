@@ -0,0 +1,292 @@
+package conversions
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// LanguageProperty is the extension property that carries the language of an object. It follows the format
+// used by Lemmy, {"identifier": "en"}, so that the language survives the conversion to and from ActivityStreams.
+const LanguageProperty = "language"
+
+// LicenseProperty is the extension property that carries the name of the license of an article, such as
+// "CC BY-SA".
+const LicenseProperty = "license"
+
+// ForkedFromProperty is the extension property that carries the ID of the article an article was forked from.
+const ForkedFromProperty = "forkedFrom"
+
+// DefaultMediaType is the media type of the content of objects that do not specify one.
+const DefaultMediaType = "text/html"
+
+var ErrUnexpectedType = errors.New("unexpected type")
+
+// ArticleToObject converts an article into an ActivityStreams Article. Its authors are given as IRIs, while the
+// linked files are embedded as attachments.
+func ArticleToObject(a domain.ArticleFed) vocab.ActivityStreamsArticle {
+	o := streams.NewActivityStreamsArticle()
+
+	id := streams.NewJSONLDIdProperty()
+	id.SetIRI(a.ApID)
+	o.SetJSONLDId(id)
+
+	name := streams.NewActivityStreamsNameProperty()
+	name.AppendXMLSchemaString(a.Title)
+	o.SetActivityStreamsName(name)
+
+	content := streams.NewActivityStreamsContentProperty()
+	content.AppendXMLSchemaString(a.Content)
+	o.SetActivityStreamsContent(content)
+
+	if a.Summary != "" {
+		summary := streams.NewActivityStreamsSummaryProperty()
+		summary.AppendXMLSchemaString(a.Summary)
+		o.SetActivityStreamsSummary(summary)
+	}
+
+	if a.MediaType != "" {
+		mediaType := streams.NewActivityStreamsMediaTypeProperty()
+		mediaType.Set(a.MediaType)
+		o.SetActivityStreamsMediaType(mediaType)
+	}
+
+	if a.Url != nil {
+		u := streams.NewActivityStreamsUrlProperty()
+		u.AppendIRI(a.Url)
+		o.SetActivityStreamsUrl(u)
+	}
+
+	if !a.Created.IsZero() {
+		published := streams.NewActivityStreamsPublishedProperty()
+		published.Set(a.Created)
+		o.SetActivityStreamsPublished(published)
+	}
+
+	if !a.LastUpdated.IsZero() {
+		updated := streams.NewActivityStreamsUpdatedProperty()
+		updated.Set(a.LastUpdated)
+		o.SetActivityStreamsUpdated(updated)
+	}
+
+	if len(a.AttributedTo) > 0 {
+		attributedTo := streams.NewActivityStreamsAttributedToProperty()
+		for _, author := range a.AttributedTo {
+			attributedTo.AppendIRI(author)
+		}
+		o.SetActivityStreamsAttributedTo(attributedTo)
+	}
+
+	if len(a.Attachments) > 0 {
+		attachment := streams.NewActivityStreamsAttachmentProperty()
+		for _, f := range a.Attachments {
+			// Documents and Images are always accepted by the property.
+			_ = attachment.AppendType(FileToDocument(f))
+		}
+		o.SetActivityStreamsAttachment(attachment)
+	}
+
+	// Only the home server of an article knows all of its Likes and Announces.
+	if a.Local {
+		likes := streams.NewActivityStreamsLikesProperty()
+		likes.SetActivityStreamsOrderedCollection(OrderedCollection(LikesIRI(a.ApID), a.Likes))
+		o.SetActivityStreamsLikes(likes)
+
+		shares := streams.NewActivityStreamsSharesProperty()
+		shares.SetActivityStreamsOrderedCollection(OrderedCollection(SharesIRI(a.ApID), a.Shares))
+		o.SetActivityStreamsShares(shares)
+	}
+
+	// Local articles are also actors that can be followed. The Article type has none of the properties of actors,
+	// so they are added as extensions.
+	if a.Local && a.PublicKey != "" {
+		props := o.GetUnknownProperties()
+		props["inbox"] = InboxIRI(a.ApID).String()
+		props["outbox"] = OutboxIRI(a.ApID).String()
+		props["followers"] = FollowersIRI(a.ApID).String()
+		props["publicKey"] = map[string]any{
+			"id":           KeyID(a.ApID).String(),
+			"owner":        a.ApID.String(),
+			"publicKeyPem": a.PublicKey,
+		}
+	}
+
+	if a.Language != "" {
+		o.GetUnknownProperties()[LanguageProperty] = map[string]any{"identifier": a.Language}
+	}
+
+	if a.License != "" {
+		o.GetUnknownProperties()[LicenseProperty] = a.License
+	}
+
+	if a.ForkedFrom != nil {
+		o.GetUnknownProperties()[ForkedFromProperty] = a.ForkedFrom.String()
+	}
+
+	return o
+}
+
+// ObjectToArticle converts an ActivityStreams Article into a foreign article. The object must have an ID, a name
+// and content.
+func ObjectToArticle(t vocab.Type) (article domain.ArticleFed, err error) {
+	o, ok := t.(vocab.ActivityStreamsArticle)
+	if !ok {
+		err = fmt.Errorf("%w: expected Article, got %s", ErrUnexpectedType, t.GetTypeName())
+		return
+	}
+
+	article.ApID = GetId(o)
+	if article.ApID == nil {
+		err = fmt.Errorf("%w: id", ErrMissingProperty)
+		return
+	}
+	article.Host = article.ApID.Host
+
+	if p := o.GetActivityStreamsName(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsXMLSchemaString() {
+				article.Title = it.GetXMLSchemaString()
+				break
+			}
+		}
+	}
+	if article.Title == "" {
+		err = fmt.Errorf("%w: name", ErrMissingProperty)
+		return
+	}
+
+	var hasContent bool
+	if p := o.GetActivityStreamsContent(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsXMLSchemaString() {
+				article.Content = it.GetXMLSchemaString()
+				hasContent = true
+				break
+			}
+		}
+	}
+	if !hasContent {
+		err = fmt.Errorf("%w: content", ErrMissingProperty)
+		return
+	}
+
+	if p := o.GetActivityStreamsSummary(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsXMLSchemaString() {
+				article.Summary = it.GetXMLSchemaString()
+				break
+			}
+		}
+	}
+
+	article.MediaType = DefaultMediaType
+	if p := o.GetActivityStreamsMediaType(); p != nil && p.IsRFCRfc2045() {
+		article.MediaType = p.Get()
+	}
+
+	article.Url = firstUrl(o.GetActivityStreamsUrl())
+
+	if p := o.GetActivityStreamsPublished(); p != nil && p.IsXMLSchemaDateTime() {
+		article.Created = p.Get()
+	}
+
+	if p := o.GetActivityStreamsUpdated(); p != nil && p.IsXMLSchemaDateTime() {
+		article.LastUpdated = p.Get()
+	}
+
+	if p := o.GetActivityStreamsAttributedTo(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			var author *url.URL
+			if it.IsIRI() {
+				author = it.GetIRI()
+			} else if it.GetType() != nil {
+				author = GetId(it.GetType())
+			}
+			if author != nil {
+				article.AttributedTo = append(article.AttributedTo, author)
+			}
+		}
+	}
+
+	if p := o.GetActivityStreamsAttachment(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.GetType() == nil {
+				continue
+			}
+			// Attachments that are not files, such as links, are ignored.
+			if file, err := DocumentToFile(it.GetType()); err == nil {
+				article.Attachments = append(article.Attachments, file)
+			}
+		}
+	}
+
+	article.Language = language(o.GetUnknownProperties())
+	article.License, _ = o.GetUnknownProperties()[LicenseProperty].(string)
+	if source, ok := o.GetUnknownProperties()[ForkedFromProperty].(string); ok {
+		// A malformed source is ignored rather than failing the whole article.
+		article.ForkedFrom, _ = url.Parse(source)
+	}
+	return
+}
+
+// firstUrl returns the first URL of the property, either given directly or as the href of a Link.
+func firstUrl(p vocab.ActivityStreamsUrlProperty) *url.URL {
+	if p == nil {
+		return nil
+	}
+
+	for it := p.Begin(); it != p.End(); it = it.Next() {
+		if it.IsIRI() {
+			return it.GetIRI()
+		}
+		if it.IsActivityStreamsLink() {
+			if href := it.GetActivityStreamsLink().GetActivityStreamsHref(); href != nil {
+				return href.Get()
+			}
+		}
+	}
+	return nil
+}
+
+// language reads the language extension property, which may either be a plain language tag or an object with an
+// identifier.
+func language(unknown map[string]any) string {
+	switch l := unknown[LanguageProperty].(type) {
+	case string:
+		return l
+	case map[string]any:
+		id, _ := l["identifier"].(string)
+		return id
+	default:
+		return ""
+	}
+}
+
+// LikesIRI returns the ID of the collection of the Likes of an article.
+func LikesIRI(article *url.URL) *url.URL {
+	return article.JoinPath("likes")
+}
+
+// SharesIRI returns the ID of the collection of the Announces of an article.
+func SharesIRI(article *url.URL) *url.URL {
+	return article.JoinPath("shares")
+}
+
+// InboxIRI returns the inbox of a local article or of the instance actor, whose ID is given.
+func InboxIRI(actor *url.URL) *url.URL {
+	return actor.JoinPath("inbox")
+}
+
+// OutboxIRI returns the outbox of a local article or of the instance actor, whose ID is given.
+func OutboxIRI(actor *url.URL) *url.URL {
+	return actor.JoinPath("outbox")
+}
+
+// FollowersIRI returns the followers collection of a local article or of the instance actor, whose ID is given.
+func FollowersIRI(actor *url.URL) *url.URL {
+	return actor.JoinPath("followers")
+}
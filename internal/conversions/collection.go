@@ -0,0 +1,95 @@
+package conversions
+
+import (
+	"net/url"
+	"strconv"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// CollectionPageSize is the number of items in each page of the collections we serve.
+const CollectionPageSize = 20
+
+// PageIRI returns the ID of the given page of a collection. Pages are numbered from 1.
+func PageIRI(collection *url.URL, page int) *url.URL {
+	u := *collection
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+	return &u
+}
+
+// OrderedCollection returns the root of a paginated collection with the given number of items, which links to
+// its first page.
+func OrderedCollection(id *url.URL, total int64) vocab.ActivityStreamsOrderedCollection {
+	c := streams.NewActivityStreamsOrderedCollection()
+
+	idProp := streams.NewJSONLDIdProperty()
+	idProp.SetIRI(id)
+	c.SetJSONLDId(idProp)
+
+	totalItems := streams.NewActivityStreamsTotalItemsProperty()
+	totalItems.Set(int(total))
+	c.SetActivityStreamsTotalItems(totalItems)
+
+	if total > 0 {
+		first := streams.NewActivityStreamsFirstProperty()
+		first.SetIRI(PageIRI(id, 1))
+		c.SetActivityStreamsFirst(first)
+	}
+
+	return c
+}
+
+// OrderedCollectionPage returns a page of a collection with the given number of items, linking to the previous
+// and next pages when they exist.
+func OrderedCollectionPage(collection *url.URL, page int, total int64, items vocab.ActivityStreamsOrderedItemsProperty) vocab.ActivityStreamsOrderedCollectionPage {
+	p := streams.NewActivityStreamsOrderedCollectionPage()
+
+	id := streams.NewJSONLDIdProperty()
+	id.SetIRI(PageIRI(collection, page))
+	p.SetJSONLDId(id)
+
+	partOf := streams.NewActivityStreamsPartOfProperty()
+	partOf.SetIRI(collection)
+	p.SetActivityStreamsPartOf(partOf)
+
+	totalItems := streams.NewActivityStreamsTotalItemsProperty()
+	totalItems.Set(int(total))
+	p.SetActivityStreamsTotalItems(totalItems)
+
+	if page > 1 {
+		prev := streams.NewActivityStreamsPrevProperty()
+		prev.SetIRI(PageIRI(collection, page-1))
+		p.SetActivityStreamsPrev(prev)
+	}
+
+	if int64(page*CollectionPageSize) < total {
+		next := streams.NewActivityStreamsNextProperty()
+		next.SetIRI(PageIRI(collection, page+1))
+		p.SetActivityStreamsNext(next)
+	}
+
+	p.SetActivityStreamsOrderedItems(items)
+	return p
+}
+
+// IRIItems returns the items of an ordered collection made of references to other objects.
+func IRIItems(iris []*url.URL) vocab.ActivityStreamsOrderedItemsProperty {
+	items := streams.NewActivityStreamsOrderedItemsProperty()
+	for _, iri := range iris {
+		items.AppendIRI(iri)
+	}
+	return items
+}
+
+// RevisionItems returns the items of an outbox made of revisions.
+func RevisionItems(revisions []domain.RevisionFed) vocab.ActivityStreamsOrderedItemsProperty {
+	items := streams.NewActivityStreamsOrderedItemsProperty()
+	for _, r := range revisions {
+		items.AppendType(RevisionToActivity(r))
+	}
+	return items
+}
@@ -0,0 +1,119 @@
+package conversions
+
+import (
+	"fmt"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// CommentToNote converts a comment into a public Note in reply to the comment it answers, or to its article.
+func CommentToNote(c domain.CommentFed) vocab.ActivityStreamsNote {
+	n := streams.NewActivityStreamsNote()
+
+	id := streams.NewJSONLDIdProperty()
+	id.SetIRI(c.ApID)
+	n.SetJSONLDId(id)
+
+	if c.Url != nil {
+		u := streams.NewActivityStreamsUrlProperty()
+		u.AppendIRI(c.Url)
+		n.SetActivityStreamsUrl(u)
+	}
+
+	attributedTo := streams.NewActivityStreamsAttributedToProperty()
+	attributedTo.AppendIRI(c.Author)
+	n.SetActivityStreamsAttributedTo(attributedTo)
+
+	inReplyTo := streams.NewActivityStreamsInReplyToProperty()
+	if c.InReplyTo != nil {
+		inReplyTo.AppendIRI(c.InReplyTo)
+	} else {
+		inReplyTo.AppendIRI(c.Article)
+	}
+	n.SetActivityStreamsInReplyTo(inReplyTo)
+
+	to := streams.NewActivityStreamsToProperty()
+	to.AppendIRI(PublicCollection)
+	n.SetActivityStreamsTo(to)
+
+	content := streams.NewActivityStreamsContentProperty()
+	content.AppendXMLSchemaString(c.Content)
+	n.SetActivityStreamsContent(content)
+
+	if !c.Created.IsZero() {
+		published := streams.NewActivityStreamsPublishedProperty()
+		published.Set(c.Created)
+		n.SetActivityStreamsPublished(published)
+	}
+
+	return n
+}
+
+// NoteToComment converts a Note into a comment. The Note must have an ID, an author, content and the object it
+// replies to, which is returned as InReplyTo: whether it is an article or a comment is up to the caller to find out.
+func NoteToComment(t vocab.Type) (c domain.CommentFed, err error) {
+	n, ok := t.(vocab.ActivityStreamsNote)
+	if !ok {
+		err = fmt.Errorf("%w: expected Note, got %s", ErrUnexpectedType, t.GetTypeName())
+		return
+	}
+
+	c.ApID = GetId(n)
+	if c.ApID == nil {
+		err = fmt.Errorf("%w: id", ErrMissingProperty)
+		return
+	}
+
+	c.Url = firstUrl(n.GetActivityStreamsUrl())
+
+	if p := n.GetActivityStreamsAttributedTo(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsIRI() {
+				c.Author = it.GetIRI()
+				break
+			}
+		}
+	}
+	if c.Author == nil {
+		err = fmt.Errorf("%w: attributedTo", ErrMissingProperty)
+		return
+	}
+
+	if p := n.GetActivityStreamsInReplyTo(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsIRI() {
+				c.InReplyTo = it.GetIRI()
+				break
+			}
+			if it.GetType() != nil {
+				c.InReplyTo = GetId(it.GetType())
+				break
+			}
+		}
+	}
+	if c.InReplyTo == nil {
+		err = fmt.Errorf("%w: inReplyTo", ErrMissingProperty)
+		return
+	}
+
+	if p := n.GetActivityStreamsContent(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsXMLSchemaString() {
+				c.Content = it.GetXMLSchemaString()
+				break
+			}
+		}
+	}
+	if c.Content == "" {
+		err = fmt.Errorf("%w: content", ErrMissingProperty)
+		return
+	}
+
+	if p := n.GetActivityStreamsPublished(); p != nil && p.IsXMLSchemaDateTime() {
+		c.Created = p.Get()
+	}
+
+	return
+}
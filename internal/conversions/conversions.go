@@ -8,8 +8,28 @@ import (
 	"github.com/sidereusnuntius/gowiki/internal/domain"
 )
 
+// actorSetter is the set of setters, shared by the actor types, that UserToActor uses.
+type actorSetter interface {
+	vocab.Type
+	SetActivityStreamsPreferredUsername(vocab.ActivityStreamsPreferredUsernameProperty)
+	SetActivityStreamsName(vocab.ActivityStreamsNameProperty)
+	SetActivityStreamsSummary(vocab.ActivityStreamsSummaryProperty)
+	SetActivityStreamsUrl(vocab.ActivityStreamsUrlProperty)
+	SetActivityStreamsInbox(vocab.ActivityStreamsInboxProperty)
+	SetActivityStreamsOutbox(vocab.ActivityStreamsOutboxProperty)
+	SetActivityStreamsFollowers(vocab.ActivityStreamsFollowersProperty)
+	SetActivityStreamsEndpoints(vocab.ActivityStreamsEndpointsProperty)
+	SetActivityStreamsPublished(vocab.ActivityStreamsPublishedProperty)
+	SetActivityStreamsUpdated(vocab.ActivityStreamsUpdatedProperty)
+	SetW3IDSecurityV1PublicKey(vocab.W3IDSecurityV1PublicKeyProperty)
+}
+
+// UserToActor converts a user into a Person, or into a Service if the user is a bot.
 func UserToActor(u domain.UserFed) vocab.Type {
-	a := streams.NewActivityStreamsPerson()
+	var a actorSetter = streams.NewActivityStreamsPerson()
+	if u.Bot {
+		a = streams.NewActivityStreamsService()
+	}
 
 	id := streams.NewJSONLDIdProperty()
 	id.SetIRI(u.ApId)
@@ -41,13 +61,25 @@ func UserToActor(u domain.UserFed) vocab.Type {
 	inbox.SetIRI(u.Inbox)
 	a.SetActivityStreamsInbox(inbox)
 
-	outbox := streams.NewActivityStreamsOutboxProperty()
-	outbox.SetIRI(u.Outbox)
-	a.SetActivityStreamsOutbox(outbox)
+	if u.Outbox != nil {
+		outbox := streams.NewActivityStreamsOutboxProperty()
+		outbox.SetIRI(u.Outbox)
+		a.SetActivityStreamsOutbox(outbox)
+	}
+
+	if u.Followers != nil {
+		followers := streams.NewActivityStreamsFollowersProperty()
+		followers.SetIRI(u.Followers)
+		a.SetActivityStreamsFollowers(followers)
+	}
 
-	followers := streams.NewActivityStreamsFollowersProperty()
-	followers.SetIRI(u.Followers)
-	a.SetActivityStreamsFollowers(followers)
+	sharedInbox := streams.NewActivityStreamsSharedInboxProperty()
+	sharedInbox.SetIRI(SharedInboxIRI(u.ApId))
+	endpoints := streams.NewActivityStreamsEndpoints()
+	endpoints.SetActivityStreamsSharedInbox(sharedInbox)
+	endpointsProp := streams.NewActivityStreamsEndpointsProperty()
+	endpointsProp.AppendActivityStreamsEndpoints(endpoints)
+	a.SetActivityStreamsEndpoints(endpointsProp)
 
 	created := streams.NewActivityStreamsPublishedProperty()
 	created.Set(u.Created)
@@ -57,22 +89,35 @@ func UserToActor(u domain.UserFed) vocab.Type {
 	a.SetActivityStreamsPublished(created)
 	a.SetActivityStreamsUpdated(updated)
 
-	a.SetW3IDSecurityV1PublicKey(PublicKeyProp(u.ApId, u.PublicKey))
+	// The current key comes first, as it is the one most software reads.
+	keys := PublicKeyProp(u.ApId, u.KeyId, u.PublicKey)
+	if u.PreviousKey != "" {
+		keys.AppendW3IDSecurityV1PublicKey(publicKey(u.ApId, u.PreviousKeyId, u.PreviousKey))
+	}
+	a.SetW3IDSecurityV1PublicKey(keys)
 
 	return a
 }
 
-func PublicKeyProp(owner *url.URL, publicKeyPem string) vocab.W3IDSecurityV1PublicKeyProperty {
+// PublicKeyProp returns the publicKey property of an actor with a single key, whose ID defaults to KeyID(owner)
+// if keyId is nil.
+func PublicKeyProp(owner, keyId *url.URL, publicKeyPem string) vocab.W3IDSecurityV1PublicKeyProperty {
 	keyProp := streams.NewW3IDSecurityV1PublicKeyProperty()
+	keyProp.AppendW3IDSecurityV1PublicKey(publicKey(owner, keyId, publicKeyPem))
+	return keyProp
+}
+
+func publicKey(owner, keyId *url.URL, publicKeyPem string) vocab.W3IDSecurityV1PublicKey {
 	key := streams.NewW3IDSecurityV1PublicKey()
 
 	ownerProp := streams.NewW3IDSecurityV1OwnerProperty()
 	ownerProp.SetIRI(owner)
 
-	// TODO: improve this.
-	keyURI := owner.JoinPath("#main-key")
+	if keyId == nil {
+		keyId = KeyID(owner)
+	}
 	keyURIProp := streams.NewJSONLDIdProperty()
-	keyURIProp.SetIRI(keyURI)
+	keyURIProp.SetIRI(keyId)
 
 	pemProp := streams.NewW3IDSecurityV1PublicKeyPemProperty()
 	pemProp.Set(publicKeyPem)
@@ -81,7 +126,17 @@ func PublicKeyProp(owner *url.URL, publicKeyPem string) vocab.W3IDSecurityV1Publ
 	key.SetW3IDSecurityV1PublicKeyPem(pemProp)
 	key.SetW3IDSecurityV1Owner(ownerProp)
 	key.SetW3IDSecurityV1PublicKeyPem(pemProp)
+	return key
+}
 
-	keyProp.AppendW3IDSecurityV1PublicKey(key)
-	return keyProp
+// SharedInboxIRI returns the inbox shared by all the actors of the wiki that hosts actor.
+func SharedInboxIRI(actor *url.URL) *url.URL {
+	return &url.URL{Scheme: actor.Scheme, Host: actor.Host, Path: "/inbox"}
+}
+
+// KeyID returns the ID of the main public key of the actor with the given ID.
+func KeyID(owner *url.URL) *url.URL {
+	keyID := *owner
+	keyID.Fragment = "main-key"
+	return &keyID
 }
@@ -0,0 +1,122 @@
+package conversions
+
+import (
+	"fmt"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// document is the set of properties of the Document and Image types used for files.
+type document interface {
+	vocab.Type
+	GetActivityStreamsName() vocab.ActivityStreamsNameProperty
+	SetActivityStreamsName(vocab.ActivityStreamsNameProperty)
+	GetActivityStreamsMediaType() vocab.ActivityStreamsMediaTypeProperty
+	SetActivityStreamsMediaType(vocab.ActivityStreamsMediaTypeProperty)
+	GetActivityStreamsUrl() vocab.ActivityStreamsUrlProperty
+	SetActivityStreamsUrl(vocab.ActivityStreamsUrlProperty)
+	GetActivityStreamsAttributedTo() vocab.ActivityStreamsAttributedToProperty
+	SetActivityStreamsAttributedTo(vocab.ActivityStreamsAttributedToProperty)
+	GetActivityStreamsPublished() vocab.ActivityStreamsPublishedProperty
+	SetActivityStreamsPublished(vocab.ActivityStreamsPublishedProperty)
+}
+
+// FileToDocument converts a file into an ActivityStreams Image or Document, according to its type.
+func FileToDocument(f domain.File) vocab.Type {
+	var d document = streams.NewActivityStreamsDocument()
+	if f.Type == domain.ImageType {
+		d = streams.NewActivityStreamsImage()
+	}
+
+	id := streams.NewJSONLDIdProperty()
+	id.SetIRI(f.ApId)
+	d.SetJSONLDId(id)
+
+	if name := f.Name; name != "" || f.Filename != "" {
+		if name == "" {
+			name = f.Filename
+		}
+		p := streams.NewActivityStreamsNameProperty()
+		p.AppendXMLSchemaString(name)
+		d.SetActivityStreamsName(p)
+	}
+
+	mediaType := streams.NewActivityStreamsMediaTypeProperty()
+	mediaType.Set(f.MimeType)
+	d.SetActivityStreamsMediaType(mediaType)
+
+	u := streams.NewActivityStreamsUrlProperty()
+	u.AppendIRI(f.Url)
+	d.SetActivityStreamsUrl(u)
+
+	if f.Uploader != nil {
+		attributedTo := streams.NewActivityStreamsAttributedToProperty()
+		attributedTo.AppendIRI(f.Uploader)
+		d.SetActivityStreamsAttributedTo(attributedTo)
+	}
+
+	if !f.Created.IsZero() {
+		published := streams.NewActivityStreamsPublishedProperty()
+		published.Set(f.Created)
+		d.SetActivityStreamsPublished(published)
+	}
+
+	return d
+}
+
+// DocumentToFile converts an ActivityStreams Document or Image into the metadata of a foreign file. The object
+// must have an ID, a URL and a media type.
+func DocumentToFile(t vocab.Type) (file domain.File, err error) {
+	d, ok := t.(document)
+	if !ok || (t.GetTypeName() != domain.DocumentType && t.GetTypeName() != domain.ImageType) {
+		err = fmt.Errorf("%w: expected Document or Image, got %s", ErrUnexpectedType, t.GetTypeName())
+		return
+	}
+	file.Type = t.GetTypeName()
+
+	file.ApId = GetId(d)
+	if file.ApId == nil {
+		err = fmt.Errorf("%w: id", ErrMissingProperty)
+		return
+	}
+
+	file.Url = firstUrl(d.GetActivityStreamsUrl())
+	if file.Url == nil {
+		err = fmt.Errorf("%w: url", ErrMissingProperty)
+		return
+	}
+
+	if p := d.GetActivityStreamsMediaType(); p != nil && p.IsRFCRfc2045() {
+		file.MimeType = p.Get()
+	}
+	if file.MimeType == "" {
+		err = fmt.Errorf("%w: mediaType", ErrMissingProperty)
+		return
+	}
+
+	if p := d.GetActivityStreamsName(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsXMLSchemaString() {
+				file.Name = it.GetXMLSchemaString()
+				break
+			}
+		}
+	}
+
+	if p := d.GetActivityStreamsAttributedTo(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsIRI() {
+				file.Uploader = it.GetIRI()
+				break
+			}
+		}
+	}
+
+	if p := d.GetActivityStreamsPublished(); p != nil && p.IsXMLSchemaDateTime() {
+		file.Created = p.Get()
+	}
+
+	return
+}
@@ -0,0 +1,147 @@
+package conversions
+
+import (
+	"fmt"
+	"net/url"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// PatchProperty is the extension property of a Create or Update that carries the diff-match-patch patch of a
+// revision.
+const PatchProperty = "patch"
+
+// BasedOnProperty is the extension property of an Update proposing an edit that carries the ID of the revision
+// the patch was made against.
+const BasedOnProperty = "basedOn"
+
+// PublicCollection is the special collection that addresses an activity to everyone.
+var PublicCollection, _ = url.Parse("https://www.w3.org/ns/activitystreams#Public")
+
+// revisionActivity is the set of properties of the Create and Update activities used for revisions.
+type revisionActivity interface {
+	vocab.Type
+	GetActivityStreamsActor() vocab.ActivityStreamsActorProperty
+	SetActivityStreamsActor(vocab.ActivityStreamsActorProperty)
+	GetActivityStreamsObject() vocab.ActivityStreamsObjectProperty
+	SetActivityStreamsObject(vocab.ActivityStreamsObjectProperty)
+	GetActivityStreamsSummary() vocab.ActivityStreamsSummaryProperty
+	SetActivityStreamsSummary(vocab.ActivityStreamsSummaryProperty)
+	GetActivityStreamsPublished() vocab.ActivityStreamsPublishedProperty
+	SetActivityStreamsPublished(vocab.ActivityStreamsPublishedProperty)
+	SetActivityStreamsTo(vocab.ActivityStreamsToProperty)
+	GetUnknownProperties() map[string]interface{}
+}
+
+// RevisionToActivity converts a revision into an activity carrying the revision's patch: the Create of the
+// article for its initial revision, or an Update of it otherwise. The ID of the activity is the ID of the revision,
+// and it is addressed to the public, like the history of the article.
+func RevisionToActivity(r domain.RevisionFed) vocab.Type {
+	var u revisionActivity = streams.NewActivityStreamsUpdate()
+	if r.Initial {
+		u = streams.NewActivityStreamsCreate()
+	}
+
+	id := streams.NewJSONLDIdProperty()
+	id.SetIRI(r.ApID)
+	u.SetJSONLDId(id)
+
+	actor := streams.NewActivityStreamsActorProperty()
+	actor.AppendIRI(r.Author)
+	u.SetActivityStreamsActor(actor)
+
+	object := streams.NewActivityStreamsObjectProperty()
+	object.AppendIRI(r.Article)
+	u.SetActivityStreamsObject(object)
+
+	to := streams.NewActivityStreamsToProperty()
+	to.AppendIRI(PublicCollection)
+	u.SetActivityStreamsTo(to)
+
+	if r.Summary != "" {
+		summary := streams.NewActivityStreamsSummaryProperty()
+		summary.AppendXMLSchemaString(r.Summary)
+		u.SetActivityStreamsSummary(summary)
+	}
+
+	if !r.Created.IsZero() {
+		published := streams.NewActivityStreamsPublishedProperty()
+		published.Set(r.Created)
+		u.SetActivityStreamsPublished(published)
+	}
+
+	u.GetUnknownProperties()[PatchProperty] = r.Diff
+	if r.BasedOn != nil {
+		u.GetUnknownProperties()[BasedOnProperty] = r.BasedOn.String()
+	}
+	return u
+}
+
+// ActivityToRevision converts a Create or Update carrying a patch into a revision. The activity must have an ID
+// and a patch, and its actor and object must be given as IRIs or embedded objects with IDs.
+func ActivityToRevision(t vocab.Type) (r domain.RevisionFed, err error) {
+	u, ok := t.(revisionActivity)
+	if !ok || (t.GetTypeName() != "Create" && t.GetTypeName() != "Update") {
+		err = fmt.Errorf("%w: expected Create or Update, got %s", ErrUnexpectedType, t.GetTypeName())
+		return
+	}
+	r.Initial = t.GetTypeName() == "Create"
+
+	r.ApID = GetId(u)
+	if r.ApID == nil {
+		err = fmt.Errorf("%w: id", ErrMissingProperty)
+		return
+	}
+
+	r.Diff, ok = u.GetUnknownProperties()[PatchProperty].(string)
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrMissingProperty, PatchProperty)
+		return
+	}
+
+	if p := u.GetActivityStreamsActor(); p != nil && p.Len() > 0 {
+		if it := p.Begin(); it.IsIRI() {
+			r.Author = it.GetIRI()
+		} else if it.GetType() != nil {
+			r.Author = GetId(it.GetType())
+		}
+	}
+	if r.Author == nil {
+		err = fmt.Errorf("%w: actor", ErrMissingProperty)
+		return
+	}
+
+	if p := u.GetActivityStreamsObject(); p != nil && p.Len() > 0 {
+		if it := p.Begin(); it.IsIRI() {
+			r.Article = it.GetIRI()
+		} else if it.GetType() != nil {
+			r.Article = GetId(it.GetType())
+		}
+	}
+	if r.Article == nil {
+		err = fmt.Errorf("%w: object", ErrMissingProperty)
+		return
+	}
+
+	if p := u.GetActivityStreamsSummary(); p != nil {
+		for it := p.Begin(); it != p.End(); it = it.Next() {
+			if it.IsXMLSchemaString() {
+				r.Summary = it.GetXMLSchemaString()
+				break
+			}
+		}
+	}
+
+	if p := u.GetActivityStreamsPublished(); p != nil && p.IsXMLSchemaDateTime() {
+		r.Created = p.Get()
+	}
+
+	// A malformed basedOn is ignored, as if the base of the proposal were unknown.
+	if basedOn, ok := u.GetUnknownProperties()[BasedOnProperty].(string); ok {
+		r.BasedOn, _ = url.Parse(basedOn)
+	}
+
+	return
+}
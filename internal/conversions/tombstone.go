@@ -0,0 +1,31 @@
+package conversions
+
+import (
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// TombstoneToObject converts the tombstone of a deleted object into an ActivityStreams Tombstone, which is served
+// at the ID of the object and carried by the Delete that announces its deletion.
+func TombstoneToObject(t domain.Tombstone) vocab.ActivityStreamsTombstone {
+	tombstone := streams.NewActivityStreamsTombstone()
+
+	id := streams.NewJSONLDIdProperty()
+	id.SetIRI(t.ApId)
+	tombstone.SetJSONLDId(id)
+
+	if t.FormerType != "" {
+		formerType := streams.NewActivityStreamsFormerTypeProperty()
+		formerType.AppendXMLSchemaString(t.FormerType)
+		tombstone.SetActivityStreamsFormerType(formerType)
+	}
+
+	if !t.Deleted.IsZero() {
+		deleted := streams.NewActivityStreamsDeletedProperty()
+		deleted.Set(t.Deleted)
+		tombstone.SetActivityStreamsDeleted(deleted)
+	}
+
+	return tombstone
+}
@@ -13,6 +13,7 @@ type Account interface {
 	InsertUser(ctx context.Context, user domain.UserFedInternal, account domain.Account, reason string, invitation string) (err error)
 	UserExists(ctx context.Context, id *url.URL) (exists bool, err error)
 	IsUserTrusted(ctx context.Context, id int64) (bool, error)
+	IsUserAdmin(ctx context.Context, id int64) (bool, error)
 	GetAuthDataByUsername(ctx context.Context, username string) (domain.Account, error)
 	GetAuthDataByEmail(ctx context.Context, email string) (domain.Account, error)
 }
@@ -9,8 +9,64 @@ import (
 
 type Article interface {
 	GetRevisionList(ctx context.Context, title string) ([]domain.Revision, error)
+	// GetForeignRevisionList returns the revisions we know of an article hosted by another server, including the
+	// edits proposed by local users.
+	GetForeignRevisionList(ctx context.Context, title, host string) ([]domain.Revision, error)
 	GetLocalArticle(ctx context.Context, title string) (domain.ArticleCore, error)
-	UpdateArticle(ctx context.Context, prevId, articleId, userId int64, summary, newContent string) (err error)
+	// UpdateArticle stores a new, published revision of a local article and returns its ActivityPub ID.
+	UpdateArticle(ctx context.Context, prevId, articleId, userId int64, summary, newContent string) (revision *url.URL, err error)
 	GetLastRevisionID(ctx context.Context, title string) (int64, *url.URL, int64, error)
-	CreateLocalArticle(ctx context.Context, userId int64, article domain.ArticleFed, initialEdit domain.Revision) (err error)
+	// CreateLocalArticle stores a new local article along with its initial revision, whose ActivityPub ID is
+	// returned.
+	CreateLocalArticle(ctx context.Context, userId int64, article domain.ArticleFed, initialEdit domain.Revision) (revision *url.URL, err error)
+	// ForkArticle stores a new local article forked from another one, whose ID is given by its ForkedFrom field.
+	// The imported history is stored first, attributed to its original authors, followed by the fork revision,
+	// whose ActivityPub ID is returned.
+	ForkArticle(ctx context.Context, userId int64, article domain.ArticleFed, history []domain.RevisionFed, fork domain.Revision) (revision *url.URL, err error)
+	// GetArticleFed returns the article, local or foreign, with the given ActivityPub ID.
+	GetArticleFed(ctx context.Context, id *url.URL) (domain.ArticleFed, error)
+	// GetForeignArticle returns the copy of the article with the given title hosted by another server.
+	GetForeignArticle(ctx context.Context, title, host string) (domain.ArticleFed, error)
+	// UpsertForeignArticle stores a copy of an article hosted by another server, updating it if already known.
+	UpsertForeignArticle(ctx context.Context, article domain.ArticleFed) (id int64, err error)
+	// DeleteForeignArticle removes the copy of a foreign article, along with its revisions.
+	DeleteForeignArticle(ctx context.Context, id *url.URL) error
+	// DeleteLocalArticle removes a local article along with its revisions, comments, reactions, follows and
+	// links, leaving a tombstone in its place.
+	DeleteLocalArticle(ctx context.Context, id *url.URL) error
+	GetRevisionFed(ctx context.Context, id *url.URL) (domain.RevisionFed, error)
+	// ListArticleRevisions returns the published revisions we know of an article, local or foreign, oldest first.
+	ListArticleRevisions(ctx context.Context, article *url.URL) ([]domain.RevisionFed, error)
+	// InsertForeignRevision stores a published revision of a foreign article. Both the article and the author
+	// must already be stored.
+	InsertForeignRevision(ctx context.Context, revision domain.RevisionFed) error
+	DeleteForeignRevision(ctx context.Context, id *url.URL) error
+	// InsertProposal stores an edit of a foreign article proposed by a local user, based on the latest revision we
+	// know of the article, and returns its ActivityPub ID.
+	InsertProposal(ctx context.Context, article *url.URL, userId int64, summary, diff string) (*url.URL, error)
+	// InsertIncomingProposal stores an edit of a local article proposed by a foreign user, who must already be
+	// stored. Proposals that were already received are ignored.
+	InsertIncomingProposal(ctx context.Context, revision domain.RevisionFed) error
+	// ListPendingProposals returns the proposed edits of local articles that have not been reviewed, oldest first.
+	ListPendingProposals(ctx context.Context) ([]domain.Revision, error)
+	// GetPendingProposal returns the ActivityPub ID of a pending proposal of a local article.
+	GetPendingProposal(ctx context.Context, id int64) (*url.URL, error)
+	// AcceptProposal publishes a pending proposal as the latest revision of its article, replacing its patch with
+	// diff and the content of the article with content. The revision is given a local ActivityPub ID, which is
+	// returned.
+	AcceptProposal(ctx context.Context, id, reviewerId int64, diff, content string) (*url.URL, error)
+	// ReviewRevision records the acceptance or rejection of a pending proposal. The reviewer may be nil or
+	// unknown.
+	ReviewRevision(ctx context.Context, id, reviewer *url.URL, accepted bool) error
+	// ListUserRevisions returns the published revisions authored by a user, most recent first.
+	ListUserRevisions(ctx context.Context, author *url.URL, limit, offset int) ([]domain.RevisionFed, error)
+	CountUserRevisions(ctx context.Context, author *url.URL) (int64, error)
+	// ListLocalRevisions returns the published revisions of a local article, or of every local article if article
+	// is nil, most recent first.
+	ListLocalRevisions(ctx context.Context, article *url.URL, limit, offset int) ([]domain.RevisionFed, error)
+	CountLocalRevisions(ctx context.Context, article *url.URL) (int64, error)
+	// ListArticlesWithoutKey returns the IDs of the local articles that have no key pair yet.
+	ListArticlesWithoutKey(ctx context.Context) ([]*url.URL, error)
+	// SetArticleKeys stores the PEM encoded key pair a local article signs its activities with.
+	SetArticleKeys(ctx context.Context, article *url.URL, publicKey, privateKey string) error
 }
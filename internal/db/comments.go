@@ -0,0 +1,24 @@
+package db
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+type Comments interface {
+	// InsertLocalComment stores a comment of a local user on a local article, in reply to the comment with the
+	// given ID, or to the article itself if replyTo is 0. It returns the ActivityPub ID of the comment.
+	InsertLocalComment(ctx context.Context, article *url.URL, userId, replyTo int64, content string) (*url.URL, error)
+	// UpsertForeignComment stores a comment received from another server, replacing the content of the copy we
+	// may already have. Hidden is only used when the comment is first stored, so that updates keep the decision of
+	// moderators. The article and the author must already be stored.
+	UpsertForeignComment(ctx context.Context, comment domain.CommentFed) error
+	GetCommentFed(ctx context.Context, id *url.URL) (domain.CommentFed, error)
+	// ListComments returns the comments on an article as threads, oldest first.
+	ListComments(ctx context.Context, article *url.URL) ([]domain.Comment, error)
+	SetCommentHidden(ctx context.Context, id int64, hidden bool) error
+	// DeleteComment removes a comment; its replies become replies to the comment it replied to.
+	DeleteComment(ctx context.Context, id int64) error
+}
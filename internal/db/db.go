@@ -12,7 +12,14 @@ var (
 type DB interface {
 	Account
 	Article
+	Comments
+	Delivery
 	Fed
+	Follows
+	Instances
+	Links
+	Reactions
+	Tombstones
 	Users
 	Files
 }
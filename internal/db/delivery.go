@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+type Delivery interface {
+	// EnqueueDeliveries schedules the delivery of the activity, sent on behalf of actor, to each of the inboxes.
+	EnqueueDeliveries(ctx context.Context, actor *url.URL, activity []byte, inboxes []*url.URL) error
+	// ClaimDeliveries returns up to limit deliveries that are due, postponing them by lease so that they are not
+	// claimed again while being attempted. Deliveries abandoned by a crash are thus retried once the lease expires.
+	// Deliveries whose actor or inbox cannot be parsed are given up on instead of being returned.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.Delivery, error)
+	DeleteDelivery(ctx context.Context, id int64) error
+	RescheduleDelivery(ctx context.Context, id int64, attempts int, next time.Time, reason string) error
+	// KillDelivery marks a delivery as dead; it will not be attempted again, but is kept for inspection.
+	KillDelivery(ctx context.Context, id int64, attempts int, reason string) error
+	// SharedInboxes maps those of the given inboxes whose foreign owner advertised a shared inbox to it.
+	SharedInboxes(ctx context.Context, inboxes []*url.URL) (map[string]*url.URL, error)
+	// GetSigningKey returns the PEM encoded private key of the local actor with the given ID, along with the ID
+	// of its public key, which is nil if the actor uses the default one.
+	GetSigningKey(ctx context.Context, actor *url.URL) (keyId *url.URL, privateKey string, err error)
+}
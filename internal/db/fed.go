@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"net/url"
+	"time"
 
 	"github.com/sidereusnuntius/gowiki/internal/domain"
 )
@@ -11,6 +12,26 @@ type Fed interface {
 	ActorIdByInbox(ctx context.Context, iri *url.URL) (*url.URL, error)
 	ActorIdByOutbox(ctx context.Context, iri *url.URL) (*url.URL, error)
 	OutboxForInbox(ctx context.Context, inboxIRI *url.URL) (*url.URL, error)
+	// GetUserFed returns the local user with the given ID.
 	GetUserFed(ctx context.Context, id *url.URL) (user domain.UserFed, err error)
+	// GetForeignUserFed returns our copy of the foreign actor with the given ID.
+	GetForeignUserFed(ctx context.Context, id *url.URL) (user domain.UserFed, err error)
+	// RotateUserKey replaces the key pair of a local user with a new one, with the given key ID. The replaced
+	// public key is kept, to be published along with the new one, until graceUntil.
+	RotateUserKey(ctx context.Context, user, keyId *url.URL, publicKey, privateKey string, graceUntil time.Time) error
 	GetInstanceIdOrCreate(ctx context.Context, hostname string) (id int64, err error)
+	// GetPublicKey returns the PEM encoded public key of the actor, local or foreign, with the given ID.
+	GetPublicKey(ctx context.Context, owner *url.URL) (string, error)
+	// UpsertForeignUser stores a foreign actor fetched from its home server, updating it if already known.
+	UpsertForeignUser(ctx context.Context, user domain.UserFed) (id int64, err error)
+	// SetInstanceKey records the public key and inbox of the actor that represents the instance as a whole.
+	SetInstanceKey(ctx context.Context, hostname, publicKey string, inbox *url.URL) error
+	// CreateInstanceActor stores the local bot that represents our own instance, unless it already exists.
+	CreateInstanceActor(ctx context.Context, actor domain.UserFedInternal) error
+	// ObjectExists reports whether a user, article, revision or file with the given ActivityPub ID is stored.
+	ObjectExists(ctx context.Context, id *url.URL) (bool, error)
+	// DeleteForeignActor handles the deletion of a foreign user by their server: their follows and reactions are
+	// removed and their comments hidden. The user is removed if nothing else refers to them; otherwise their
+	// profile is cleared. Either way, a tombstone is left for them.
+	DeleteForeignActor(ctx context.Context, id *url.URL) error
 }
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"net/url"
 
 	"github.com/sidereusnuntius/gowiki/internal/domain"
 )
@@ -10,4 +11,9 @@ type Files interface {
 	Save(ctx context.Context, file domain.File) (id int64, err error)
 	FileExists(ctx context.Context, hash string) (bool, error)
 	GetFile(ctx context.Context, hash string) (domain.File, error)
+	// GetFileFed returns the file, local or foreign, with the given ActivityPub ID.
+	GetFileFed(ctx context.Context, id *url.URL) (domain.File, error)
+	// UpsertForeignFile stores the metadata of a file hosted by another server; its content is not fetched.
+	UpsertForeignFile(ctx context.Context, file domain.File) error
+	DeleteForeignFile(ctx context.Context, id *url.URL) error
 }
\ No newline at end of file
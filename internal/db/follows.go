@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+type Follows interface {
+	// AddFollow records a follow relationship, replacing any previous one between the same actors. The follower
+	// must already be stored.
+	AddFollow(ctx context.Context, follow domain.Follow) error
+	RemoveFollow(ctx context.Context, follower, followee *url.URL) error
+	// ListFollowers returns the IDs of the accepted followers of an actor, most recent first.
+	ListFollowers(ctx context.Context, followee *url.URL, limit, offset int) ([]*url.URL, error)
+	CountFollowers(ctx context.Context, followee *url.URL) (int64, error)
+	// FollowerInboxes returns the inboxes of the foreign actors that follow any of the given actors, without
+	// duplicates.
+	FollowerInboxes(ctx context.Context, followees ...*url.URL) ([]*url.URL, error)
+	// LocalRecipients returns, without duplicates, the inboxes of the local actors an activity addressed to the
+	// given IRIs is meant for: the actors themselves or their followers collections, and the followers of the
+	// foreign actors, or of the followers collections, among them.
+	LocalRecipients(ctx context.Context, addressed []*url.URL) ([]*url.URL, error)
+	// ListFollowing returns the IDs of the actors a user follows, most recent first.
+	ListFollowing(ctx context.Context, follower *url.URL, limit, offset int) ([]*url.URL, error)
+	CountFollowing(ctx context.Context, follower *url.URL) (int64, error)
+}
@@ -23,6 +23,11 @@ func (d *dbImpl) IsUserTrusted(ctx context.Context, id int64) (bool, error) {
 	return trusted, d.HandleError(err)
 }
 
+func (d *dbImpl) IsUserAdmin(ctx context.Context, id int64) (bool, error) {
+	admin, err := d.queries.IsUserAdmin(ctx, id)
+	return admin, d.HandleError(err)
+}
+
 func (d *dbImpl) GetAuthDataByUsername(ctx context.Context, username string) (domain.Account, error) {
 	u, err := d.queries.AuthUserByUsername(ctx, username)
 	if err != nil {
@@ -78,8 +83,8 @@ func (d *dbImpl) InsertUser(ctx context.Context, user domain.UserFedInternal, ac
 		Username:   user.Username,
 		Name:       user.Name,
 		Inbox:      user.Inbox.String(),
-		Outbox:     user.Outbox.String(),
-		Followers:  user.Followers.String(),
+		Outbox:     nullIRI(user.Outbox),
+		Followers:  nullIRI(user.Followers),
 		PublicKey:  user.PublicKey,
 		PrivateKey: user.PrivateKey,
 		Summary: sql.NullString{
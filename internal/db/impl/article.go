@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
 	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
 	"github.com/rs/zerolog/log"
 )
 
@@ -16,25 +19,47 @@ func (d *dbImpl) GetRevisionList(ctx context.Context, title string) ([]domain.Re
 		return nil, d.HandleError(err)
 	}
 
+	return revisionList(list), nil
+}
+
+func (d *dbImpl) GetForeignRevisionList(ctx context.Context, title, host string) ([]domain.Revision, error) {
+	list, err := d.queries.GetForeignRevisionList(ctx, queries.GetForeignRevisionListParams{
+		Title:    title,
+		Hostname: validate.NormalizeHost(host),
+	})
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	rows := make([]queries.GetRevisionListRow, 0, len(list))
+	for _, r := range list {
+		rows = append(rows, queries.GetRevisionListRow(r))
+	}
+	return revisionList(rows), nil
+}
+
+func revisionList(list []queries.GetRevisionListRow) []domain.Revision {
 	edits := make([]domain.Revision, 0, len(list))
 	for _, r := range list {
 		edits = append(edits, domain.Revision{
-			ID:       r.ID,
-			Reviewed: r.Reviewed,
-			Title:    r.Title,
-			Summary:  r.Summary.String,
-			Username: r.Username,
-			Created:  r.Created,
+			ID:        r.ID,
+			Reviewed:  r.Reviewed,
+			Published: r.Published,
+			Proposal:  r.Proposal,
+			Title:     r.Title,
+			Summary:   r.Summary.String,
+			Username:  r.Username,
+			Domain:    r.Domain.String,
+			Created:   r.Created,
 		})
 	}
-
-	return edits, nil
+	return edits
 }
 
-func (d *dbImpl) UpdateArticle(ctx context.Context, prevId, articleId, userId int64, summary, newContent string) (err error) {
+func (d *dbImpl) UpdateArticle(ctx context.Context, prevId, articleId, userId int64, summary, newContent string) (revision *url.URL, err error) {
 	content, err := d.queries.GetArticleContent(ctx, articleId)
 	if err != nil {
-		return d.HandleError(err)
+		return nil, d.HandleError(err)
 	}
 
 	t, err := d.db.Begin()
@@ -54,9 +79,7 @@ func (d *dbImpl) UpdateArticle(ctx context.Context, prevId, articleId, userId in
 	diffs := d.DMP.DiffMain(content, newContent, false)
 	patches := d.DMP.PatchMake(diffs)
 
-	err = tx.InsertRevision(ctx, queries.InsertRevisionParams{
-		//TODO: generate apId. Perhaps use the generated id?
-		//ApID: ,
+	revisionId, err := tx.InsertRevision(ctx, queries.InsertRevisionParams{
 		ArticleID: articleId,
 		UserID:    userId,
 		Summary: sql.NullString{
@@ -73,6 +96,10 @@ func (d *dbImpl) UpdateArticle(ctx context.Context, prevId, articleId, userId in
 		return
 	}
 
+	if revision, err = setRevisionApId(ctx, tx, revisionId); err != nil {
+		return
+	}
+
 	err = tx.UpdateArticle(ctx, queries.UpdateArticleParams{
 		Content: newContent,
 		ID:      articleId,
@@ -80,6 +107,15 @@ func (d *dbImpl) UpdateArticle(ctx context.Context, prevId, articleId, userId in
 	return
 }
 
+// setRevisionApId assigns a local revision its ActivityPub ID, which is derived from the ID of its article.
+func setRevisionApId(ctx context.Context, tx *queries.Queries, id int64) (*url.URL, error) {
+	apId, err := tx.SetLocalRevisionApId(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return url.Parse(apId.String)
+}
+
 // GetArticleIds returns the article's ID, ActivityPub ID and the ID of its last revision, if the article exists.
 func (d *dbImpl) GetLastRevisionID(ctx context.Context, title string) (int64, *url.URL, int64, error) {
 	a, err := d.queries.GetArticleIDS(ctx, title)
@@ -91,7 +127,7 @@ func (d *dbImpl) GetLastRevisionID(ctx context.Context, title string) (int64, *u
 }
 
 // CreateArticle creates a new local article, also inserting the article's first revision.
-func (d *dbImpl) CreateLocalArticle(ctx context.Context, userId int64, article domain.ArticleFed, initialEdit domain.Revision) (err error) {
+func (d *dbImpl) CreateLocalArticle(ctx context.Context, userId int64, article domain.ArticleFed, initialEdit domain.Revision) (revision *url.URL, err error) {
 	log.Info().
 		Str("title", article.Title).
 		Msg("creating new local article")
@@ -110,11 +146,36 @@ func (d *dbImpl) CreateLocalArticle(ctx context.Context, userId int64, article d
 		err = d.HandleError(err)
 	}()
 
+	tx := d.queries.WithTx(t)
+	articleId, err := insertLocalArticle(ctx, tx, article)
+	if err != nil {
+		return
+	}
+
+	revisionId, err := tx.EditArticle(ctx, queries.EditArticleParams{
+		ArticleID: articleId,
+		UserID:    userId,
+		Summary: sql.NullString{
+			Valid:  initialEdit.Summary != "",
+			String: initialEdit.Summary,
+		},
+		Diff:      initialEdit.Diff,
+		Published: true,
+	})
+	if err != nil {
+		return
+	}
+
+	revision, err = setRevisionApId(ctx, tx, revisionId)
+	return
+}
+
+// insertLocalArticle stores a new local article, without any revision, and returns its database ID.
+func insertLocalArticle(ctx context.Context, tx *queries.Queries, article domain.ArticleFed) (int64, error) {
 	var apid string
 	if article.ApID != nil {
 		apid = article.ApID.String()
 	}
-	tx := d.queries.WithTx(t)
 	articleId, err := tx.CreateArticle(ctx, queries.CreateArticleParams{
 		ApID: article.ApID.String(),
 		Url: sql.NullString{
@@ -126,35 +187,584 @@ func (d *dbImpl) CreateLocalArticle(ctx context.Context, userId int64, article d
 		MediaType:  article.MediaType,
 		Title:      article.Title,
 		Content:    article.Content,
+		ForkedFrom: nullIRI(article.ForkedFrom),
 	})
 	if err != nil {
-		return
+		return 0, err
 	}
 
-	_, err = tx.EditArticle(ctx, queries.EditArticleParams{
-		ApID: sql.NullString{
-			// TODO
-		},
-		ArticleID: articleId,
-		UserID:    userId,
-		Summary: sql.NullString{
-			Valid:  initialEdit.Summary != "",
-			String: initialEdit.Summary,
-		},
-		Diff: initialEdit.Diff,
+	// An article may be created again with the title of a deleted one, which is no longer gone.
+	return articleId, tx.DeleteTombstone(ctx, article.ApID.String())
+}
+
+func (d *dbImpl) ForkArticle(ctx context.Context, userId int64, article domain.ArticleFed, history []domain.RevisionFed, fork domain.Revision) (revision *url.URL, err error) {
+	log.Info().
+		Str("title", article.Title).
+		Str("source", article.ForkedFrom.String()).
+		Msg("forking article")
+
+	err = d.WithTx(func(tx *queries.Queries) error {
+		articleId, err := insertLocalArticle(ctx, tx, article)
+		if err != nil {
+			return err
+		}
+
+		var prev sql.NullInt64
+		for _, r := range history {
+			authorId, err := tx.GetUserIdByApId(ctx, r.Author.String())
+			if err != nil {
+				return err
+			}
+
+			id, err := tx.ImportRevision(ctx, queries.ImportRevisionParams{
+				ArticleID: articleId,
+				UserID:    authorId,
+				Summary: sql.NullString{
+					Valid:  r.Summary != "",
+					String: r.Summary,
+				},
+				Diff:    r.Diff,
+				Prev:    prev,
+				Created: r.Created.Unix(),
+			})
+			if err != nil {
+				return err
+			}
+
+			// Imported revisions are served as revisions of the fork, since their original IDs are taken.
+			if _, err = setRevisionApId(ctx, tx, id); err != nil {
+				return err
+			}
+			prev = sql.NullInt64{Valid: true, Int64: id}
+		}
+
+		revisionId, err := tx.EditArticle(ctx, queries.EditArticleParams{
+			ArticleID: articleId,
+			UserID:    userId,
+			Summary: sql.NullString{
+				Valid:  fork.Summary != "",
+				String: fork.Summary,
+			},
+			Diff:      fork.Diff,
+			Published: true,
+			Prev:      prev,
+		})
+		if err != nil {
+			return err
+		}
+
+		revision, err = setRevisionApId(ctx, tx, revisionId)
+		return err
 	})
 	return
 }
 
 func (d *dbImpl) GetLocalArticle(ctx context.Context, title string) (domain.ArticleCore, error) {
 	a, err := d.queries.GetLocalArticleByTitle(ctx, title)
+	forkedFrom, _ := parseOptional(a.ForkedFrom.String)
 	return domain.ArticleCore{
-		Title:     a.Title,
-		Summary:   a.Summary.String,
-		Content:   a.Content,
-		Protected: a.Protected,
-		MediaType: a.MediaType,
-		License:   "", // TODO
-		Language:  a.Language,
+		Title:      a.Title,
+		Summary:    a.Summary.String,
+		Content:    a.Content,
+		Protected:  a.Protected,
+		MediaType:  a.MediaType,
+		License:    "", // TODO
+		Language:   a.Language,
+		Likes:      a.Likes,
+		Shares:     a.Shares,
+		ForkedFrom: forkedFrom,
+		Revision:   a.Revision,
 	}, d.HandleError(err)
 }
+
+func (d *dbImpl) GetArticleFed(ctx context.Context, id *url.URL) (article domain.ArticleFed, err error) {
+	a, err := d.queries.GetArticleByApId(ctx, id.String())
+	if err != nil {
+		return article, d.HandleError(err)
+	}
+	return d.articleFed(ctx, a)
+}
+
+func (d *dbImpl) GetForeignArticle(ctx context.Context, title, host string) (article domain.ArticleFed, err error) {
+	a, err := d.queries.GetForeignArticleByTitle(ctx, queries.GetForeignArticleByTitleParams{
+		Title:    title,
+		Hostname: validate.NormalizeHost(host),
+	})
+	if err != nil {
+		return article, d.HandleError(err)
+	}
+	return d.articleFed(ctx, queries.GetArticleByApIdRow(a))
+}
+
+// articleFed converts a stored article, loading its authors and attachments.
+func (d *dbImpl) articleFed(ctx context.Context, a queries.GetArticleByApIdRow) (article domain.ArticleFed, err error) {
+	apId, err := url.Parse(a.ApID)
+	if err != nil {
+		return article, d.HandleError(err)
+	}
+
+	var u *url.URL
+	if a.Url.Valid {
+		if u, err = url.Parse(a.Url.String); err != nil {
+			return article, d.HandleError(err)
+		}
+	}
+
+	forkedFrom, err := parseOptional(a.ForkedFrom.String)
+	if err != nil {
+		return article, d.HandleError(err)
+	}
+
+	article = domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{
+			Title:      a.Title,
+			Summary:    a.Summary.String,
+			Content:    a.Content,
+			Protected:  a.Protected,
+			MediaType:  a.MediaType,
+			Language:   a.Language,
+			Likes:      a.Likes,
+			Shares:     a.Shares,
+			ForkedFrom: forkedFrom,
+		},
+		ApID:        apId,
+		Url:         u,
+		Host:        a.Hostname.String,
+		Local:       a.Local,
+		Created:     time.Unix(a.Created, 0),
+		LastUpdated: time.Unix(a.LastUpdated, 0),
+		PublicKey:   a.PublicKey.String,
+	}
+	if a.LastFetched.Valid {
+		article.LastFetched = time.Unix(a.LastFetched.Int64, 0)
+	}
+
+	authors, err := d.queries.ListArticleAuthors(ctx, a.ID)
+	if err != nil {
+		return article, d.HandleError(err)
+	}
+	if article.AttributedTo, err = d.parseAll(authors); err != nil {
+		return
+	}
+
+	files, err := d.queries.ListArticleFiles(ctx, a.ID)
+	if err != nil {
+		return article, d.HandleError(err)
+	}
+	for _, f := range files {
+		file, err := fileFromRow(queries.GetFileByApIdRow(f))
+		if err != nil {
+			return article, d.HandleError(err)
+		}
+		article.Attachments = append(article.Attachments, file)
+	}
+	return
+}
+
+func (d *dbImpl) UpsertForeignArticle(ctx context.Context, article domain.ArticleFed) (id int64, err error) {
+	host := article.Host
+	if host == "" {
+		host = article.ApID.Host
+	}
+
+	instanceId, err := d.GetInstanceIdOrCreate(ctx, host)
+	if err != nil {
+		return
+	}
+
+	var u string
+	if article.Url != nil {
+		u = article.Url.String()
+	}
+
+	id, err = d.queries.UpsertForeignArticle(ctx, queries.UpsertForeignArticleParams{
+		ApID: article.ApID.String(),
+		Url: sql.NullString{
+			Valid:  u != "",
+			String: u,
+		},
+		InstanceID: sql.NullInt64{
+			Valid: true,
+			Int64: instanceId,
+		},
+		Language:  article.Language,
+		MediaType: article.MediaType,
+		Title:     article.Title,
+		Summary: sql.NullString{
+			Valid:  article.Summary != "",
+			String: article.Summary,
+		},
+		Content:    article.Content,
+		ForkedFrom: nullIRI(article.ForkedFrom),
+	})
+	return id, d.HandleError(err)
+}
+
+func (d *dbImpl) DeleteForeignArticle(ctx context.Context, id *url.URL) error {
+	return d.WithTx(func(tx *queries.Queries) error {
+		articleId, err := tx.GetForeignArticleId(ctx, id.String())
+		if err != nil {
+			return err
+		}
+		return deleteArticle(ctx, tx, id, articleId)
+	})
+}
+
+func (d *dbImpl) DeleteLocalArticle(ctx context.Context, id *url.URL) error {
+	return d.WithTx(func(tx *queries.Queries) error {
+		articleId, err := tx.GetLocalArticleId(ctx, id.String())
+		if err != nil {
+			return err
+		}
+
+		if err = deleteArticle(ctx, tx, id, articleId); err != nil {
+			return err
+		}
+
+		return tx.InsertTombstone(ctx, queries.InsertTombstoneParams{
+			ApID:       id.String(),
+			FormerType: "Article",
+		})
+	})
+}
+
+// deleteArticle deletes an article along with everything that refers to it: its files, comments, likes,
+// announces, revisions, links and followers.
+func deleteArticle(ctx context.Context, tx *queries.Queries, id *url.URL, articleId int64) error {
+	for _, del := range []func(context.Context, int64) error{
+		tx.DeleteArticleFiles,
+		tx.DeleteArticleComments,
+		tx.DeleteArticleLikes,
+		tx.DeleteArticleAnnounces,
+		tx.DeleteArticleRevisions,
+		tx.DeleteArticleLinks,
+	} {
+		if err := del(ctx, articleId); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.DeleteFollowsOf(ctx, id.String()); err != nil {
+		return err
+	}
+	return tx.DeleteArticle(ctx, articleId)
+}
+
+func (d *dbImpl) GetRevisionFed(ctx context.Context, id *url.URL) (revision domain.RevisionFed, err error) {
+	r, err := d.queries.GetRevisionByApId(ctx, sql.NullString{Valid: true, String: id.String()})
+	if err != nil {
+		return revision, d.HandleError(err)
+	}
+
+	article, err := url.Parse(r.Article)
+	if err != nil {
+		return revision, d.HandleError(err)
+	}
+
+	author, err := url.Parse(r.Author)
+	if err != nil {
+		return revision, d.HandleError(err)
+	}
+
+	basedOn, err := parseOptional(r.BasedOn.String)
+	if err != nil {
+		return revision, d.HandleError(err)
+	}
+
+	reviewer, err := parseOptional(r.Reviewer.String)
+	if err != nil {
+		return revision, d.HandleError(err)
+	}
+
+	revision = domain.RevisionFed{
+		ApID:      id,
+		Article:   article,
+		Author:    author,
+		Summary:   r.Summary.String,
+		Diff:      r.Diff,
+		Published: r.Published,
+		Reviewed:  r.Reviewed,
+		Proposal:  r.Proposal,
+		BasedOn:   basedOn,
+		Reviewer:  reviewer,
+		Initial:   r.Initial.Bool,
+		Created:   time.Unix(r.Created, 0),
+	}
+	return
+}
+
+func (d *dbImpl) ListArticleRevisions(ctx context.Context, article *url.URL) ([]domain.RevisionFed, error) {
+	rows, err := d.queries.ListArticleRevisions(ctx, article.String())
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	revisions := make([]domain.RevisionFed, 0, len(rows))
+	for _, r := range rows {
+		author, err := url.Parse(r.Author)
+		if err != nil {
+			return nil, d.HandleError(err)
+		}
+
+		id := revisionIRI(article, r.ID)
+		if r.ApID.Valid {
+			if id, err = url.Parse(r.ApID.String); err != nil {
+				return nil, d.HandleError(err)
+			}
+		}
+
+		revisions = append(revisions, domain.RevisionFed{
+			ApID:      id,
+			Article:   article,
+			Author:    author,
+			Summary:   r.Summary.String,
+			Diff:      r.Diff,
+			Published: true,
+			Initial:   r.Initial.Bool,
+			Created:   time.Unix(r.Created, 0),
+		})
+	}
+	return revisions, nil
+}
+
+func (d *dbImpl) InsertProposal(ctx context.Context, article *url.URL, userId int64, summary, diff string) (id *url.URL, err error) {
+	err = d.WithTx(func(tx *queries.Queries) error {
+		articleId, err := tx.GetForeignArticleId(ctx, article.String())
+		if err != nil {
+			return err
+		}
+
+		var basedOn sql.NullInt64
+		basedOn.Int64, err = tx.GetLatestRevisionId(ctx, articleId)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		basedOn.Valid = err == nil
+
+		revisionId, err := tx.InsertProposal(ctx, queries.InsertProposalParams{
+			ArticleID: articleId,
+			UserID:    userId,
+			Summary: sql.NullString{
+				Valid:  summary != "",
+				String: summary,
+			},
+			Diff:    diff,
+			BasedOn: basedOn,
+		})
+		if err != nil {
+			return err
+		}
+
+		id = d.Config.Url.JoinPath("proposals", strconv.FormatInt(revisionId, 10))
+		return tx.SetRevisionApId(ctx, queries.SetRevisionApIdParams{
+			ApID: sql.NullString{Valid: true, String: id.String()},
+			ID:   revisionId,
+		})
+	})
+	return
+}
+
+func (d *dbImpl) ReviewRevision(ctx context.Context, id, reviewer *url.URL, accepted bool) error {
+	return d.WithTx(func(tx *queries.Queries) error {
+		var reviewerId sql.NullInt64
+		if reviewer != nil {
+			var err error
+			reviewerId.Int64, err = tx.GetUserIdByApId(ctx, reviewer.String())
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			reviewerId.Valid = err == nil
+		}
+
+		n, err := tx.ReviewRevision(ctx, queries.ReviewRevisionParams{
+			Accepted: accepted,
+			Reviewer: reviewerId,
+			ApID:     sql.NullString{Valid: true, String: id.String()},
+		})
+		if err == nil && n == 0 {
+			err = sql.ErrNoRows
+		}
+		return err
+	})
+}
+
+func (d *dbImpl) InsertForeignRevision(ctx context.Context, revision domain.RevisionFed) error {
+	created := time.Now().Unix()
+	if !revision.Created.IsZero() {
+		created = revision.Created.Unix()
+	}
+
+	return d.WithTx(func(tx *queries.Queries) error {
+		articleId, err := tx.GetForeignArticleId(ctx, revision.Article.String())
+		if err != nil {
+			return err
+		}
+
+		userId, err := tx.GetUserIdByApId(ctx, revision.Author.String())
+		if err != nil {
+			return err
+		}
+
+		var prev sql.NullInt64
+		if !revision.Initial {
+			prev.Int64, err = tx.GetLatestRevisionId(ctx, articleId)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			prev.Valid = err == nil
+		}
+
+		return tx.InsertForeignRevision(ctx, queries.InsertForeignRevisionParams{
+			ApID: sql.NullString{
+				Valid:  true,
+				String: revision.ApID.String(),
+			},
+			ArticleID: articleId,
+			UserID:    userId,
+			Summary: sql.NullString{
+				Valid:  revision.Summary != "",
+				String: revision.Summary,
+			},
+			Diff:    revision.Diff,
+			Prev:    prev,
+			Created: created,
+		})
+	})
+}
+
+func (d *dbImpl) DeleteForeignRevision(ctx context.Context, id *url.URL) error {
+	n, err := d.queries.DeleteForeignRevision(ctx, sql.NullString{Valid: true, String: id.String()})
+	if err == nil && n == 0 {
+		err = sql.ErrNoRows
+	}
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) ListUserRevisions(ctx context.Context, author *url.URL, limit, offset int) ([]domain.RevisionFed, error) {
+	rows, err := d.queries.ListUserRevisions(ctx, queries.ListUserRevisionsParams{
+		ApID:   author.String(),
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	revisions := make([]domain.RevisionFed, 0, len(rows))
+	for _, r := range rows {
+		article, err := url.Parse(r.Article)
+		if err != nil {
+			return nil, d.HandleError(err)
+		}
+
+		id := revisionIRI(article, r.ID)
+		if r.ApID.Valid {
+			if id, err = url.Parse(r.ApID.String); err != nil {
+				return nil, d.HandleError(err)
+			}
+		}
+
+		revisions = append(revisions, domain.RevisionFed{
+			ApID:      id,
+			Article:   article,
+			Author:    author,
+			Summary:   r.Summary.String,
+			Diff:      r.Diff,
+			Published: true,
+			Initial:   r.Initial.Bool,
+			Created:   time.Unix(r.Created, 0),
+		})
+	}
+	return revisions, nil
+}
+
+func (d *dbImpl) CountUserRevisions(ctx context.Context, author *url.URL) (int64, error) {
+	n, err := d.queries.CountUserRevisions(ctx, author.String())
+	return n, d.HandleError(err)
+}
+
+func (d *dbImpl) ListLocalRevisions(ctx context.Context, article *url.URL, limit, offset int) ([]domain.RevisionFed, error) {
+	rows, err := d.queries.ListLocalRevisions(ctx, queries.ListLocalRevisionsParams{
+		Article: optionalIRI(article),
+		Limit:   int64(limit),
+		Offset:  int64(offset),
+	})
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	revisions := make([]domain.RevisionFed, 0, len(rows))
+	for _, r := range rows {
+		article, err := url.Parse(r.Article)
+		if err != nil {
+			return nil, d.HandleError(err)
+		}
+
+		author, err := url.Parse(r.Author)
+		if err != nil {
+			return nil, d.HandleError(err)
+		}
+
+		id := revisionIRI(article, r.ID)
+		if r.ApID.Valid {
+			if id, err = url.Parse(r.ApID.String); err != nil {
+				return nil, d.HandleError(err)
+			}
+		}
+
+		revisions = append(revisions, domain.RevisionFed{
+			ApID:      id,
+			Article:   article,
+			Author:    author,
+			Summary:   r.Summary.String,
+			Diff:      r.Diff,
+			Published: true,
+			Initial:   r.Initial.Bool,
+			Created:   time.Unix(r.Created, 0),
+		})
+	}
+	return revisions, nil
+}
+
+func (d *dbImpl) CountLocalRevisions(ctx context.Context, article *url.URL) (int64, error) {
+	n, err := d.queries.CountLocalRevisions(ctx, optionalIRI(article))
+	return n, d.HandleError(err)
+}
+
+func (d *dbImpl) ListArticlesWithoutKey(ctx context.Context) ([]*url.URL, error) {
+	ids, err := d.queries.ListArticlesWithoutKey(ctx)
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+	return d.parseAll(ids)
+}
+
+func (d *dbImpl) SetArticleKeys(ctx context.Context, article *url.URL, publicKey, privateKey string) error {
+	err := d.queries.SetArticleKeys(ctx, queries.SetArticleKeysParams{
+		PublicKey:  sql.NullString{Valid: true, String: publicKey},
+		PrivateKey: sql.NullString{Valid: true, String: privateKey},
+		ApID:       article.String(),
+	})
+	return d.HandleError(err)
+}
+
+// optionalIRI converts an IRI into a query parameter that is NULL if the IRI is nil.
+func optionalIRI(iri *url.URL) any {
+	if iri == nil {
+		return nil
+	}
+	return iri.String()
+}
+
+// nullIRI converts an optional IRI into a nullable column.
+func nullIRI(iri *url.URL) sql.NullString {
+	if iri == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{Valid: true, String: iri.String()}
+}
+
+// revisionIRI returns the ID of a local revision that has not been assigned one.
+func revisionIRI(article *url.URL, id int64) *url.URL {
+	return article.JoinPath("history", strconv.FormatInt(id, 10))
+}
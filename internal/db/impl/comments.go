@@ -0,0 +1,169 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+func (d *dbImpl) InsertLocalComment(ctx context.Context, article *url.URL, userId, replyTo int64, content string) (id *url.URL, err error) {
+	err = d.WithTx(func(tx *queries.Queries) error {
+		articleId, err := tx.GetLocalArticleId(ctx, article.String())
+		if err != nil {
+			return err
+		}
+
+		var inReplyTo sql.NullInt64
+		if replyTo != 0 {
+			parentArticle, err := tx.GetCommentArticleId(ctx, replyTo)
+			if err != nil {
+				return err
+			}
+			// Threads do not cross discussion pages.
+			if parentArticle != articleId {
+				return sql.ErrNoRows
+			}
+			inReplyTo = sql.NullInt64{Valid: true, Int64: replyTo}
+		}
+
+		commentId, err := tx.InsertLocalComment(ctx, queries.InsertLocalCommentParams{
+			ArticleID: articleId,
+			UserID:    userId,
+			InReplyTo: inReplyTo,
+			Content:   content,
+		})
+		if err != nil {
+			return err
+		}
+
+		id = article.JoinPath("discussion", strconv.FormatInt(commentId, 10))
+		page := article.JoinPath("discussion")
+		page.Fragment = "comment-" + strconv.FormatInt(commentId, 10)
+		return tx.SetCommentApId(ctx, queries.SetCommentApIdParams{
+			ApID: sql.NullString{Valid: true, String: id.String()},
+			Url:  sql.NullString{Valid: true, String: page.String()},
+			ID:   commentId,
+		})
+	})
+	return
+}
+
+func (d *dbImpl) UpsertForeignComment(ctx context.Context, comment domain.CommentFed) error {
+	created := time.Now().Unix()
+	if !comment.Created.IsZero() {
+		created = comment.Created.Unix()
+	}
+
+	var u, inReplyTo sql.NullString
+	if comment.Url != nil {
+		u = sql.NullString{Valid: true, String: comment.Url.String()}
+	}
+	if comment.InReplyTo != nil {
+		inReplyTo = sql.NullString{Valid: true, String: comment.InReplyTo.String()}
+	}
+
+	err := d.queries.UpsertForeignComment(ctx, queries.UpsertForeignCommentParams{
+		ApID:      sql.NullString{Valid: true, String: comment.ApID.String()},
+		Url:       u,
+		Article:   comment.Article.String(),
+		Author:    comment.Author.String(),
+		InReplyTo: inReplyTo,
+		Content:   comment.Content,
+		Hidden:    comment.Hidden,
+		Created:   created,
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) GetCommentFed(ctx context.Context, id *url.URL) (comment domain.CommentFed, err error) {
+	c, err := d.queries.GetCommentByApId(ctx, sql.NullString{Valid: true, String: id.String()})
+	if err != nil {
+		return comment, d.HandleError(err)
+	}
+
+	comment = domain.CommentFed{
+		ApID:    id,
+		Content: c.Content,
+		Local:   c.Local,
+		Hidden:  c.Hidden,
+		Created: time.Unix(c.Created, 0),
+	}
+
+	if comment.Url, err = parseOptional(c.Url.String); err != nil {
+		return comment, d.HandleError(err)
+	}
+	if comment.Article, err = url.Parse(c.Article); err != nil {
+		return comment, d.HandleError(err)
+	}
+	if comment.Author, err = url.Parse(c.Author); err != nil {
+		return comment, d.HandleError(err)
+	}
+	if comment.InReplyTo, err = parseOptional(c.InReplyTo.String); err != nil {
+		return comment, d.HandleError(err)
+	}
+	return
+}
+
+func (d *dbImpl) ListComments(ctx context.Context, article *url.URL) ([]domain.Comment, error) {
+	rows, err := d.queries.ListArticleComments(ctx, article.String())
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	// Comments are listed oldest first, so a reply always comes after the comment it replies to.
+	comments := make(map[int64]*domain.Comment, len(rows))
+	for _, r := range rows {
+		comments[r.ID] = &domain.Comment{
+			ID:       r.ID,
+			Username: r.Username,
+			Domain:   r.Domain.String,
+			Content:  r.Content,
+			Hidden:   r.Hidden,
+			Created:  time.Unix(r.Created, 0),
+		}
+	}
+
+	// The threads are assembled from the newest comments up, so that every reply is complete when it is copied
+	// into its parent.
+	var threads []domain.Comment
+	for i := len(rows) - 1; i >= 0; i-- {
+		r := rows[i]
+		c := comments[r.ID]
+		if parent, ok := comments[r.InReplyTo.Int64]; r.InReplyTo.Valid && ok {
+			parent.Replies = append([]domain.Comment{*c}, parent.Replies...)
+			continue
+		}
+		threads = append([]domain.Comment{*c}, threads...)
+	}
+	return threads, nil
+}
+
+func (d *dbImpl) SetCommentHidden(ctx context.Context, id int64, hidden bool) error {
+	n, err := d.queries.SetCommentHidden(ctx, queries.SetCommentHiddenParams{
+		Hidden: hidden,
+		ID:     id,
+	})
+	if err == nil && n == 0 {
+		err = sql.ErrNoRows
+	}
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) DeleteComment(ctx context.Context, id int64) error {
+	return d.WithTx(func(tx *queries.Queries) error {
+		if err := tx.ReparentReplies(ctx, id); err != nil {
+			return err
+		}
+
+		n, err := tx.DeleteComment(ctx, id)
+		if err == nil && n == 0 {
+			err = sql.ErrNoRows
+		}
+		return err
+	})
+}
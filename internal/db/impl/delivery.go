@@ -0,0 +1,151 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+func (d *dbImpl) EnqueueDeliveries(ctx context.Context, actor *url.URL, activity []byte, inboxes []*url.URL) error {
+	return d.WithTx(func(tx *queries.Queries) error {
+		for _, inbox := range inboxes {
+			err := tx.InsertDelivery(ctx, queries.InsertDeliveryParams{
+				Actor:    actor.String(),
+				Inbox:    inbox.String(),
+				Activity: string(activity),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *dbImpl) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) (deliveries []domain.Delivery, err error) {
+	now := time.Now()
+	err = d.WithTx(func(tx *queries.Queries) error {
+		rows, err := tx.ListDueDeliveries(ctx, queries.ListDueDeliveriesParams{
+			Now:   now.Unix(),
+			Limit: int64(limit),
+		})
+		if err != nil {
+			return err
+		}
+
+		deliveries = make([]domain.Delivery, 0, len(rows))
+		for _, r := range rows {
+			err = tx.LeaseDelivery(ctx, queries.LeaseDeliveryParams{
+				NextAttempt: now.Add(lease).Unix(),
+				ID:          r.ID,
+			})
+			if err != nil {
+				return err
+			}
+
+			// A delivery whose actor or inbox cannot be parsed will never succeed, so it is given up on.
+			actor, err := url.Parse(r.Actor)
+			if err != nil {
+				log.Error().Err(err).Int64("id", r.ID).Msg("failed to parse stored delivery actor")
+				if err = killDelivery(ctx, tx, r.ID, r.Attempts, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			inbox, err := url.Parse(r.Inbox)
+			if err != nil {
+				log.Error().Err(err).Int64("id", r.ID).Msg("failed to parse stored delivery inbox")
+				if err = killDelivery(ctx, tx, r.ID, r.Attempts, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			deliveries = append(deliveries, domain.Delivery{
+				ID:       r.ID,
+				Actor:    actor,
+				Inbox:    inbox,
+				Activity: []byte(r.Activity),
+				Attempts: int(r.Attempts),
+			})
+		}
+		return nil
+	})
+	return
+}
+
+func killDelivery(ctx context.Context, tx *queries.Queries, id, attempts int64, reason error) error {
+	return tx.KillDelivery(ctx, queries.KillDeliveryParams{
+		Attempts:  attempts,
+		LastError: sql.NullString{Valid: true, String: reason.Error()},
+		ID:        id,
+	})
+}
+
+func (d *dbImpl) DeleteDelivery(ctx context.Context, id int64) error {
+	return d.HandleError(d.queries.DeleteDelivery(ctx, id))
+}
+
+func (d *dbImpl) RescheduleDelivery(ctx context.Context, id int64, attempts int, next time.Time, reason string) error {
+	err := d.queries.RescheduleDelivery(ctx, queries.RescheduleDeliveryParams{
+		Attempts:    int64(attempts),
+		NextAttempt: next.Unix(),
+		LastError: sql.NullString{
+			Valid:  reason != "",
+			String: reason,
+		},
+		ID: id,
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) KillDelivery(ctx context.Context, id int64, attempts int, reason string) error {
+	err := d.queries.KillDelivery(ctx, queries.KillDeliveryParams{
+		Attempts: int64(attempts),
+		LastError: sql.NullString{
+			Valid:  reason != "",
+			String: reason,
+		},
+		ID: id,
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) SharedInboxes(ctx context.Context, inboxes []*url.URL) (map[string]*url.URL, error) {
+	list := make([]string, 0, len(inboxes))
+	for _, inbox := range inboxes {
+		list = append(list, inbox.String())
+	}
+
+	rows, err := d.queries.ListSharedInboxes(ctx, list)
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	shared := make(map[string]*url.URL, len(rows))
+	for _, r := range rows {
+		iri, err := url.Parse(r.SharedInbox.String)
+		if err != nil {
+			log.Error().Err(err).Str("inbox", r.Inbox).Msg("failed to parse stored shared inbox")
+			continue
+		}
+		shared[r.Inbox] = iri
+	}
+	return shared, nil
+}
+
+func (d *dbImpl) GetSigningKey(ctx context.Context, actor *url.URL) (*url.URL, string, error) {
+	key, err := d.queries.GetSigningKey(ctx, actor.String())
+	if err != nil {
+		return nil, "", d.HandleError(err)
+	}
+
+	keyId, err := parseOptional(key.KeyID.String)
+	return keyId, key.PrivateKey, d.HandleError(err)
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"net/url"
+	"strings"
 	"time"
 
+	"github.com/sidereusnuntius/gowiki/internal/db"
 	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
 	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
 )
 
 func (d *dbImpl) ActorIdByOutbox(ctx context.Context, iri *url.URL) (*url.URL, error) {
-	id, err := d.queries.UserIdByOutbox(ctx, iri.String())
+	id, err := d.queries.UserIdByOutbox(ctx, nullIRI(iri))
 
 	if err != nil {
 		return nil, d.HandleError(err)
@@ -37,22 +40,33 @@ func (d *dbImpl) OutboxForInbox(ctx context.Context, inboxIRI *url.URL) (*url.UR
 	if err != nil {
 		return nil, d.HandleError(err)
 	}
-	outboxIRI, err := url.Parse(id)
+	if !id.Valid {
+		return nil, db.ErrNotFound
+	}
+	outboxIRI, err := url.Parse(id.String)
 	return outboxIRI, d.HandleError(err)
 }
 
-func (d *dbImpl) GetUserFed(ctx context.Context, id *url.URL) (user domain.UserFed, err error) {
-	defer func() {
-		if err != nil {
-			err = d.HandleError(err)
-		}
-	}()
-
+func (d *dbImpl) GetUserFed(ctx context.Context, id *url.URL) (domain.UserFed, error) {
 	u, err := d.queries.GetUserFull(ctx, id.String())
 	if err != nil {
-		return
+		return domain.UserFed{}, d.HandleError(err)
+	}
+	user, err := userFed(u)
+	return user, d.HandleError(err)
+}
+
+func (d *dbImpl) GetForeignUserFed(ctx context.Context, id *url.URL) (domain.UserFed, error) {
+	u, err := d.queries.GetForeignUserFull(ctx, id.String())
+	if err != nil {
+		return domain.UserFed{}, d.HandleError(err)
 	}
+	user, err := userFed(queries.GetUserFullRow(u))
+	return user, d.HandleError(err)
+}
 
+// userFed converts a stored user, local or foreign, into its federated representation.
+func userFed(u queries.GetUserFullRow) (user domain.UserFed, err error) {
 	apId, err := url.Parse(u.ApID)
 	if err != nil {
 		return
@@ -63,23 +77,34 @@ func (d *dbImpl) GetUserFed(ctx context.Context, id *url.URL) (user domain.UserF
 		return
 	}
 
-	outbox, err := url.Parse(u.Outbox)
+	// Foreign actors are not required to have an outbox or a followers collection.
+	outbox, err := parseOptional(u.Outbox.String)
 	if err != nil {
 		return
 	}
 
-	followers, err := url.Parse(u.Followers)
+	followers, err := parseOptional(u.Followers.String)
 	if err != nil {
 		return
 	}
 
+	var profile *url.URL
+	if u.Url.Valid {
+		profile, err = url.Parse(u.Url.String)
+		if err != nil {
+			return
+		}
+	}
+
 	user = domain.UserFed{
 		UserCore: domain.UserCore{
 			Username: u.Username,
 			Name:     u.Name,
+			Domain:   u.Domain.String,
 			Summary:  u.Summary.String,
-			//URL: , TODO
+			URL:      profile,
 		},
+		Bot:         u.Bot,
 		ApId:        apId,
 		Inbox:       inbox,
 		Outbox:      outbox,
@@ -88,10 +113,37 @@ func (d *dbImpl) GetUserFed(ctx context.Context, id *url.URL) (user domain.UserF
 		Created:     time.Unix(u.Created, 0),
 		LastUpdated: time.Unix(u.LastUpdated, 0),
 	}
+
+	if user.KeyId, err = parseOptional(u.KeyID.String); err != nil {
+		return
+	}
+
+	// The replaced key is no longer published once its grace period is over.
+	if u.PreviousPublicKey.String != "" && time.Now().Unix() < u.PreviousKeyExpires.Int64 {
+		user.PreviousKey = u.PreviousPublicKey.String
+		user.PreviousKeyId, err = parseOptional(u.PreviousKeyID.String)
+	}
 	return
 }
 
+func (d *dbImpl) RotateUserKey(ctx context.Context, user, keyId *url.URL, publicKey, privateKey string, graceUntil time.Time) error {
+	n, err := d.queries.RotateUserKey(ctx, queries.RotateUserKeyParams{
+		PreviousKeyExpires: sql.NullInt64{Valid: true, Int64: graceUntil.Unix()},
+		KeyID:              sql.NullString{Valid: true, String: keyId.String()},
+		PublicKey:          publicKey,
+		PrivateKey:         privateKey,
+		ApID:               user.String(),
+	})
+	if err == nil && n == 0 {
+		err = sql.ErrNoRows
+	}
+	return d.HandleError(err)
+}
+
+// GetInstanceIdOrCreate returns the ID of the instance with the given hostname, which is normalized with
+// validate.NormalizeHost, storing the instance if it is unknown.
 func (d *dbImpl) GetInstanceIdOrCreate(ctx context.Context, hostname string) (id int64, err error) {
+	hostname = validate.NormalizeHost(hostname)
 	id, err = d.queries.GetInstanceId(ctx, hostname)
 
 	if err == sql.ErrNoRows {
@@ -107,4 +159,145 @@ func (d *dbImpl) GetInstanceIdOrCreate(ctx context.Context, hostname string) (id
 	}
 
 	return
-}
\ No newline at end of file
+}
+func (d *dbImpl) GetPublicKey(ctx context.Context, owner *url.URL) (string, error) {
+	key, err := d.queries.GetPublicKey(ctx, owner.String())
+	return key, d.HandleError(err)
+}
+
+func (d *dbImpl) UpsertForeignUser(ctx context.Context, user domain.UserFed) (id int64, err error) {
+	// Every server we learn of is recorded, so that an admin may choose its policy.
+	if _, err = d.GetInstanceIdOrCreate(ctx, user.ApId.Host); err != nil {
+		return
+	}
+
+	var profile string
+	if user.URL != nil {
+		profile = user.URL.String()
+	}
+
+	id, err = d.queries.UpsertForeignUser(ctx, queries.UpsertForeignUserParams{
+		Bot:  user.Bot,
+		ApID: user.ApId.String(),
+		Url: sql.NullString{
+			Valid:  profile != "",
+			String: profile,
+		},
+		Username: strings.ToLower(user.Username),
+		Name:     user.Name,
+		Domain: sql.NullString{
+			Valid:  user.Domain != "",
+			String: user.Domain,
+		},
+		Summary: sql.NullString{
+			Valid:  user.Summary != "",
+			String: user.Summary,
+		},
+		Inbox:       user.Inbox.String(),
+		Outbox:      nullIRI(user.Outbox),
+		Followers:   nullIRI(user.Followers),
+		PublicKey:   user.PublicKey,
+		SharedInbox: nullIRI(user.SharedInbox),
+	})
+
+	if err != nil {
+		err = d.HandleError(err)
+	}
+	return
+}
+
+func (d *dbImpl) SetInstanceKey(ctx context.Context, hostname, publicKey string, inbox *url.URL) error {
+	hostname = validate.NormalizeHost(hostname)
+	if _, err := d.GetInstanceIdOrCreate(ctx, hostname); err != nil {
+		return err
+	}
+
+	var inboxStr string
+	if inbox != nil {
+		inboxStr = inbox.String()
+	}
+
+	err := d.queries.SetInstanceKey(ctx, queries.SetInstanceKeyParams{
+		PublicKey: sql.NullString{
+			Valid:  publicKey != "",
+			String: publicKey,
+		},
+		Inbox: sql.NullString{
+			Valid:  inboxStr != "",
+			String: inboxStr,
+		},
+		Hostname: hostname,
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) CreateInstanceActor(ctx context.Context, actor domain.UserFedInternal) error {
+	var profile string
+	if actor.URL != nil {
+		profile = actor.URL.String()
+	}
+
+	err := d.queries.CreateInstanceActor(ctx, queries.CreateInstanceActorParams{
+		ApID: actor.ApId.String(),
+		Url: sql.NullString{
+			Valid:  profile != "",
+			String: profile,
+		},
+		Username:   actor.Username,
+		Name:       actor.Name,
+		Inbox:      actor.Inbox.String(),
+		Outbox:     nullIRI(actor.Outbox),
+		Followers:  nullIRI(actor.Followers),
+		PublicKey:  actor.PublicKey,
+		PrivateKey: actor.PrivateKey,
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) ObjectExists(ctx context.Context, id *url.URL) (bool, error) {
+	exists, err := d.queries.ObjectExists(ctx, id.String())
+	return exists.Bool, d.HandleError(err)
+}
+
+func (d *dbImpl) DeleteForeignActor(ctx context.Context, id *url.URL) error {
+	return d.WithTx(func(tx *queries.Queries) error {
+		userId, err := tx.GetForeignUserId(ctx, id.String())
+		if err != nil {
+			return err
+		}
+
+		for _, del := range []func(context.Context, int64) error{
+			tx.DeleteUserFollows,
+			tx.DeleteUserLikes,
+			tx.DeleteUserAnnounces,
+			tx.HideUserComments,
+		} {
+			if err = del(ctx, userId); err != nil {
+				return err
+			}
+		}
+
+		err = tx.InsertTombstone(ctx, queries.InsertTombstoneParams{
+			ApID:       id.String(),
+			FormerType: "Person",
+		})
+		if err != nil {
+			return err
+		}
+
+		// Users who authored revisions or comments are kept, as those refer to them.
+		n, err := tx.DeleteForeignUser(ctx, id.String())
+		if err != nil || n > 0 {
+			return err
+		}
+		return tx.ClearForeignUser(ctx, userId)
+	})
+}
+
+// parseOptional parses a stored URL that may be empty, in which case it returns nil.
+func parseOptional(s string) (*url.URL, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return url.Parse(s)
+}
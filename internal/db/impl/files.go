@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"net/url"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/sidereusnuntius/gowiki/internal/db"
@@ -92,3 +93,80 @@ func (d *dbImpl) GetFile(ctx context.Context, digest string) (file domain.File,
 	}
 	return
 }
+
+func (d *dbImpl) GetFileFed(ctx context.Context, id *url.URL) (file domain.File, err error) {
+	f, err := d.queries.GetFileByApId(ctx, id.String())
+	if err != nil {
+		return file, d.HandleError(err)
+	}
+
+	file, err = fileFromRow(f)
+	return file, d.HandleError(err)
+}
+
+func fileFromRow(f queries.GetFileByApIdRow) (file domain.File, err error) {
+	apId, err := url.Parse(f.ApID)
+	if err != nil {
+		return
+	}
+
+	u, err := url.Parse(f.Url)
+	if err != nil {
+		return
+	}
+
+	var uploader *url.URL
+	if f.Uploader.Valid {
+		if uploader, err = url.Parse(f.Uploader.String); err != nil {
+			return
+		}
+	}
+
+	file = domain.File{
+		FileMetadata: domain.FileMetadata{
+			Name:      f.Name.String,
+			Filename:  f.Filename.String,
+			Type:      f.Type,
+			MimeType:  f.MimeType,
+			SizeBytes: f.SizeBytes.Int64,
+			Local:     f.Local,
+		},
+		ApId:     apId,
+		Url:      u,
+		Uploader: uploader,
+		Created:  time.Unix(f.Created, 0),
+	}
+	return
+}
+
+func (d *dbImpl) UpsertForeignFile(ctx context.Context, file domain.File) error {
+	err := d.queries.UpsertForeignFile(ctx, queries.UpsertForeignFileParams{
+		ApID: file.ApId.String(),
+		Name: sql.NullString{
+			Valid:  file.Name != "",
+			String: file.Name,
+		},
+		Type:     file.Type,
+		MimeType: file.MimeType,
+		SizeBytes: sql.NullInt64{
+			Valid: file.SizeBytes != 0,
+			Int64: file.SizeBytes,
+		},
+		Url: file.Url.String(),
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) DeleteForeignFile(ctx context.Context, id *url.URL) error {
+	return d.WithTx(func(tx *queries.Queries) error {
+		if err := tx.DeleteForeignFileLinks(ctx, id.String()); err != nil {
+			return err
+		}
+
+		n, err := tx.DeleteForeignFile(ctx, id.String())
+		if err == nil && n == 0 {
+			err = sql.ErrNoRows
+		}
+		return err
+	})
+}
@@ -0,0 +1,117 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+
+	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+func (d *dbImpl) AddFollow(ctx context.Context, follow domain.Follow) error {
+	var apId string
+	if follow.ApID != nil {
+		apId = follow.ApID.String()
+	}
+
+	err := d.queries.UpsertFollow(ctx, queries.UpsertFollowParams{
+		ApID: sql.NullString{
+			Valid:  apId != "",
+			String: apId,
+		},
+		Follower: follow.Follower.String(),
+		Followee: follow.Followee.String(),
+		Accepted: follow.Accepted,
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) RemoveFollow(ctx context.Context, follower, followee *url.URL) error {
+	n, err := d.queries.DeleteFollow(ctx, queries.DeleteFollowParams{
+		Followee: followee.String(),
+		Follower: follower.String(),
+	})
+	if err == nil && n == 0 {
+		err = sql.ErrNoRows
+	}
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) ListFollowers(ctx context.Context, followee *url.URL, limit, offset int) ([]*url.URL, error) {
+	ids, err := d.queries.ListFollowers(ctx, queries.ListFollowersParams{
+		Followee: followee.String(),
+		Limit:    int64(limit),
+		Offset:   int64(offset),
+	})
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+	return d.parseAll(ids)
+}
+
+func (d *dbImpl) CountFollowers(ctx context.Context, followee *url.URL) (int64, error) {
+	n, err := d.queries.CountFollowers(ctx, followee.String())
+	return n, d.HandleError(err)
+}
+
+func (d *dbImpl) FollowerInboxes(ctx context.Context, followees ...*url.URL) ([]*url.URL, error) {
+	ids := make([]string, 0, len(followees))
+	for _, f := range followees {
+		ids = append(ids, f.String())
+	}
+
+	inboxes, err := d.queries.ListFollowerInboxes(ctx, ids)
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+	return d.parseAll(inboxes)
+}
+
+func (d *dbImpl) LocalRecipients(ctx context.Context, addressed []*url.URL) ([]*url.URL, error) {
+	seen := make(map[string]bool)
+	var inboxes []string
+	for _, iri := range addressed {
+		list, err := d.queries.ListLocalRecipients(ctx, iri.String())
+		if err != nil {
+			return nil, d.HandleError(err)
+		}
+		for _, inbox := range list {
+			if !seen[inbox] {
+				seen[inbox] = true
+				inboxes = append(inboxes, inbox)
+			}
+		}
+	}
+	return d.parseAll(inboxes)
+}
+
+func (d *dbImpl) ListFollowing(ctx context.Context, follower *url.URL, limit, offset int) ([]*url.URL, error) {
+	ids, err := d.queries.ListFollowing(ctx, queries.ListFollowingParams{
+		ApID:   follower.String(),
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+	return d.parseAll(ids)
+}
+
+func (d *dbImpl) CountFollowing(ctx context.Context, follower *url.URL) (int64, error) {
+	n, err := d.queries.CountFollowing(ctx, follower.String())
+	return n, d.HandleError(err)
+}
+
+// parseAll parses a list of stored URLs.
+func (d *dbImpl) parseAll(list []string) ([]*url.URL, error) {
+	urls := make([]*url.URL, 0, len(list))
+	for _, s := range list {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, d.HandleError(err)
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
@@ -2,17 +2,21 @@ package impl
 
 import (
 	"context"
+	"database/sql"
 	"net/url"
+	"strings"
 	"testing"
 
 	_ "github.com/golang-migrate/migrate/source/file"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/sidereusnuntius/gowiki/internal/config"
 	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
 	"github.com/sidereusnuntius/gowiki/internal/initialization"
 )
 
 var DB db.DB
+var database *sql.DB
 var ctx = context.Background()
 
 func TestMain(m *testing.M) {
@@ -21,14 +25,15 @@ func TestMain(m *testing.M) {
 		return
 	}
 
+	database = d
 	err = initialization.SetupDB(d, "temp")
 	if err != nil {
 		return
 	}
 	hostname, _ := url.Parse("https://test.wiki")
 	DB = New(config.Configuration{
-	    Domain: "test.wiki",
-		Url: hostname,
+		Domain: "test.wiki",
+		Url:    hostname,
 	}, d)
 	m.Run()
 }
@@ -46,4 +51,127 @@ func TestGetInstanceIdOrCreate(t *testing.T) {
 	if id1 != id2 {
 		t.Errorf("expected second query to return id %d, but it returned %d", id1, id2)
 	}
-}
\ No newline at end of file
+}
+
+func TestLinks(t *testing.T) {
+	wiki, _ := url.Parse("https://test.wiki")
+	galileo := wiki.JoinPath("u", "galileo")
+	err := DB.InsertUser(ctx, domain.UserFedInternal{
+		UserFed: domain.UserFed{
+			UserCore: domain.UserCore{Username: "galileo"},
+			ApId:     galileo,
+			Inbox:    galileo.JoinPath("inbox"),
+		},
+	}, domain.Account{Email: "galileo@test.wiki"}, "", "")
+	if err != nil {
+		t.Fatalf("failed to create user: %s", err)
+	}
+	var userId int64
+	if err = database.QueryRow("SELECT id FROM users WHERE ap_id = ?", galileo.String()).Scan(&userId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+
+	create := func(title, content string) *url.URL {
+		id := wiki.JoinPath("a", title)
+		_, err := DB.CreateLocalArticle(ctx, userId, domain.ArticleFed{
+			ArticleCore: domain.ArticleCore{Title: title, Content: content, Language: "it", MediaType: "text/markdown"},
+			ApID:        id,
+		}, domain.Revision{Diff: "@@ -0,0 +1 @@\n+" + content + "\n"})
+		if err != nil {
+			t.Fatalf("failed to create %s: %s", title, err)
+		}
+		return id
+	}
+	unlinked := func(id *url.URL) bool {
+		articles, err := DB.ListUnlinkedArticles(ctx)
+		if err != nil {
+			t.Fatalf("failed to list unlinked articles: %s", err)
+		}
+		for _, article := range articles {
+			if article.ApID.String() == id.String() {
+				return true
+			}
+		}
+		return false
+	}
+	expect := func(what string, got []string, err error, want ...string) {
+		t.Helper()
+		if err != nil || strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("expected %s %q, got %q (%v)", what, want, got, err)
+		}
+	}
+
+	sidereus := create("Sidereus", "[[Io]] and [[Europa]]")
+	if !unlinked(sidereus) {
+		t.Errorf("expected the links of a new article not to be stored")
+	}
+	if err = DB.SetArticleLinks(ctx, sidereus, []string{"Io", "Europa"}); err != nil {
+		t.Fatalf("failed to set links: %s", err)
+	}
+	if unlinked(sidereus) {
+		t.Errorf("expected the links of the article to be stored")
+	}
+
+	missing, err := DB.MissingLinks(ctx, sidereus)
+	expect("red links", missing, err, "Europa", "Io")
+	backlinks, err := DB.Backlinks(ctx, "Io")
+	expect("backlinks", backlinks, err, "Sidereus")
+
+	// Creating a linked article turns its links blue.
+	create("Io", "A moon")
+	missing, err = DB.MissingLinks(ctx, sidereus)
+	expect("red links", missing, err, "Europa")
+
+	// Saving the article again replaces its links.
+	if err = DB.SetArticleLinks(ctx, sidereus, []string{"Europa"}); err != nil {
+		t.Fatalf("failed to set links: %s", err)
+	}
+	backlinks, err = DB.Backlinks(ctx, "Io")
+	expect("backlinks", backlinks, err)
+
+	// An article without links is not listed again once its links were stored.
+	callisto := create("Callisto", "`[[code]]`")
+	if err = DB.SetArticleLinks(ctx, callisto, nil); err != nil {
+		t.Fatalf("failed to set links: %s", err)
+	}
+	if unlinked(callisto) {
+		t.Errorf("expected an article without links not to be listed once indexed")
+	}
+
+	// The links of a deleted article are deleted along with it.
+	if err = DB.DeleteLocalArticle(ctx, sidereus); err != nil {
+		t.Fatalf("failed to delete article: %s", err)
+	}
+	backlinks, err = DB.Backlinks(ctx, "Europa")
+	expect("backlinks", backlinks, err)
+}
+
+func TestInstancePolicy(t *testing.T) {
+	if err := DB.SetInstancePolicy(ctx, "Evil.Example", domain.PolicySuspend); err != nil {
+		t.Fatalf("failed to set policy: %s", err)
+	}
+
+	// Hostnames differing only in case or in a default port name the same server.
+	for _, host := range []string{"evil.example", "EVIL.example", "evil.example:443", "Evil.Example:80", "evil.example."} {
+		if policy, err := DB.GetInstancePolicy(ctx, host); err != nil || policy != domain.PolicySuspend {
+			t.Errorf("expected %s to be suspended, got %q (%v)", host, policy, err)
+		}
+	}
+	if policy, err := DB.GetInstancePolicy(ctx, "evil.example:8443"); err != nil || policy != "" {
+		t.Errorf("expected a server on another port to have no policy, got %q (%v)", policy, err)
+	}
+
+	id, err := DB.GetInstanceIdOrCreate(ctx, "EVIL.EXAMPLE:443")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var n int
+	if err = database.QueryRow("SELECT COUNT(*) FROM instances WHERE id = ? AND hostname = 'evil.example'", id).Scan(&n); err != nil || n != 1 {
+		t.Errorf("expected a single instance stored as evil.example, got %d (%v)", n, err)
+	}
+
+	_, err = database.Exec("INSERT INTO instances (hostname) VALUES ('evil.example')")
+	if err == nil {
+		t.Error("expected hostnames to be unique")
+	}
+}
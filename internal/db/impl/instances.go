@@ -0,0 +1,112 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
+)
+
+func (d *dbImpl) ListInstances(ctx context.Context) ([]domain.Instance, error) {
+	rows, err := d.queries.ListInstances(ctx)
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	instances := make([]domain.Instance, 0, len(rows))
+	for _, r := range rows {
+		instances = append(instances, domain.Instance{
+			Hostname:        r.Hostname,
+			Policy:          domain.Policy(r.Policy.String),
+			InterwikiPrefix: r.InterwikiPrefix.String,
+			Users:           r.Users,
+			Articles:        r.Articles,
+			Created:         time.Unix(r.Created, 0),
+		})
+	}
+	return instances, nil
+}
+
+func (d *dbImpl) InstanceExists(ctx context.Context, hostname string) (bool, error) {
+	_, err := d.queries.GetInstanceId(ctx, validate.NormalizeHost(hostname))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, d.HandleError(err)
+}
+
+func (d *dbImpl) GetInstancePolicy(ctx context.Context, hostname string) (domain.Policy, error) {
+	policy, err := d.queries.GetInstancePolicy(ctx, validate.NormalizeHost(hostname))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return domain.Policy(policy.String), d.HandleError(err)
+}
+
+func (d *dbImpl) SetInstancePolicy(ctx context.Context, hostname string, policy domain.Policy) error {
+	hostname = validate.NormalizeHost(hostname)
+	if _, err := d.GetInstanceIdOrCreate(ctx, hostname); err != nil {
+		return err
+	}
+
+	err := d.queries.SetInstancePolicy(ctx, queries.SetInstancePolicyParams{
+		Policy: sql.NullString{
+			Valid:  policy != "",
+			String: string(policy),
+		},
+		Hostname: hostname,
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) SetInterwikiPrefix(ctx context.Context, hostname, prefix string) error {
+	hostname = validate.NormalizeHost(hostname)
+	if _, err := d.GetInstanceIdOrCreate(ctx, hostname); err != nil {
+		return err
+	}
+
+	err := d.queries.SetInterwikiPrefix(ctx, queries.SetInterwikiPrefixParams{
+		InterwikiPrefix: sql.NullString{
+			Valid:  prefix != "",
+			String: prefix,
+		},
+		Hostname: hostname,
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) InterwikiPrefixes(ctx context.Context) (map[string]string, error) {
+	rows, err := d.queries.ListInterwikiPrefixes(ctx)
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	prefixes := make(map[string]string, len(rows))
+	for _, r := range rows {
+		prefixes[r.InterwikiPrefix.String] = r.Hostname
+	}
+	return prefixes, nil
+}
+
+func (d *dbImpl) GetStats(ctx context.Context, month, halfyear time.Time) (domain.Stats, error) {
+	r, err := d.queries.GetStats(ctx, queries.GetStatsParams{
+		Month:    month.Unix(),
+		Halfyear: halfyear.Unix(),
+	})
+	if err != nil {
+		return domain.Stats{}, d.HandleError(err)
+	}
+
+	return domain.Stats{
+		Users:          r.Users,
+		ActiveMonth:    r.ActiveMonth,
+		ActiveHalfyear: r.ActiveHalfyear,
+		Articles:       r.Articles,
+		Revisions:      r.Revisions,
+		Comments:       r.Comments,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package impl
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+func (d *dbImpl) SetArticleLinks(ctx context.Context, article *url.URL, targets []string) error {
+	return d.WithTx(func(tx *queries.Queries) error {
+		articleId, err := tx.GetLocalArticleId(ctx, article.String())
+		if err != nil {
+			return err
+		}
+
+		if err = tx.DeleteArticleLinks(ctx, articleId); err != nil {
+			return err
+		}
+
+		for _, target := range targets {
+			err = tx.InsertArticleLink(ctx, queries.InsertArticleLinkParams{
+				ArticleID: articleId,
+				Target:    target,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return tx.SetLinksIndexed(ctx, articleId)
+	})
+}
+
+func (d *dbImpl) MissingLinks(ctx context.Context, article *url.URL) ([]string, error) {
+	titles, err := d.queries.ListMissingLinks(ctx, article.String())
+	return titles, d.HandleError(err)
+}
+
+func (d *dbImpl) ExistingArticles(ctx context.Context, titles []string) ([]string, error) {
+	if len(titles) == 0 {
+		return nil, nil
+	}
+	existing, err := d.queries.ListExistingArticles(ctx, titles)
+	return existing, d.HandleError(err)
+}
+
+func (d *dbImpl) Backlinks(ctx context.Context, title string) ([]string, error) {
+	titles, err := d.queries.ListBacklinks(ctx, title)
+	return titles, d.HandleError(err)
+}
+
+func (d *dbImpl) ListUnlinkedArticles(ctx context.Context) ([]domain.ArticleFed, error) {
+	rows, err := d.queries.ListUnlinkedArticles(ctx)
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	articles := make([]domain.ArticleFed, 0, len(rows))
+	for _, row := range rows {
+		apId, err := url.Parse(row.ApID)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, domain.ArticleFed{
+			ArticleCore: domain.ArticleCore{
+				MediaType: row.MediaType,
+				Content:   row.Content,
+			},
+			ApID: apId,
+		})
+	}
+	return articles, nil
+}
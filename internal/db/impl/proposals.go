@@ -0,0 +1,101 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+func (d *dbImpl) InsertIncomingProposal(ctx context.Context, revision domain.RevisionFed) error {
+	created := time.Now().Unix()
+	if !revision.Created.IsZero() {
+		created = revision.Created.Unix()
+	}
+
+	var basedOn sql.NullString
+	if revision.BasedOn != nil {
+		basedOn = sql.NullString{Valid: true, String: revision.BasedOn.String()}
+	}
+
+	err := d.queries.InsertIncomingProposal(ctx, queries.InsertIncomingProposalParams{
+		ApID:    sql.NullString{Valid: true, String: revision.ApID.String()},
+		Article: revision.Article.String(),
+		Author:  revision.Author.String(),
+		Summary: sql.NullString{
+			Valid:  revision.Summary != "",
+			String: revision.Summary,
+		},
+		Diff:    revision.Diff,
+		BasedOn: basedOn,
+		Created: created,
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) ListPendingProposals(ctx context.Context) ([]domain.Revision, error) {
+	list, err := d.queries.ListPendingProposals(ctx)
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	proposals := make([]domain.Revision, 0, len(list))
+	for _, r := range list {
+		proposals = append(proposals, domain.Revision{
+			ID:       r.ID,
+			Title:    r.Title,
+			Proposal: true,
+			Diff:     r.Diff,
+			Summary:  r.Summary.String,
+			Username: r.Username,
+			Domain:   r.Domain.String,
+			Created:  r.Created,
+		})
+	}
+	return proposals, nil
+}
+
+func (d *dbImpl) GetPendingProposal(ctx context.Context, id int64) (*url.URL, error) {
+	apId, err := d.queries.GetPendingProposalApId(ctx, id)
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+
+	u, err := url.Parse(apId.String)
+	return u, d.HandleError(err)
+}
+
+func (d *dbImpl) AcceptProposal(ctx context.Context, id, reviewerId int64, diff, content string) (revision *url.URL, err error) {
+	err = d.WithTx(func(tx *queries.Queries) error {
+		n, err := tx.AcceptProposal(ctx, queries.AcceptProposalParams{
+			Reviewer: sql.NullInt64{Valid: true, Int64: reviewerId},
+			Diff:     diff,
+			ID:       id,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+
+		// The revision now belongs to the history of the local article.
+		if revision, err = setRevisionApId(ctx, tx, id); err != nil {
+			return err
+		}
+
+		articleId, err := tx.GetRevisionArticleId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateArticle(ctx, queries.UpdateArticleParams{
+			Content: content,
+			ID:      articleId,
+		})
+	})
+	return
+}
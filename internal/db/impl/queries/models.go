@@ -20,6 +20,14 @@ type Account struct {
 	LastUpdated   string
 }
 
+type Announce struct {
+	ID        int64
+	ApID      string
+	UserID    int64
+	ArticleID int64
+	Created   int64
+}
+
 type ApprovalRequest struct {
 	ID        int64
 	AccountID int64
@@ -30,20 +38,24 @@ type ApprovalRequest struct {
 }
 
 type Article struct {
-	ID          int64
-	Local       bool
-	ApID        string
-	Url         sql.NullString
-	InstanceID  sql.NullInt64
-	Language    string
-	MediaType   string
-	Title       string
-	Protected   bool
-	Summary     sql.NullString
-	Content     string
-	Created     int64
-	LastUpdated int64
-	LastFetched sql.NullInt64
+	ID           int64
+	Local        bool
+	ApID         string
+	Url          sql.NullString
+	InstanceID   sql.NullInt64
+	Language     string
+	MediaType    string
+	Title        string
+	Protected    bool
+	Summary      sql.NullString
+	Content      string
+	Created      int64
+	LastUpdated  int64
+	LastFetched  sql.NullInt64
+	PublicKey    sql.NullString
+	PrivateKey   sql.NullString
+	ForkedFrom   sql.NullString
+	LinksIndexed bool
 }
 
 type ArticleFile struct {
@@ -51,6 +63,37 @@ type ArticleFile struct {
 	FileID    int64
 }
 
+type ArticleLink struct {
+	ID        int64
+	ArticleID int64
+	Target    string
+}
+
+type Comment struct {
+	ID        int64
+	ApID      sql.NullString
+	Url       sql.NullString
+	ArticleID int64
+	UserID    int64
+	InReplyTo sql.NullInt64
+	Content   string
+	Local     bool
+	Hidden    bool
+	Created   int64
+}
+
+type Delivery struct {
+	ID          int64
+	Actor       string
+	Inbox       string
+	Activity    string
+	Attempts    int64
+	NextAttempt int64
+	LastError   sql.NullString
+	Dead        bool
+	Created     int64
+}
+
 type File struct {
 	ID         int64
 	Digest     string
@@ -67,13 +110,24 @@ type File struct {
 	Created    int64
 }
 
+type Follow struct {
+	ID       int64
+	ApID     sql.NullString
+	Follower int64
+	Followee string
+	Accepted bool
+	Created  int64
+}
+
 type Instance struct {
-	ID        int64
-	Hostname  string
-	PublicKey sql.NullString
-	Inbox     sql.NullString
-	Created   int64
-	Updated   int64
+	ID              int64
+	Hostname        string
+	PublicKey       sql.NullString
+	Inbox           sql.NullString
+	Created         int64
+	Updated         int64
+	Policy          sql.NullString
+	InterwikiPrefix sql.NullString
 }
 
 type Invitation struct {
@@ -85,6 +139,14 @@ type Invitation struct {
 	Created string
 }
 
+type Like struct {
+	ID        int64
+	ApID      string
+	UserID    int64
+	ArticleID int64
+	Created   int64
+}
+
 type Revision struct {
 	ID         int64
 	ApID       sql.NullString
@@ -99,25 +161,38 @@ type Revision struct {
 	Prev       sql.NullInt64
 	BasedOn    sql.NullInt64
 	Created    int64
+	Proposal   bool
+}
+
+type Tombstone struct {
+	ID         int64
+	ApID       string
+	FormerType string
+	Deleted    int64
 }
 
 type User struct {
-	ID          int64
-	Bot         bool
-	Local       bool
-	ApID        string
-	Url         sql.NullString
-	Username    string
-	Name        string
-	Domain      sql.NullString
-	Summary     sql.NullString
-	Inbox       string
-	Outbox      string
-	Followers   string
-	PublicKey   string
-	PrivateKey  string
-	Trusted     bool
-	Created     int64
-	LastUpdated int64
-	LastFetched sql.NullInt64
+	ID                 int64
+	Bot                bool
+	Local              bool
+	ApID               string
+	Url                sql.NullString
+	Username           string
+	Name               string
+	Domain             sql.NullString
+	Summary            sql.NullString
+	Inbox              string
+	Outbox             sql.NullString
+	Followers          sql.NullString
+	PublicKey          string
+	PrivateKey         string
+	Trusted            bool
+	Created            int64
+	LastUpdated        int64
+	LastFetched        sql.NullInt64
+	KeyID              sql.NullString
+	PreviousKeyID      sql.NullString
+	PreviousPublicKey  sql.NullString
+	PreviousKeyExpires sql.NullInt64
+	SharedInbox        sql.NullString
 }
@@ -52,7 +52,15 @@ SELECT
     content,
     protected,
     media_type,
-    language
+    language,
+    forked_from,
+    (SELECT COUNT(*) FROM likes l WHERE l.article_id = articles.id) AS likes,
+    (SELECT COUNT(*) FROM announces s WHERE s.article_id = articles.id) AS shares,
+    cast(coalesce((
+        SELECT r.id FROM revisions r
+        WHERE r.article_id = articles.id AND r.published
+        ORDER BY r.id DESC LIMIT 1
+    ), 0) as int) AS revision
 FROM
     articles
 where local AND title = ?1
@@ -69,8 +77,9 @@ INSERT INTO articles (
     language,
     media_type,
     title,
-    content
-) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id;
+    content,
+    forked_from
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;
 
 -- name: EditArticle :one
 INSERT INTO revisions (
@@ -95,7 +104,7 @@ WHERE lower(a.title) = lower(@title)
 ORDER BY r.created DESC
 LIMIT 1;
 
--- name: InsertRevision :exec
+-- name: InsertRevision :one
 INSERT INTO revisions (
     ap_id,
     article_id,
@@ -104,7 +113,13 @@ INSERT INTO revisions (
     diff,
     published,
     prev
-) VALUES (?1, ?2, ?3, ?4, ?5, true, ?6);
+) VALUES (?1, ?2, ?3, ?4, ?5, true, ?6) RETURNING id;
+
+-- name: SetLocalRevisionApId :one
+UPDATE revisions
+SET ap_id = (SELECT a.ap_id FROM articles a WHERE a.id = revisions.article_id) || '/history/' || revisions.id
+WHERE revisions.id = ?1
+RETURNING ap_id;
 
 -- name: UpdateArticle :exec
 UPDATE articles
@@ -120,17 +135,38 @@ SELECT content FROM articles WHERE id = ?;
 SELECT
     r.id,
     r.reviewed,
+    r.published,
+    r.proposal,
     r.summary,
     a.title,
     u.username,
+    u.domain,
     r.created
 FROM (
-    SELECT id, title from articles WHERE lower(title) = lower(@title) LIMIT 1
+    SELECT id, title from articles WHERE local AND lower(title) = lower(@title) LIMIT 1
 ) a
 JOIN revisions r ON r.article_id = a.id
 JOIN users u ON r.user_id = u.id
 ORDER BY r.created DESC;
 
+-- name: GetForeignRevisionList :many
+SELECT
+    r.id,
+    r.reviewed,
+    r.published,
+    r.proposal,
+    r.summary,
+    a.title,
+    u.username,
+    u.domain,
+    r.created
+FROM articles a
+JOIN instances i ON i.id = a.instance_id
+JOIN revisions r ON r.article_id = a.id
+JOIN users u ON r.user_id = u.id
+WHERE NOT a.local AND lower(a.title) = lower(@title) AND i.hostname = @hostname
+ORDER BY r.created DESC;
+
 -- name: GetLocalUserData :one
 SELECT
     id,
@@ -144,12 +180,15 @@ WHERE local AND username = lower(?);
 -- name: GetForeignUserData :one
 SELECT
     id,
+    ap_id,
     username,
     name,
     domain,
     url,
     local,
-    summary
+    summary,
+    outbox,
+    last_fetched
 FROM users
 WHERE username = lower(?) AND NOT local AND domain = ?;
 
@@ -157,30 +196,63 @@ WHERE username = lower(?) AND NOT local AND domain = ?;
 SELECT
     r.id,
     a.title,
+    i.hostname,
     r.summary,
     r.reviewed,
+    r.published,
+    r.proposal,
     r.created
 FROM revisions r
 JOIN articles a
 ON a.id = r.article_id
-WHERE r.user_id = ?;
+LEFT JOIN instances i ON i.id = a.instance_id AND NOT a.local
+WHERE r.user_id = ?
+ORDER BY r.created DESC;
 
 -- name: GetUserFull :one
 SELECT
     ap_id,
+    bot,
     url,
     username,
     name,
+    domain,
     summary,
     inbox,
     outbox,
     followers,
     public_key,
+    key_id,
+    previous_key_id,
+    previous_public_key,
+    previous_key_expires,
     created,
     last_updated
 FROM users
 WHERE local AND ap_id = ?;
 
+-- name: GetForeignUserFull :one
+SELECT
+    ap_id,
+    bot,
+    url,
+    username,
+    name,
+    domain,
+    summary,
+    inbox,
+    outbox,
+    followers,
+    public_key,
+    key_id,
+    previous_key_id,
+    previous_public_key,
+    previous_key_expires,
+    created,
+    last_updated
+FROM users
+WHERE NOT local AND ap_id = ?;
+
 -- name: UserExists :one
 SELECT COUNT(id) == 1 FROM users WHERE ap_id = ?;
 
@@ -188,7 +260,9 @@ SELECT COUNT(id) == 1 FROM users WHERE ap_id = ?;
 SELECT ap_id from users where outbox = ?;
 
 -- name: UserIdByInbox :one
-SELECT ap_id from users where inbox = ?;
+SELECT ap_id from users where inbox = ?1
+UNION ALL
+SELECT ap_id from articles where local AND ap_id || '/inbox' = ?1;
 
 -- name: OutboxForInbox :one
 SELECT outbox from users where inbox = ?;
@@ -238,3 +312,821 @@ SELECT
 FROM files f
 LEFT JOIN users u ON u.id = f.uploaded_by
 WHERE f.digest = ?;
+
+-- name: GetPublicKey :one
+SELECT u.public_key FROM users u WHERE u.ap_id = ?1
+UNION ALL
+SELECT a.public_key FROM articles a WHERE a.local AND a.ap_id = ?1 AND a.public_key IS NOT NULL;
+
+-- name: UpsertForeignUser :one
+INSERT INTO users (
+    local,
+    bot,
+    ap_id,
+    url,
+    username,
+    name,
+    domain,
+    summary,
+    inbox,
+    outbox,
+    followers,
+    public_key,
+    shared_inbox,
+    trusted,
+    last_fetched
+) VALUES (false, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, false, cast(strftime('%s','now') as int))
+ON CONFLICT (ap_id) DO UPDATE SET
+    bot = excluded.bot,
+    url = excluded.url,
+    username = excluded.username,
+    name = excluded.name,
+    summary = excluded.summary,
+    inbox = excluded.inbox,
+    outbox = excluded.outbox,
+    followers = excluded.followers,
+    public_key = excluded.public_key,
+    shared_inbox = excluded.shared_inbox,
+    last_updated = cast(strftime('%s','now') as int),
+    last_fetched = excluded.last_fetched
+RETURNING id;
+
+-- name: SetInstanceKey :exec
+UPDATE instances
+SET
+    public_key = ?,
+    inbox = ?,
+    updated = cast(strftime('%s','now') as int)
+WHERE hostname = ?;
+
+-- name: InsertDelivery :exec
+INSERT INTO deliveries (actor, inbox, activity) VALUES (?, ?, ?);
+
+-- name: ListDueDeliveries :many
+SELECT
+    id,
+    actor,
+    inbox,
+    activity,
+    attempts
+FROM deliveries
+WHERE NOT dead AND next_attempt <= @now
+ORDER BY next_attempt
+LIMIT @limit;
+
+-- name: LeaseDelivery :exec
+UPDATE deliveries SET next_attempt = ? WHERE id = ?;
+
+-- name: DeleteDelivery :exec
+DELETE FROM deliveries WHERE id = ?;
+
+-- name: RescheduleDelivery :exec
+UPDATE deliveries
+SET
+    attempts = ?,
+    next_attempt = ?,
+    last_error = ?
+WHERE id = ?;
+
+-- name: KillDelivery :exec
+UPDATE deliveries
+SET
+    attempts = ?,
+    last_error = ?,
+    dead = true
+WHERE id = ?;
+
+-- name: GetSigningKey :one
+SELECT u.private_key, u.key_id FROM users u WHERE u.local AND u.ap_id = ?1
+UNION ALL
+SELECT a.private_key, NULL FROM articles a WHERE a.local AND a.ap_id = ?1 AND a.private_key IS NOT NULL;
+
+-- name: RotateUserKey :execrows
+UPDATE users SET
+    previous_key_id = key_id,
+    previous_public_key = public_key,
+    previous_key_expires = @previous_key_expires,
+    key_id = @key_id,
+    public_key = @public_key,
+    private_key = @private_key,
+    last_updated = cast(strftime('%s','now') as int)
+WHERE local AND ap_id = @ap_id;
+
+-- name: ObjectExists :one
+SELECT
+    EXISTS (SELECT 1 FROM users u WHERE u.ap_id = ?1)
+    OR EXISTS (SELECT 1 FROM articles a WHERE a.ap_id = ?1)
+    OR EXISTS (SELECT 1 FROM revisions r WHERE r.ap_id = ?1)
+    OR EXISTS (SELECT 1 FROM files f WHERE f.ap_id = ?1);
+
+-- name: DeleteForeignUser :execrows
+DELETE FROM users
+WHERE users.ap_id = ?1
+    AND NOT users.local
+    AND NOT EXISTS (SELECT 1 FROM revisions r WHERE r.user_id = users.id)
+    AND NOT EXISTS (SELECT 1 FROM comments c WHERE c.user_id = users.id);
+
+-- name: GetUserIdByApId :one
+SELECT id FROM users WHERE ap_id = ?;
+
+-- name: GetArticleByApId :one
+SELECT
+    a.id,
+    a.local,
+    a.ap_id,
+    a.url,
+    a.language,
+    a.media_type,
+    a.title,
+    a.protected,
+    a.summary,
+    a.content,
+    a.created,
+    a.last_updated,
+    a.last_fetched,
+    a.public_key,
+    a.forked_from,
+    i.hostname,
+    (SELECT COUNT(*) FROM likes l WHERE l.article_id = a.id) AS likes,
+    (SELECT COUNT(*) FROM announces s WHERE s.article_id = a.id) AS shares
+FROM articles a
+LEFT JOIN instances i ON i.id = a.instance_id
+WHERE a.ap_id = ?;
+
+-- name: GetForeignArticleByTitle :one
+SELECT
+    a.id,
+    a.local,
+    a.ap_id,
+    a.url,
+    a.language,
+    a.media_type,
+    a.title,
+    a.protected,
+    a.summary,
+    a.content,
+    a.created,
+    a.last_updated,
+    a.last_fetched,
+    a.public_key,
+    a.forked_from,
+    i.hostname,
+    (SELECT COUNT(*) FROM likes l WHERE l.article_id = a.id) AS likes,
+    (SELECT COUNT(*) FROM announces s WHERE s.article_id = a.id) AS shares
+FROM articles a
+LEFT JOIN instances i ON i.id = a.instance_id
+WHERE NOT a.local AND lower(a.title) = lower(@title) AND i.hostname = @hostname;
+
+-- name: ListArticleAuthors :many
+SELECT u.ap_id
+FROM revisions r
+JOIN users u ON u.id = r.user_id
+WHERE r.article_id = ? AND r.published
+GROUP BY u.ap_id
+ORDER BY MIN(r.id);
+
+-- name: ListArticleFiles :many
+SELECT
+    f.ap_id,
+    f.name,
+    f.filename,
+    f.type,
+    f.mime_type,
+    f.size_bytes,
+    f.local,
+    f.url,
+    f.created,
+    u.ap_id AS uploader
+FROM article_files af
+JOIN files f ON f.id = af.file_id
+LEFT JOIN users u ON u.id = f.uploaded_by
+WHERE af.article_id = ?
+ORDER BY f.id;
+
+-- name: UpsertForeignArticle :one
+INSERT INTO articles (
+    local,
+    ap_id,
+    url,
+    instance_id,
+    language,
+    media_type,
+    title,
+    summary,
+    content,
+    forked_from,
+    last_fetched
+) VALUES (false, ?, ?, ?, ?, ?, ?, ?, ?, ?, cast(strftime('%s','now') as int))
+ON CONFLICT (ap_id) DO UPDATE SET
+    url = excluded.url,
+    language = excluded.language,
+    media_type = excluded.media_type,
+    title = excluded.title,
+    summary = excluded.summary,
+    content = excluded.content,
+    forked_from = excluded.forked_from,
+    last_updated = cast(strftime('%s','now') as int),
+    last_fetched = excluded.last_fetched
+WHERE NOT local
+RETURNING id;
+
+-- name: GetForeignArticleId :one
+SELECT id FROM articles WHERE ap_id = ? AND NOT local;
+
+-- name: DeleteArticleFiles :exec
+DELETE FROM article_files WHERE article_id = ?;
+
+-- name: DeleteArticleRevisions :exec
+DELETE FROM revisions WHERE article_id = ?;
+
+-- name: DeleteArticle :exec
+DELETE FROM articles WHERE id = ?;
+
+-- name: DeleteArticleComments :exec
+DELETE FROM comments WHERE article_id = ?;
+
+-- name: DeleteArticleLikes :exec
+DELETE FROM likes WHERE article_id = ?;
+
+-- name: DeleteArticleAnnounces :exec
+DELETE FROM announces WHERE article_id = ?;
+
+-- name: DeleteFollowsOf :exec
+DELETE FROM follows WHERE followee = ?;
+
+-- name: GetRevisionByApId :one
+SELECT
+    r.ap_id,
+    r.summary,
+    r.diff,
+    r.published,
+    r.reviewed,
+    r.proposal,
+    r.prev IS NULL AND NOT r.proposal AS initial,
+    r.created,
+    a.ap_id AS article,
+    u.ap_id AS author,
+    b.ap_id AS based_on,
+    v.ap_id AS reviewer
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+LEFT JOIN revisions b ON b.id = r.based_on
+LEFT JOIN users v ON v.id = r.reviewer
+WHERE r.ap_id = ?;
+
+-- name: InsertProposal :one
+INSERT INTO revisions (
+    article_id,
+    user_id,
+    summary,
+    diff,
+    proposal,
+    based_on
+) VALUES (?, ?, ?, ?, TRUE, ?) RETURNING id;
+
+-- name: InsertIncomingProposal :exec
+INSERT INTO revisions (
+    ap_id,
+    article_id,
+    user_id,
+    summary,
+    diff,
+    proposal,
+    based_on,
+    created
+) VALUES (
+    @ap_id,
+    (SELECT a.id FROM articles a WHERE a.ap_id = @article AND a.local),
+    (SELECT u.id FROM users u WHERE u.ap_id = @author),
+    @summary,
+    @diff,
+    TRUE,
+    (
+        SELECT b.id FROM revisions b
+        JOIN articles ba ON ba.id = b.article_id
+        WHERE b.ap_id = @based_on AND ba.ap_id = @article AND b.published
+    ),
+    @created
+)
+ON CONFLICT (ap_id) DO NOTHING;
+
+-- name: ListPendingProposals :many
+SELECT
+    r.id,
+    r.summary,
+    r.diff,
+    a.title,
+    u.username,
+    u.domain,
+    r.created
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+WHERE a.local AND r.proposal AND NOT r.reviewed
+ORDER BY r.id;
+
+-- name: GetPendingProposalApId :one
+SELECT r.ap_id
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+WHERE r.id = ? AND a.local AND r.proposal AND NOT r.reviewed;
+
+-- name: AcceptProposal :execrows
+UPDATE revisions
+SET
+    reviewed = TRUE,
+    published = TRUE,
+    reviewer = @reviewer,
+    reviewed_at = datetime('now'),
+    diff = @diff,
+    prev = (
+        SELECT p.id FROM revisions p
+        WHERE p.article_id = revisions.article_id AND p.published
+        ORDER BY p.id DESC LIMIT 1
+    )
+WHERE revisions.id = @id AND revisions.proposal AND NOT revisions.reviewed;
+
+-- name: GetRevisionArticleId :one
+SELECT article_id FROM revisions WHERE id = ?;
+
+-- name: SetRevisionApId :exec
+UPDATE revisions SET ap_id = ? WHERE id = ?;
+
+-- name: ReviewRevision :execrows
+UPDATE revisions
+SET
+    reviewed = TRUE,
+    published = @accepted,
+    reviewer = @reviewer,
+    reviewed_at = datetime('now')
+WHERE ap_id = @ap_id AND proposal AND NOT reviewed;
+
+-- name: InsertForeignRevision :exec
+INSERT INTO revisions (
+    ap_id,
+    article_id,
+    user_id,
+    summary,
+    diff,
+    reviewed,
+    published,
+    prev,
+    created
+) VALUES (?, ?, ?, ?, ?, true, true, ?, ?)
+ON CONFLICT (ap_id) DO NOTHING;
+
+-- name: ListArticleRevisions :many
+SELECT
+    r.id,
+    r.ap_id,
+    r.summary,
+    r.diff,
+    r.prev IS NULL AND NOT r.proposal AS initial,
+    r.created,
+    u.ap_id AS author
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+WHERE a.ap_id = ? AND r.published
+ORDER BY r.created, r.id;
+
+-- name: ImportRevision :one
+INSERT INTO revisions (
+    article_id,
+    user_id,
+    summary,
+    diff,
+    reviewed,
+    published,
+    prev,
+    created
+) VALUES (?, ?, ?, ?, true, true, ?, ?) RETURNING id;
+
+-- name: GetLatestRevisionId :one
+SELECT id FROM revisions WHERE article_id = ? AND published ORDER BY id DESC LIMIT 1;
+
+-- name: DeleteForeignRevision :execrows
+DELETE FROM revisions
+WHERE revisions.ap_id = ?1
+    AND revisions.article_id IN (SELECT a.id FROM articles a WHERE NOT a.local);
+
+-- name: GetFileByApId :one
+SELECT
+    f.ap_id,
+    f.name,
+    f.filename,
+    f.type,
+    f.mime_type,
+    f.size_bytes,
+    f.local,
+    f.url,
+    f.created,
+    u.ap_id AS uploader
+FROM files f
+LEFT JOIN users u ON u.id = f.uploaded_by
+WHERE f.ap_id = ?;
+
+-- name: UpsertForeignFile :exec
+INSERT INTO files (
+    local,
+    ap_id,
+    name,
+    type,
+    mime_type,
+    size_bytes,
+    url
+) VALUES (false, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (ap_id) DO UPDATE SET
+    name = excluded.name,
+    type = excluded.type,
+    mime_type = excluded.mime_type,
+    size_bytes = excluded.size_bytes,
+    url = excluded.url
+WHERE NOT local;
+
+-- name: DeleteForeignFileLinks :exec
+DELETE FROM article_files
+WHERE file_id IN (SELECT f.id FROM files f WHERE f.ap_id = ?1 AND NOT f.local);
+
+-- name: DeleteForeignFile :execrows
+DELETE FROM files WHERE ap_id = ? AND NOT local;
+
+-- name: UpsertFollow :exec
+INSERT INTO follows (ap_id, follower, followee, accepted)
+VALUES (?, (SELECT id FROM users WHERE users.ap_id = @follower), @followee, ?)
+ON CONFLICT (follower, followee) DO UPDATE SET
+    ap_id = excluded.ap_id,
+    accepted = excluded.accepted;
+
+-- name: DeleteFollow :execrows
+DELETE FROM follows
+WHERE follows.followee = @followee
+    AND follows.follower = (SELECT u.id FROM users u WHERE u.ap_id = @follower);
+
+-- name: ListFollowers :many
+SELECT u.ap_id
+FROM follows f
+JOIN users u ON u.id = f.follower
+WHERE f.followee = ? AND f.accepted
+ORDER BY f.id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountFollowers :one
+SELECT COUNT(*) FROM follows WHERE followee = ? AND accepted;
+
+-- name: ListFollowerInboxes :many
+SELECT DISTINCT u.inbox
+FROM follows f
+JOIN users u ON u.id = f.follower
+WHERE f.followee IN (sqlc.slice('followees')) AND f.accepted AND NOT u.local AND u.inbox IS NOT NULL;
+
+-- name: ListLocalRecipients :many
+SELECT u.inbox FROM users u
+WHERE u.local AND (u.ap_id = ?1 OR u.followers = ?1)
+UNION
+SELECT a.ap_id || '/inbox' FROM articles a
+WHERE a.local AND (a.ap_id = ?1 OR a.ap_id || '/followers' = ?1)
+UNION
+SELECT lu.inbox FROM follows f
+JOIN users lu ON lu.id = f.follower
+WHERE lu.local AND f.accepted AND (
+    f.followee = ?1
+    OR EXISTS (SELECT 1 FROM users ru WHERE ru.ap_id = f.followee AND ru.followers = ?1)
+);
+
+-- name: ListSharedInboxes :many
+SELECT inbox, shared_inbox FROM users WHERE inbox IN (sqlc.slice('inboxes')) AND shared_inbox IS NOT NULL;
+
+-- name: ListFollowing :many
+SELECT f.followee
+FROM follows f
+JOIN users u ON u.id = f.follower
+WHERE u.ap_id = ? AND f.accepted
+ORDER BY f.id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountFollowing :one
+SELECT COUNT(*)
+FROM follows f
+JOIN users u ON u.id = f.follower
+WHERE u.ap_id = ? AND f.accepted;
+
+-- name: ListUserRevisions :many
+SELECT
+    r.id,
+    r.ap_id,
+    r.summary,
+    r.diff,
+    r.prev IS NULL AND NOT r.proposal AS initial,
+    r.created,
+    a.ap_id AS article
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+WHERE u.ap_id = ? AND r.published
+ORDER BY r.id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountUserRevisions :one
+SELECT COUNT(*)
+FROM revisions r
+JOIN users u ON u.id = r.user_id
+WHERE u.ap_id = ? AND r.published;
+
+-- name: UpsertLike :exec
+INSERT INTO likes (ap_id, user_id, article_id)
+VALUES (
+    @ap_id,
+    (SELECT u.id FROM users u WHERE u.ap_id = @actor),
+    (SELECT a.id FROM articles a WHERE a.ap_id = @article)
+)
+ON CONFLICT (user_id, article_id) DO UPDATE SET ap_id = excluded.ap_id;
+
+-- name: DeleteLike :execrows
+DELETE FROM likes
+WHERE likes.user_id = (SELECT u.id FROM users u WHERE u.ap_id = @actor)
+    AND likes.article_id = (SELECT a.id FROM articles a WHERE a.ap_id = @article);
+
+-- name: ListLikes :many
+SELECT l.ap_id
+FROM likes l
+JOIN articles a ON a.id = l.article_id
+WHERE a.ap_id = ?
+ORDER BY l.id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountLikes :one
+SELECT COUNT(*)
+FROM likes l
+JOIN articles a ON a.id = l.article_id
+WHERE a.ap_id = ?;
+
+-- name: UpsertAnnounce :exec
+INSERT INTO announces (ap_id, user_id, article_id)
+VALUES (
+    @ap_id,
+    (SELECT u.id FROM users u WHERE u.ap_id = @actor),
+    (SELECT a.id FROM articles a WHERE a.ap_id = @article)
+)
+ON CONFLICT (user_id, article_id) DO UPDATE SET ap_id = excluded.ap_id;
+
+-- name: DeleteAnnounce :execrows
+DELETE FROM announces
+WHERE announces.user_id = (SELECT u.id FROM users u WHERE u.ap_id = @actor)
+    AND announces.article_id = (SELECT a.id FROM articles a WHERE a.ap_id = @article);
+
+-- name: ListAnnounces :many
+SELECT s.ap_id
+FROM announces s
+JOIN articles a ON a.id = s.article_id
+WHERE a.ap_id = ?
+ORDER BY s.id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountAnnounces :one
+SELECT COUNT(*)
+FROM announces s
+JOIN articles a ON a.id = s.article_id
+WHERE a.ap_id = ?;
+
+-- name: IsUserAdmin :one
+SELECT a.admin FROM accounts a WHERE a.user_id = ? LIMIT 1;
+
+-- name: InsertLocalComment :one
+INSERT INTO comments (article_id, user_id, in_reply_to, content, local)
+VALUES (@article_id, @user_id, @in_reply_to, @content, TRUE)
+RETURNING id;
+
+-- name: SetCommentApId :exec
+UPDATE comments SET ap_id = @ap_id, url = @url WHERE id = @id;
+
+-- name: GetLocalArticleId :one
+SELECT id FROM articles WHERE local AND ap_id = ?;
+
+-- name: GetCommentArticleId :one
+SELECT article_id FROM comments WHERE id = ?;
+
+-- name: UpsertForeignComment :exec
+INSERT INTO comments (ap_id, url, article_id, user_id, in_reply_to, content, hidden, created)
+VALUES (
+    @ap_id,
+    @url,
+    (SELECT a.id FROM articles a WHERE a.ap_id = @article),
+    (SELECT u.id FROM users u WHERE u.ap_id = @author),
+    (SELECT p.id FROM comments p WHERE p.ap_id = @in_reply_to),
+    @content,
+    @hidden,
+    @created
+)
+ON CONFLICT (ap_id) DO UPDATE SET
+    url = excluded.url,
+    content = excluded.content;
+
+-- name: GetCommentByApId :one
+SELECT
+    c.id,
+    c.ap_id,
+    c.url,
+    c.content,
+    c.local,
+    c.hidden,
+    c.created,
+    a.ap_id AS article,
+    u.ap_id AS author,
+    p.ap_id AS in_reply_to
+FROM comments c
+JOIN articles a ON a.id = c.article_id
+JOIN users u ON u.id = c.user_id
+LEFT JOIN comments p ON p.id = c.in_reply_to
+WHERE c.ap_id = ?;
+
+-- name: ListArticleComments :many
+SELECT
+    c.id,
+    c.in_reply_to,
+    c.content,
+    c.hidden,
+    c.created,
+    u.username,
+    u.domain
+FROM comments c
+JOIN articles a ON a.id = c.article_id
+JOIN users u ON u.id = c.user_id
+WHERE a.ap_id = ?
+ORDER BY c.id;
+
+-- name: SetCommentHidden :execrows
+UPDATE comments SET hidden = @hidden WHERE id = @id;
+
+-- name: ReparentReplies :exec
+UPDATE comments
+SET in_reply_to = (SELECT p.in_reply_to FROM comments p WHERE p.id = @id)
+WHERE in_reply_to = @id;
+
+-- name: DeleteComment :execrows
+DELETE FROM comments WHERE id = ?;
+
+-- name: ListArticlesWithoutKey :many
+SELECT ap_id FROM articles WHERE local AND private_key IS NULL;
+
+-- name: SetArticleKeys :exec
+UPDATE articles SET public_key = ?, private_key = ? WHERE local AND ap_id = ?;
+
+-- name: CreateInstanceActor :exec
+INSERT INTO users (
+    bot,
+    ap_id,
+    url,
+    username,
+    name,
+    inbox,
+    outbox,
+    followers,
+    public_key,
+    private_key,
+    trusted
+    )
+VALUES (TRUE, ?, ?, ?, ?, ?, ?, ?, ?, ?, FALSE)
+ON CONFLICT (ap_id) DO NOTHING;
+
+-- name: ListLocalRevisions :many
+SELECT
+    r.id,
+    r.ap_id,
+    r.summary,
+    r.diff,
+    r.prev IS NULL AND NOT r.proposal AS initial,
+    r.created,
+    a.ap_id AS article,
+    u.ap_id AS author
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+WHERE a.local AND r.published AND (sqlc.narg('article') IS NULL OR a.ap_id = sqlc.narg('article'))
+ORDER BY r.id DESC
+LIMIT @limit OFFSET @offset;
+
+-- name: CountLocalRevisions :one
+SELECT COUNT(*)
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+WHERE a.local AND r.published AND (sqlc.narg('article') IS NULL OR a.ap_id = sqlc.narg('article'));
+
+-- name: GetInstancePolicy :one
+SELECT policy FROM instances WHERE hostname = ? LIMIT 1;
+
+-- name: SetInstancePolicy :exec
+UPDATE instances
+SET
+    policy = ?,
+    updated = cast(strftime('%s','now') as int)
+WHERE hostname = ?;
+
+-- name: ListInstances :many
+SELECT
+    i.hostname,
+    i.policy,
+    i.interwiki_prefix,
+    i.created,
+    (SELECT COUNT(*) FROM users u WHERE NOT u.local AND u.domain = i.hostname) AS users,
+    (SELECT COUNT(*) FROM articles a WHERE a.instance_id = i.id) AS articles
+FROM instances i
+ORDER BY i.hostname;
+
+-- name: GetStats :one
+SELECT
+    (SELECT COUNT(*) FROM accounts) AS users,
+    (SELECT COUNT(*) FROM users u WHERE u.local AND (
+        EXISTS (SELECT 1 FROM revisions r WHERE r.user_id = u.id AND r.created >= @month)
+        OR EXISTS (SELECT 1 FROM comments c WHERE c.user_id = u.id AND c.created >= @month)
+    )) AS active_month,
+    (SELECT COUNT(*) FROM users u WHERE u.local AND (
+        EXISTS (SELECT 1 FROM revisions r WHERE r.user_id = u.id AND r.created >= @halfyear)
+        OR EXISTS (SELECT 1 FROM comments c WHERE c.user_id = u.id AND c.created >= @halfyear)
+    )) AS active_halfyear,
+    (SELECT COUNT(*) FROM articles a WHERE a.local) AS articles,
+    (SELECT COUNT(*) FROM revisions r JOIN users u ON u.id = r.user_id WHERE u.local AND r.published) AS revisions,
+    (SELECT COUNT(*) FROM comments c WHERE c.local) AS comments;
+
+-- name: InsertTombstone :exec
+INSERT INTO tombstones (ap_id, former_type) VALUES (?, ?)
+ON CONFLICT (ap_id) DO UPDATE SET
+    former_type = excluded.former_type,
+    deleted = cast(strftime('%s','now') as int);
+
+-- name: GetTombstone :one
+SELECT ap_id, former_type, deleted FROM tombstones WHERE ap_id = ?;
+
+-- name: DeleteTombstone :exec
+DELETE FROM tombstones WHERE ap_id = ?;
+
+-- name: GetForeignUserId :one
+SELECT id FROM users WHERE ap_id = ? AND NOT local;
+
+-- name: DeleteUserFollows :exec
+DELETE FROM follows
+WHERE follower = @id
+    OR followee = (SELECT u.ap_id FROM users u WHERE u.id = @id);
+
+-- name: DeleteUserLikes :exec
+DELETE FROM likes WHERE user_id = ?;
+
+-- name: DeleteUserAnnounces :exec
+DELETE FROM announces WHERE user_id = ?;
+
+-- name: HideUserComments :exec
+UPDATE comments SET hidden = true WHERE user_id = ?;
+
+-- name: ClearForeignUser :exec
+UPDATE users SET
+    name = '',
+    summary = NULL,
+    url = NULL,
+    public_key = NULL,
+    last_updated = cast(strftime('%s','now') as int)
+WHERE id = ?;
+
+-- name: DeleteArticleLinks :exec
+DELETE FROM article_links WHERE article_id = ?;
+
+-- name: InsertArticleLink :exec
+INSERT INTO article_links (article_id, target) VALUES (?, ?)
+ON CONFLICT (article_id, target) DO NOTHING;
+
+-- name: ListMissingLinks :many
+SELECT l.target
+FROM article_links l
+JOIN articles a ON a.id = l.article_id
+WHERE a.ap_id = ? AND NOT EXISTS (
+    SELECT 1 FROM articles t WHERE t.local AND t.title = l.target
+)
+ORDER BY l.target;
+
+-- name: ListBacklinks :many
+SELECT a.title
+FROM article_links l
+JOIN articles a ON a.id = l.article_id
+WHERE l.target = ? AND a.local
+ORDER BY a.title;
+
+-- name: ListExistingArticles :many
+SELECT title FROM articles WHERE local AND title IN (sqlc.slice('titles'));
+
+-- name: ListUnlinkedArticles :many
+SELECT ap_id, media_type, content
+FROM articles a
+WHERE a.local AND NOT a.links_indexed AND a.content LIKE '%[[%';
+
+-- name: SetLinksIndexed :exec
+UPDATE articles SET links_indexed = TRUE WHERE id = ?;
+
+-- name: SetInterwikiPrefix :exec
+UPDATE instances
+SET
+    interwiki_prefix = ?,
+    updated = cast(strftime('%s','now') as int)
+WHERE hostname = ?;
+
+-- name: ListInterwikiPrefixes :many
+SELECT interwiki_prefix, hostname FROM instances WHERE interwiki_prefix IS NOT NULL;
@@ -8,8 +8,39 @@ package queries
 import (
 	"context"
 	"database/sql"
+	"strings"
 )
 
+const acceptProposal = `-- name: AcceptProposal :execrows
+UPDATE revisions
+SET
+    reviewed = TRUE,
+    published = TRUE,
+    reviewer = ?1,
+    reviewed_at = datetime('now'),
+    diff = ?2,
+    prev = (
+        SELECT p.id FROM revisions p
+        WHERE p.article_id = revisions.article_id AND p.published
+        ORDER BY p.id DESC LIMIT 1
+    )
+WHERE revisions.id = ?3 AND revisions.proposal AND NOT revisions.reviewed
+`
+
+type AcceptProposalParams struct {
+	Reviewer sql.NullInt64
+	Diff     string
+	ID       int64
+}
+
+func (q *Queries) AcceptProposal(ctx context.Context, arg AcceptProposalParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, acceptProposal, arg.Reviewer, arg.Diff, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const authUserByEmail = `-- name: AuthUserByEmail :one
 SELECT
     u.id AS user_id,
@@ -80,6 +111,102 @@ func (q *Queries) AuthUserByUsername(ctx context.Context, username string) (Auth
 	return i, err
 }
 
+const clearForeignUser = `-- name: ClearForeignUser :exec
+UPDATE users SET
+    name = '',
+    summary = NULL,
+    url = NULL,
+    public_key = NULL,
+    last_updated = cast(strftime('%s','now') as int)
+WHERE id = ?
+`
+
+func (q *Queries) ClearForeignUser(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, clearForeignUser, id)
+	return err
+}
+
+const countAnnounces = `-- name: CountAnnounces :one
+SELECT COUNT(*)
+FROM announces s
+JOIN articles a ON a.id = s.article_id
+WHERE a.ap_id = ?
+`
+
+func (q *Queries) CountAnnounces(ctx context.Context, apID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAnnounces, apID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countFollowers = `-- name: CountFollowers :one
+SELECT COUNT(*) FROM follows WHERE followee = ? AND accepted
+`
+
+func (q *Queries) CountFollowers(ctx context.Context, followee string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFollowers, followee)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countFollowing = `-- name: CountFollowing :one
+SELECT COUNT(*)
+FROM follows f
+JOIN users u ON u.id = f.follower
+WHERE u.ap_id = ? AND f.accepted
+`
+
+func (q *Queries) CountFollowing(ctx context.Context, apID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFollowing, apID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countLikes = `-- name: CountLikes :one
+SELECT COUNT(*)
+FROM likes l
+JOIN articles a ON a.id = l.article_id
+WHERE a.ap_id = ?
+`
+
+func (q *Queries) CountLikes(ctx context.Context, apID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countLikes, apID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countLocalRevisions = `-- name: CountLocalRevisions :one
+SELECT COUNT(*)
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+WHERE a.local AND r.published AND (?1 IS NULL OR a.ap_id = ?1)
+`
+
+func (q *Queries) CountLocalRevisions(ctx context.Context, article interface{}) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countLocalRevisions, article)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserRevisions = `-- name: CountUserRevisions :one
+SELECT COUNT(*)
+FROM revisions r
+JOIN users u ON u.id = r.user_id
+WHERE u.ap_id = ? AND r.published
+`
+
+func (q *Queries) CountUserRevisions(ctx context.Context, apID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserRevisions, apID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAccount = `-- name: CreateAccount :exec
 INSERT INTO 
     accounts (password, admin, email, user_id)
@@ -112,8 +239,9 @@ INSERT INTO articles (
     language,
     media_type,
     title,
-    content
-) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id
+    content,
+    forked_from
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id
 `
 
 type CreateArticleParams struct {
@@ -124,6 +252,7 @@ type CreateArticleParams struct {
 	MediaType  string
 	Title      string
 	Content    string
+	ForkedFrom sql.NullString
 }
 
 func (q *Queries) CreateArticle(ctx context.Context, arg CreateArticleParams) (int64, error) {
@@ -135,12 +264,58 @@ func (q *Queries) CreateArticle(ctx context.Context, arg CreateArticleParams) (i
 		arg.MediaType,
 		arg.Title,
 		arg.Content,
+		arg.ForkedFrom,
 	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
 
+const createInstanceActor = `-- name: CreateInstanceActor :exec
+INSERT INTO users (
+    bot,
+    ap_id,
+    url,
+    username,
+    name,
+    inbox,
+    outbox,
+    followers,
+    public_key,
+    private_key,
+    trusted
+    )
+VALUES (TRUE, ?, ?, ?, ?, ?, ?, ?, ?, ?, FALSE)
+ON CONFLICT (ap_id) DO NOTHING
+`
+
+type CreateInstanceActorParams struct {
+	ApID       string
+	Url        sql.NullString
+	Username   string
+	Name       string
+	Inbox      string
+	Outbox     sql.NullString
+	Followers  sql.NullString
+	PublicKey  string
+	PrivateKey string
+}
+
+func (q *Queries) CreateInstanceActor(ctx context.Context, arg CreateInstanceActorParams) error {
+	_, err := q.db.ExecContext(ctx, createInstanceActor,
+		arg.ApID,
+		arg.Url,
+		arg.Username,
+		arg.Name,
+		arg.Inbox,
+		arg.Outbox,
+		arg.Followers,
+		arg.PublicKey,
+		arg.PrivateKey,
+	)
+	return err
+}
+
 const createLocalUser = `-- name: CreateLocalUser :one
 INSERT INTO users (
     ap_id,
@@ -164,8 +339,8 @@ type CreateLocalUserParams struct {
 	Trusted    bool
 	Summary    sql.NullString
 	Inbox      string
-	Outbox     string
-	Followers  string
+	Outbox     sql.NullString
+	Followers  sql.NullString
 	PublicKey  string
 	PrivateKey string
 }
@@ -188,6 +363,246 @@ func (q *Queries) CreateLocalUser(ctx context.Context, arg CreateLocalUserParams
 	return id, err
 }
 
+const deleteAnnounce = `-- name: DeleteAnnounce :execrows
+DELETE FROM announces
+WHERE announces.user_id = (SELECT u.id FROM users u WHERE u.ap_id = ?1)
+    AND announces.article_id = (SELECT a.id FROM articles a WHERE a.ap_id = ?2)
+`
+
+type DeleteAnnounceParams struct {
+	Actor   string
+	Article string
+}
+
+func (q *Queries) DeleteAnnounce(ctx context.Context, arg DeleteAnnounceParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAnnounce, arg.Actor, arg.Article)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteArticle = `-- name: DeleteArticle :exec
+DELETE FROM articles WHERE id = ?
+`
+
+func (q *Queries) DeleteArticle(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticle, id)
+	return err
+}
+
+const deleteArticleAnnounces = `-- name: DeleteArticleAnnounces :exec
+DELETE FROM announces WHERE article_id = ?
+`
+
+func (q *Queries) DeleteArticleAnnounces(ctx context.Context, articleID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticleAnnounces, articleID)
+	return err
+}
+
+const deleteArticleComments = `-- name: DeleteArticleComments :exec
+DELETE FROM comments WHERE article_id = ?
+`
+
+func (q *Queries) DeleteArticleComments(ctx context.Context, articleID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticleComments, articleID)
+	return err
+}
+
+const deleteArticleFiles = `-- name: DeleteArticleFiles :exec
+DELETE FROM article_files WHERE article_id = ?
+`
+
+func (q *Queries) DeleteArticleFiles(ctx context.Context, articleID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticleFiles, articleID)
+	return err
+}
+
+const deleteArticleLikes = `-- name: DeleteArticleLikes :exec
+DELETE FROM likes WHERE article_id = ?
+`
+
+func (q *Queries) DeleteArticleLikes(ctx context.Context, articleID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticleLikes, articleID)
+	return err
+}
+
+const deleteArticleLinks = `-- name: DeleteArticleLinks :exec
+DELETE FROM article_links WHERE article_id = ?
+`
+
+func (q *Queries) DeleteArticleLinks(ctx context.Context, articleID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticleLinks, articleID)
+	return err
+}
+
+const deleteArticleRevisions = `-- name: DeleteArticleRevisions :exec
+DELETE FROM revisions WHERE article_id = ?
+`
+
+func (q *Queries) DeleteArticleRevisions(ctx context.Context, articleID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticleRevisions, articleID)
+	return err
+}
+
+const deleteComment = `-- name: DeleteComment :execrows
+DELETE FROM comments WHERE id = ?
+`
+
+func (q *Queries) DeleteComment(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteComment, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteDelivery = `-- name: DeleteDelivery :exec
+DELETE FROM deliveries WHERE id = ?
+`
+
+func (q *Queries) DeleteDelivery(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteDelivery, id)
+	return err
+}
+
+const deleteFollow = `-- name: DeleteFollow :execrows
+DELETE FROM follows
+WHERE follows.followee = ?1
+    AND follows.follower = (SELECT u.id FROM users u WHERE u.ap_id = ?2)
+`
+
+type DeleteFollowParams struct {
+	Followee string
+	Follower string
+}
+
+func (q *Queries) DeleteFollow(ctx context.Context, arg DeleteFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFollow, arg.Followee, arg.Follower)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFollowsOf = `-- name: DeleteFollowsOf :exec
+DELETE FROM follows WHERE followee = ?
+`
+
+func (q *Queries) DeleteFollowsOf(ctx context.Context, followee string) error {
+	_, err := q.db.ExecContext(ctx, deleteFollowsOf, followee)
+	return err
+}
+
+const deleteForeignFile = `-- name: DeleteForeignFile :execrows
+DELETE FROM files WHERE ap_id = ? AND NOT local
+`
+
+func (q *Queries) DeleteForeignFile(ctx context.Context, apID string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteForeignFile, apID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteForeignFileLinks = `-- name: DeleteForeignFileLinks :exec
+DELETE FROM article_files
+WHERE file_id IN (SELECT f.id FROM files f WHERE f.ap_id = ?1 AND NOT f.local)
+`
+
+func (q *Queries) DeleteForeignFileLinks(ctx context.Context, apID string) error {
+	_, err := q.db.ExecContext(ctx, deleteForeignFileLinks, apID)
+	return err
+}
+
+const deleteForeignRevision = `-- name: DeleteForeignRevision :execrows
+DELETE FROM revisions
+WHERE revisions.ap_id = ?1
+    AND revisions.article_id IN (SELECT a.id FROM articles a WHERE NOT a.local)
+`
+
+func (q *Queries) DeleteForeignRevision(ctx context.Context, apID sql.NullString) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteForeignRevision, apID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteForeignUser = `-- name: DeleteForeignUser :execrows
+DELETE FROM users
+WHERE users.ap_id = ?1
+    AND NOT users.local
+    AND NOT EXISTS (SELECT 1 FROM revisions r WHERE r.user_id = users.id)
+    AND NOT EXISTS (SELECT 1 FROM comments c WHERE c.user_id = users.id)
+`
+
+func (q *Queries) DeleteForeignUser(ctx context.Context, apID string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteForeignUser, apID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteLike = `-- name: DeleteLike :execrows
+DELETE FROM likes
+WHERE likes.user_id = (SELECT u.id FROM users u WHERE u.ap_id = ?1)
+    AND likes.article_id = (SELECT a.id FROM articles a WHERE a.ap_id = ?2)
+`
+
+type DeleteLikeParams struct {
+	Actor   string
+	Article string
+}
+
+func (q *Queries) DeleteLike(ctx context.Context, arg DeleteLikeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteLike, arg.Actor, arg.Article)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteTombstone = `-- name: DeleteTombstone :exec
+DELETE FROM tombstones WHERE ap_id = ?
+`
+
+func (q *Queries) DeleteTombstone(ctx context.Context, apID string) error {
+	_, err := q.db.ExecContext(ctx, deleteTombstone, apID)
+	return err
+}
+
+const deleteUserAnnounces = `-- name: DeleteUserAnnounces :exec
+DELETE FROM announces WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserAnnounces(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserAnnounces, userID)
+	return err
+}
+
+const deleteUserFollows = `-- name: DeleteUserFollows :exec
+DELETE FROM follows
+WHERE follower = ?1
+    OR followee = (SELECT u.ap_id FROM users u WHERE u.id = ?1)
+`
+
+func (q *Queries) DeleteUserFollows(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserFollows, id)
+	return err
+}
+
+const deleteUserLikes = `-- name: DeleteUserLikes :exec
+DELETE FROM likes WHERE user_id = ?
+`
+
+func (q *Queries) DeleteUserLikes(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteUserLikes, userID)
+	return err
+}
+
 const editArticle = `-- name: EditArticle :one
 INSERT INTO revisions (
     ap_id,
@@ -242,6 +657,78 @@ func (q *Queries) FileExists(ctx context.Context, digest string) (bool, error) {
 	return column_1, err
 }
 
+const getArticleByApId = `-- name: GetArticleByApId :one
+SELECT
+    a.id,
+    a.local,
+    a.ap_id,
+    a.url,
+    a.language,
+    a.media_type,
+    a.title,
+    a.protected,
+    a.summary,
+    a.content,
+    a.created,
+    a.last_updated,
+    a.last_fetched,
+    a.public_key,
+    a.forked_from,
+    i.hostname,
+    (SELECT COUNT(*) FROM likes l WHERE l.article_id = a.id) AS likes,
+    (SELECT COUNT(*) FROM announces s WHERE s.article_id = a.id) AS shares
+FROM articles a
+LEFT JOIN instances i ON i.id = a.instance_id
+WHERE a.ap_id = ?
+`
+
+type GetArticleByApIdRow struct {
+	ID          int64
+	Local       bool
+	ApID        string
+	Url         sql.NullString
+	Language    string
+	MediaType   string
+	Title       string
+	Protected   bool
+	Summary     sql.NullString
+	Content     string
+	Created     int64
+	LastUpdated int64
+	LastFetched sql.NullInt64
+	PublicKey   sql.NullString
+	ForkedFrom  sql.NullString
+	Hostname    sql.NullString
+	Likes       int64
+	Shares      int64
+}
+
+func (q *Queries) GetArticleByApId(ctx context.Context, apID string) (GetArticleByApIdRow, error) {
+	row := q.db.QueryRowContext(ctx, getArticleByApId, apID)
+	var i GetArticleByApIdRow
+	err := row.Scan(
+		&i.ID,
+		&i.Local,
+		&i.ApID,
+		&i.Url,
+		&i.Language,
+		&i.MediaType,
+		&i.Title,
+		&i.Protected,
+		&i.Summary,
+		&i.Content,
+		&i.Created,
+		&i.LastUpdated,
+		&i.LastFetched,
+		&i.PublicKey,
+		&i.ForkedFrom,
+		&i.Hostname,
+		&i.Likes,
+		&i.Shares,
+	)
+	return i, err
+}
+
 const getArticleContent = `-- name: GetArticleContent :one
 SELECT content FROM articles WHERE id = ?
 `
@@ -277,6 +764,67 @@ func (q *Queries) GetArticleIDS(ctx context.Context, title string) (GetArticleID
 	return i, err
 }
 
+const getCommentArticleId = `-- name: GetCommentArticleId :one
+SELECT article_id FROM comments WHERE id = ?
+`
+
+func (q *Queries) GetCommentArticleId(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getCommentArticleId, id)
+	var article_id int64
+	err := row.Scan(&article_id)
+	return article_id, err
+}
+
+const getCommentByApId = `-- name: GetCommentByApId :one
+SELECT
+    c.id,
+    c.ap_id,
+    c.url,
+    c.content,
+    c.local,
+    c.hidden,
+    c.created,
+    a.ap_id AS article,
+    u.ap_id AS author,
+    p.ap_id AS in_reply_to
+FROM comments c
+JOIN articles a ON a.id = c.article_id
+JOIN users u ON u.id = c.user_id
+LEFT JOIN comments p ON p.id = c.in_reply_to
+WHERE c.ap_id = ?
+`
+
+type GetCommentByApIdRow struct {
+	ID        int64
+	ApID      sql.NullString
+	Url       sql.NullString
+	Content   string
+	Local     bool
+	Hidden    bool
+	Created   int64
+	Article   string
+	Author    string
+	InReplyTo sql.NullString
+}
+
+func (q *Queries) GetCommentByApId(ctx context.Context, apID sql.NullString) (GetCommentByApIdRow, error) {
+	row := q.db.QueryRowContext(ctx, getCommentByApId, apID)
+	var i GetCommentByApIdRow
+	err := row.Scan(
+		&i.ID,
+		&i.ApID,
+		&i.Url,
+		&i.Content,
+		&i.Local,
+		&i.Hidden,
+		&i.Created,
+		&i.Article,
+		&i.Author,
+		&i.InReplyTo,
+	)
+	return i, err
+}
+
 const getFile = `-- name: GetFile :one
 SELECT
     f.id,
@@ -337,169 +885,196 @@ func (q *Queries) GetFile(ctx context.Context, digest string) (GetFileRow, error
 	return i, err
 }
 
-const getForeignUserData = `-- name: GetForeignUserData :one
+const getFileByApId = `-- name: GetFileByApId :one
 SELECT
-    id,
-    username,
-    name,
-    domain,
-    url,
-    local,
-    summary
-FROM users
-WHERE username = lower(?) AND NOT local AND domain = ?
+    f.ap_id,
+    f.name,
+    f.filename,
+    f.type,
+    f.mime_type,
+    f.size_bytes,
+    f.local,
+    f.url,
+    f.created,
+    u.ap_id AS uploader
+FROM files f
+LEFT JOIN users u ON u.id = f.uploaded_by
+WHERE f.ap_id = ?
 `
 
-type GetForeignUserDataParams struct {
-	LOWER  string
-	Domain sql.NullString
-}
-
-type GetForeignUserDataRow struct {
-	ID       int64
-	Username string
-	Name     string
-	Domain   sql.NullString
-	Url      sql.NullString
-	Local    bool
-	Summary  sql.NullString
+type GetFileByApIdRow struct {
+	ApID      string
+	Name      sql.NullString
+	Filename  sql.NullString
+	Type      string
+	MimeType  string
+	SizeBytes sql.NullInt64
+	Local     bool
+	Url       string
+	Created   int64
+	Uploader  sql.NullString
 }
 
-func (q *Queries) GetForeignUserData(ctx context.Context, arg GetForeignUserDataParams) (GetForeignUserDataRow, error) {
-	row := q.db.QueryRowContext(ctx, getForeignUserData, arg.LOWER, arg.Domain)
-	var i GetForeignUserDataRow
+func (q *Queries) GetFileByApId(ctx context.Context, apID string) (GetFileByApIdRow, error) {
+	row := q.db.QueryRowContext(ctx, getFileByApId, apID)
+	var i GetFileByApIdRow
 	err := row.Scan(
-		&i.ID,
-		&i.Username,
+		&i.ApID,
 		&i.Name,
-		&i.Domain,
-		&i.Url,
+		&i.Filename,
+		&i.Type,
+		&i.MimeType,
+		&i.SizeBytes,
 		&i.Local,
-		&i.Summary,
+		&i.Url,
+		&i.Created,
+		&i.Uploader,
 	)
 	return i, err
 }
 
-const getInstanceId = `-- name: GetInstanceId :one
-SELECT id from instances where hostname = ?
+const getForeignArticleByTitle = `-- name: GetForeignArticleByTitle :one
+SELECT
+    a.id,
+    a.local,
+    a.ap_id,
+    a.url,
+    a.language,
+    a.media_type,
+    a.title,
+    a.protected,
+    a.summary,
+    a.content,
+    a.created,
+    a.last_updated,
+    a.last_fetched,
+    a.public_key,
+    a.forked_from,
+    i.hostname,
+    (SELECT COUNT(*) FROM likes l WHERE l.article_id = a.id) AS likes,
+    (SELECT COUNT(*) FROM announces s WHERE s.article_id = a.id) AS shares
+FROM articles a
+LEFT JOIN instances i ON i.id = a.instance_id
+WHERE NOT a.local AND lower(a.title) = lower(?1) AND i.hostname = ?2
 `
 
-func (q *Queries) GetInstanceId(ctx context.Context, hostname string) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getInstanceId, hostname)
-	var id int64
-	err := row.Scan(&id)
-	return id, err
+type GetForeignArticleByTitleParams struct {
+	Title    string
+	Hostname string
 }
 
-const getLocalArticleByTitle = `-- name: GetLocalArticleByTitle :one
-SELECT
-    title,
-    summary,
-    content,
-    protected,
-    media_type,
-    language
-FROM
-    articles
-where local AND title = ?1
-LIMIT 1
-`
-
-type GetLocalArticleByTitleRow struct {
-	Title     string
-	Summary   sql.NullString
-	Content   string
-	Protected bool
-	MediaType string
-	Language  string
+type GetForeignArticleByTitleRow struct {
+	ID          int64
+	Local       bool
+	ApID        string
+	Url         sql.NullString
+	Language    string
+	MediaType   string
+	Title       string
+	Protected   bool
+	Summary     sql.NullString
+	Content     string
+	Created     int64
+	LastUpdated int64
+	LastFetched sql.NullInt64
+	PublicKey   sql.NullString
+	ForkedFrom  sql.NullString
+	Hostname    sql.NullString
+	Likes       int64
+	Shares      int64
 }
 
-func (q *Queries) GetLocalArticleByTitle(ctx context.Context, title string) (GetLocalArticleByTitleRow, error) {
-	row := q.db.QueryRowContext(ctx, getLocalArticleByTitle, title)
-	var i GetLocalArticleByTitleRow
+func (q *Queries) GetForeignArticleByTitle(ctx context.Context, arg GetForeignArticleByTitleParams) (GetForeignArticleByTitleRow, error) {
+	row := q.db.QueryRowContext(ctx, getForeignArticleByTitle, arg.Title, arg.Hostname)
+	var i GetForeignArticleByTitleRow
 	err := row.Scan(
+		&i.ID,
+		&i.Local,
+		&i.ApID,
+		&i.Url,
+		&i.Language,
+		&i.MediaType,
 		&i.Title,
+		&i.Protected,
 		&i.Summary,
 		&i.Content,
-		&i.Protected,
-		&i.MediaType,
-		&i.Language,
+		&i.Created,
+		&i.LastUpdated,
+		&i.LastFetched,
+		&i.PublicKey,
+		&i.ForkedFrom,
+		&i.Hostname,
+		&i.Likes,
+		&i.Shares,
 	)
 	return i, err
 }
 
-const getLocalUserData = `-- name: GetLocalUserData :one
-SELECT
-    id,
-    username,
-    name,
-    url,
-    summary
-FROM users
-WHERE local AND username = lower(?)
+const getForeignArticleId = `-- name: GetForeignArticleId :one
+SELECT id FROM articles WHERE ap_id = ? AND NOT local
 `
 
-type GetLocalUserDataRow struct {
-	ID       int64
-	Username string
-	Name     string
-	Url      sql.NullString
-	Summary  sql.NullString
-}
-
-func (q *Queries) GetLocalUserData(ctx context.Context, lower string) (GetLocalUserDataRow, error) {
-	row := q.db.QueryRowContext(ctx, getLocalUserData, lower)
-	var i GetLocalUserDataRow
-	err := row.Scan(
-		&i.ID,
-		&i.Username,
-		&i.Name,
-		&i.Url,
-		&i.Summary,
-	)
-	return i, err
+func (q *Queries) GetForeignArticleId(ctx context.Context, apID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getForeignArticleId, apID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
 }
 
-const getRevisionList = `-- name: GetRevisionList :many
+const getForeignRevisionList = `-- name: GetForeignRevisionList :many
 SELECT
     r.id,
     r.reviewed,
+    r.published,
+    r.proposal,
     r.summary,
     a.title,
     u.username,
+    u.domain,
     r.created
-FROM (
-    SELECT id, title from articles WHERE lower(title) = lower(?1) LIMIT 1
-) a
+FROM articles a
+JOIN instances i ON i.id = a.instance_id
 JOIN revisions r ON r.article_id = a.id
 JOIN users u ON r.user_id = u.id
+WHERE NOT a.local AND lower(a.title) = lower(?1) AND i.hostname = ?2
 ORDER BY r.created DESC
 `
 
-type GetRevisionListRow struct {
-	ID       int64
-	Reviewed bool
-	Summary  sql.NullString
+type GetForeignRevisionListParams struct {
 	Title    string
-	Username string
-	Created  int64
+	Hostname string
 }
 
-func (q *Queries) GetRevisionList(ctx context.Context, title string) ([]GetRevisionListRow, error) {
-	rows, err := q.db.QueryContext(ctx, getRevisionList, title)
+type GetForeignRevisionListRow struct {
+	ID        int64
+	Reviewed  bool
+	Published bool
+	Proposal  bool
+	Summary   sql.NullString
+	Title     string
+	Username  string
+	Domain    sql.NullString
+	Created   int64
+}
+
+func (q *Queries) GetForeignRevisionList(ctx context.Context, arg GetForeignRevisionListParams) ([]GetForeignRevisionListRow, error) {
+	rows, err := q.db.QueryContext(ctx, getForeignRevisionList, arg.Title, arg.Hostname)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetRevisionListRow
+	var items []GetForeignRevisionListRow
 	for rows.Next() {
-		var i GetRevisionListRow
+		var i GetForeignRevisionListRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Reviewed,
+			&i.Published,
+			&i.Proposal,
 			&i.Summary,
 			&i.Title,
 			&i.Username,
+			&i.Domain,
 			&i.Created,
 		); err != nil {
 			return nil, err
@@ -515,227 +1090,2272 @@ func (q *Queries) GetRevisionList(ctx context.Context, title string) ([]GetRevis
 	return items, nil
 }
 
-const getRevisionsByUserId = `-- name: GetRevisionsByUserId :many
+const getForeignUserData = `-- name: GetForeignUserData :one
 SELECT
-    r.id,
-    a.title,
-    r.summary,
-    r.reviewed,
-    r.created
-FROM revisions r
-JOIN articles a
-ON a.id = r.article_id
-WHERE r.user_id = ?
+    id,
+    ap_id,
+    username,
+    name,
+    domain,
+    url,
+    local,
+    summary,
+    outbox,
+    last_fetched
+FROM users
+WHERE username = lower(?) AND NOT local AND domain = ?
 `
 
-type GetRevisionsByUserIdRow struct {
-	ID       int64
-	Title    string
-	Summary  sql.NullString
-	Reviewed bool
-	Created  int64
+type GetForeignUserDataParams struct {
+	LOWER  string
+	Domain sql.NullString
 }
 
-func (q *Queries) GetRevisionsByUserId(ctx context.Context, userID int64) ([]GetRevisionsByUserIdRow, error) {
-	rows, err := q.db.QueryContext(ctx, getRevisionsByUserId, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetRevisionsByUserIdRow
-	for rows.Next() {
-		var i GetRevisionsByUserIdRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.Title,
-			&i.Summary,
-			&i.Reviewed,
-			&i.Created,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type GetForeignUserDataRow struct {
+	ID          int64
+	ApID        string
+	Username    string
+	Name        string
+	Domain      sql.NullString
+	Url         sql.NullString
+	Local       bool
+	Summary     sql.NullString
+	Outbox      sql.NullString
+	LastFetched sql.NullInt64
 }
 
-const getUserFull = `-- name: GetUserFull :one
+func (q *Queries) GetForeignUserData(ctx context.Context, arg GetForeignUserDataParams) (GetForeignUserDataRow, error) {
+	row := q.db.QueryRowContext(ctx, getForeignUserData, arg.LOWER, arg.Domain)
+	var i GetForeignUserDataRow
+	err := row.Scan(
+		&i.ID,
+		&i.ApID,
+		&i.Username,
+		&i.Name,
+		&i.Domain,
+		&i.Url,
+		&i.Local,
+		&i.Summary,
+		&i.Outbox,
+		&i.LastFetched,
+	)
+	return i, err
+}
+
+const getForeignUserFull = `-- name: GetForeignUserFull :one
 SELECT
     ap_id,
+    bot,
     url,
     username,
     name,
+    domain,
     summary,
     inbox,
     outbox,
     followers,
     public_key,
+    key_id,
+    previous_key_id,
+    previous_public_key,
+    previous_key_expires,
     created,
     last_updated
 FROM users
-WHERE local AND ap_id = ?
+WHERE NOT local AND ap_id = ?
 `
 
-type GetUserFullRow struct {
-	ApID        string
-	Url         sql.NullString
-	Username    string
-	Name        string
-	Summary     sql.NullString
-	Inbox       string
-	Outbox      string
-	Followers   string
-	PublicKey   string
-	Created     int64
-	LastUpdated int64
+type GetForeignUserFullRow struct {
+	ApID               string
+	Bot                bool
+	Url                sql.NullString
+	Username           string
+	Name               string
+	Domain             sql.NullString
+	Summary            sql.NullString
+	Inbox              string
+	Outbox             sql.NullString
+	Followers          sql.NullString
+	PublicKey          string
+	KeyID              sql.NullString
+	PreviousKeyID      sql.NullString
+	PreviousPublicKey  sql.NullString
+	PreviousKeyExpires sql.NullInt64
+	Created            int64
+	LastUpdated        int64
 }
 
-func (q *Queries) GetUserFull(ctx context.Context, apID string) (GetUserFullRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserFull, apID)
-	var i GetUserFullRow
+func (q *Queries) GetForeignUserFull(ctx context.Context, apID string) (GetForeignUserFullRow, error) {
+	row := q.db.QueryRowContext(ctx, getForeignUserFull, apID)
+	var i GetForeignUserFullRow
 	err := row.Scan(
 		&i.ApID,
+		&i.Bot,
 		&i.Url,
 		&i.Username,
 		&i.Name,
+		&i.Domain,
 		&i.Summary,
 		&i.Inbox,
 		&i.Outbox,
 		&i.Followers,
 		&i.PublicKey,
+		&i.KeyID,
+		&i.PreviousKeyID,
+		&i.PreviousPublicKey,
+		&i.PreviousKeyExpires,
 		&i.Created,
 		&i.LastUpdated,
 	)
 	return i, err
 }
 
-const insertFile = `-- name: InsertFile :one
-INSERT INTO files (
-    local,
-    digest,
-    path,
-    ap_id,
+const getForeignUserId = `-- name: GetForeignUserId :one
+SELECT id FROM users WHERE ap_id = ? AND NOT local
+`
+
+func (q *Queries) GetForeignUserId(ctx context.Context, apID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getForeignUserId, apID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getInstanceId = `-- name: GetInstanceId :one
+SELECT id from instances where hostname = ?
+`
+
+func (q *Queries) GetInstanceId(ctx context.Context, hostname string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getInstanceId, hostname)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getInstancePolicy = `-- name: GetInstancePolicy :one
+SELECT policy FROM instances WHERE hostname = ? LIMIT 1
+`
+
+func (q *Queries) GetInstancePolicy(ctx context.Context, hostname string) (sql.NullString, error) {
+	row := q.db.QueryRowContext(ctx, getInstancePolicy, hostname)
+	var policy sql.NullString
+	err := row.Scan(&policy)
+	return policy, err
+}
+
+const getLatestRevisionId = `-- name: GetLatestRevisionId :one
+SELECT id FROM revisions WHERE article_id = ? AND published ORDER BY id DESC LIMIT 1
+`
+
+func (q *Queries) GetLatestRevisionId(ctx context.Context, articleID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLatestRevisionId, articleID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getLocalArticleByTitle = `-- name: GetLocalArticleByTitle :one
+SELECT
+    title,
+    summary,
+    content,
+    protected,
+    media_type,
+    language,
+    forked_from,
+    (SELECT COUNT(*) FROM likes l WHERE l.article_id = articles.id) AS likes,
+    (SELECT COUNT(*) FROM announces s WHERE s.article_id = articles.id) AS shares,
+    cast(coalesce((
+        SELECT r.id FROM revisions r
+        WHERE r.article_id = articles.id AND r.published
+        ORDER BY r.id DESC LIMIT 1
+    ), 0) as int) AS revision
+FROM
+    articles
+where local AND title = ?1
+LIMIT 1
+`
+
+type GetLocalArticleByTitleRow struct {
+	Title      string
+	Summary    sql.NullString
+	Content    string
+	Protected  bool
+	MediaType  string
+	Language   string
+	ForkedFrom sql.NullString
+	Likes      int64
+	Shares     int64
+	Revision   int64
+}
+
+func (q *Queries) GetLocalArticleByTitle(ctx context.Context, title string) (GetLocalArticleByTitleRow, error) {
+	row := q.db.QueryRowContext(ctx, getLocalArticleByTitle, title)
+	var i GetLocalArticleByTitleRow
+	err := row.Scan(
+		&i.Title,
+		&i.Summary,
+		&i.Content,
+		&i.Protected,
+		&i.MediaType,
+		&i.Language,
+		&i.ForkedFrom,
+		&i.Likes,
+		&i.Shares,
+		&i.Revision,
+	)
+	return i, err
+}
+
+const getLocalArticleId = `-- name: GetLocalArticleId :one
+SELECT id FROM articles WHERE local AND ap_id = ?
+`
+
+func (q *Queries) GetLocalArticleId(ctx context.Context, apID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLocalArticleId, apID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getLocalUserData = `-- name: GetLocalUserData :one
+SELECT
+    id,
+    username,
     name,
-    filename,
-    type,
-    mime_type,
-    size_bytes,
-    uploaded_by,
-    url
-) VALUES
-    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-RETURNING id
+    url,
+    summary
+FROM users
+WHERE local AND username = lower(?)
+`
+
+type GetLocalUserDataRow struct {
+	ID       int64
+	Username string
+	Name     string
+	Url      sql.NullString
+	Summary  sql.NullString
+}
+
+func (q *Queries) GetLocalUserData(ctx context.Context, lower string) (GetLocalUserDataRow, error) {
+	row := q.db.QueryRowContext(ctx, getLocalUserData, lower)
+	var i GetLocalUserDataRow
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Name,
+		&i.Url,
+		&i.Summary,
+	)
+	return i, err
+}
+
+const getPendingProposalApId = `-- name: GetPendingProposalApId :one
+SELECT r.ap_id
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+WHERE r.id = ? AND a.local AND r.proposal AND NOT r.reviewed
+`
+
+func (q *Queries) GetPendingProposalApId(ctx context.Context, id int64) (sql.NullString, error) {
+	row := q.db.QueryRowContext(ctx, getPendingProposalApId, id)
+	var ap_id sql.NullString
+	err := row.Scan(&ap_id)
+	return ap_id, err
+}
+
+const getPublicKey = `-- name: GetPublicKey :one
+SELECT u.public_key FROM users u WHERE u.ap_id = ?1
+UNION ALL
+SELECT a.public_key FROM articles a WHERE a.local AND a.ap_id = ?1 AND a.public_key IS NOT NULL
+`
+
+func (q *Queries) GetPublicKey(ctx context.Context, apID string) (string, error) {
+	row := q.db.QueryRowContext(ctx, getPublicKey, apID)
+	var public_key string
+	err := row.Scan(&public_key)
+	return public_key, err
+}
+
+const getRevisionArticleId = `-- name: GetRevisionArticleId :one
+SELECT article_id FROM revisions WHERE id = ?
+`
+
+func (q *Queries) GetRevisionArticleId(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getRevisionArticleId, id)
+	var article_id int64
+	err := row.Scan(&article_id)
+	return article_id, err
+}
+
+const getRevisionByApId = `-- name: GetRevisionByApId :one
+SELECT
+    r.ap_id,
+    r.summary,
+    r.diff,
+    r.published,
+    r.reviewed,
+    r.proposal,
+    r.prev IS NULL AND NOT r.proposal AS initial,
+    r.created,
+    a.ap_id AS article,
+    u.ap_id AS author,
+    b.ap_id AS based_on,
+    v.ap_id AS reviewer
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+LEFT JOIN revisions b ON b.id = r.based_on
+LEFT JOIN users v ON v.id = r.reviewer
+WHERE r.ap_id = ?
+`
+
+type GetRevisionByApIdRow struct {
+	ApID      sql.NullString
+	Summary   sql.NullString
+	Diff      string
+	Published bool
+	Reviewed  bool
+	Proposal  bool
+	Initial   sql.NullBool
+	Created   int64
+	Article   string
+	Author    string
+	BasedOn   sql.NullString
+	Reviewer  sql.NullString
+}
+
+func (q *Queries) GetRevisionByApId(ctx context.Context, apID sql.NullString) (GetRevisionByApIdRow, error) {
+	row := q.db.QueryRowContext(ctx, getRevisionByApId, apID)
+	var i GetRevisionByApIdRow
+	err := row.Scan(
+		&i.ApID,
+		&i.Summary,
+		&i.Diff,
+		&i.Published,
+		&i.Reviewed,
+		&i.Proposal,
+		&i.Initial,
+		&i.Created,
+		&i.Article,
+		&i.Author,
+		&i.BasedOn,
+		&i.Reviewer,
+	)
+	return i, err
+}
+
+const getRevisionList = `-- name: GetRevisionList :many
+SELECT
+    r.id,
+    r.reviewed,
+    r.published,
+    r.proposal,
+    r.summary,
+    a.title,
+    u.username,
+    u.domain,
+    r.created
+FROM (
+    SELECT id, title from articles WHERE local AND lower(title) = lower(?1) LIMIT 1
+) a
+JOIN revisions r ON r.article_id = a.id
+JOIN users u ON r.user_id = u.id
+ORDER BY r.created DESC
+`
+
+type GetRevisionListRow struct {
+	ID        int64
+	Reviewed  bool
+	Published bool
+	Proposal  bool
+	Summary   sql.NullString
+	Title     string
+	Username  string
+	Domain    sql.NullString
+	Created   int64
+}
+
+func (q *Queries) GetRevisionList(ctx context.Context, title string) ([]GetRevisionListRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRevisionList, title)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRevisionListRow
+	for rows.Next() {
+		var i GetRevisionListRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Reviewed,
+			&i.Published,
+			&i.Proposal,
+			&i.Summary,
+			&i.Title,
+			&i.Username,
+			&i.Domain,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRevisionsByUserId = `-- name: GetRevisionsByUserId :many
+SELECT
+    r.id,
+    a.title,
+    i.hostname,
+    r.summary,
+    r.reviewed,
+    r.published,
+    r.proposal,
+    r.created
+FROM revisions r
+JOIN articles a
+ON a.id = r.article_id
+LEFT JOIN instances i ON i.id = a.instance_id AND NOT a.local
+WHERE r.user_id = ?
+ORDER BY r.created DESC
+`
+
+type GetRevisionsByUserIdRow struct {
+	ID        int64
+	Title     string
+	Hostname  sql.NullString
+	Summary   sql.NullString
+	Reviewed  bool
+	Published bool
+	Proposal  bool
+	Created   int64
+}
+
+func (q *Queries) GetRevisionsByUserId(ctx context.Context, userID int64) ([]GetRevisionsByUserIdRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRevisionsByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRevisionsByUserIdRow
+	for rows.Next() {
+		var i GetRevisionsByUserIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Hostname,
+			&i.Summary,
+			&i.Reviewed,
+			&i.Published,
+			&i.Proposal,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSigningKey = `-- name: GetSigningKey :one
+SELECT u.private_key, u.key_id FROM users u WHERE u.local AND u.ap_id = ?1
+UNION ALL
+SELECT a.private_key, NULL FROM articles a WHERE a.local AND a.ap_id = ?1 AND a.private_key IS NOT NULL
+`
+
+type GetSigningKeyRow struct {
+	PrivateKey string
+	KeyID      sql.NullString
+}
+
+func (q *Queries) GetSigningKey(ctx context.Context, apID string) (GetSigningKeyRow, error) {
+	row := q.db.QueryRowContext(ctx, getSigningKey, apID)
+	var i GetSigningKeyRow
+	err := row.Scan(&i.PrivateKey, &i.KeyID)
+	return i, err
+}
+
+const getStats = `-- name: GetStats :one
+SELECT
+    (SELECT COUNT(*) FROM accounts) AS users,
+    (SELECT COUNT(*) FROM users u WHERE u.local AND (
+        EXISTS (SELECT 1 FROM revisions r WHERE r.user_id = u.id AND r.created >= ?1)
+        OR EXISTS (SELECT 1 FROM comments c WHERE c.user_id = u.id AND c.created >= ?1)
+    )) AS active_month,
+    (SELECT COUNT(*) FROM users u WHERE u.local AND (
+        EXISTS (SELECT 1 FROM revisions r WHERE r.user_id = u.id AND r.created >= ?2)
+        OR EXISTS (SELECT 1 FROM comments c WHERE c.user_id = u.id AND c.created >= ?2)
+    )) AS active_halfyear,
+    (SELECT COUNT(*) FROM articles a WHERE a.local) AS articles,
+    (SELECT COUNT(*) FROM revisions r JOIN users u ON u.id = r.user_id WHERE u.local AND r.published) AS revisions,
+    (SELECT COUNT(*) FROM comments c WHERE c.local) AS comments
+`
+
+type GetStatsParams struct {
+	Month    int64
+	Halfyear int64
+}
+
+type GetStatsRow struct {
+	Users          int64
+	ActiveMonth    int64
+	ActiveHalfyear int64
+	Articles       int64
+	Revisions      int64
+	Comments       int64
+}
+
+func (q *Queries) GetStats(ctx context.Context, arg GetStatsParams) (GetStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getStats, arg.Month, arg.Halfyear)
+	var i GetStatsRow
+	err := row.Scan(
+		&i.Users,
+		&i.ActiveMonth,
+		&i.ActiveHalfyear,
+		&i.Articles,
+		&i.Revisions,
+		&i.Comments,
+	)
+	return i, err
+}
+
+const getTombstone = `-- name: GetTombstone :one
+SELECT ap_id, former_type, deleted FROM tombstones WHERE ap_id = ?
+`
+
+type GetTombstoneRow struct {
+	ApID       string
+	FormerType string
+	Deleted    int64
+}
+
+func (q *Queries) GetTombstone(ctx context.Context, apID string) (GetTombstoneRow, error) {
+	row := q.db.QueryRowContext(ctx, getTombstone, apID)
+	var i GetTombstoneRow
+	err := row.Scan(&i.ApID, &i.FormerType, &i.Deleted)
+	return i, err
+}
+
+const getUserFull = `-- name: GetUserFull :one
+SELECT
+    ap_id,
+    bot,
+    url,
+    username,
+    name,
+    domain,
+    summary,
+    inbox,
+    outbox,
+    followers,
+    public_key,
+    key_id,
+    previous_key_id,
+    previous_public_key,
+    previous_key_expires,
+    created,
+    last_updated
+FROM users
+WHERE local AND ap_id = ?
+`
+
+type GetUserFullRow struct {
+	ApID               string
+	Bot                bool
+	Url                sql.NullString
+	Username           string
+	Name               string
+	Domain             sql.NullString
+	Summary            sql.NullString
+	Inbox              string
+	Outbox             sql.NullString
+	Followers          sql.NullString
+	PublicKey          string
+	KeyID              sql.NullString
+	PreviousKeyID      sql.NullString
+	PreviousPublicKey  sql.NullString
+	PreviousKeyExpires sql.NullInt64
+	Created            int64
+	LastUpdated        int64
+}
+
+func (q *Queries) GetUserFull(ctx context.Context, apID string) (GetUserFullRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserFull, apID)
+	var i GetUserFullRow
+	err := row.Scan(
+		&i.ApID,
+		&i.Bot,
+		&i.Url,
+		&i.Username,
+		&i.Name,
+		&i.Domain,
+		&i.Summary,
+		&i.Inbox,
+		&i.Outbox,
+		&i.Followers,
+		&i.PublicKey,
+		&i.KeyID,
+		&i.PreviousKeyID,
+		&i.PreviousPublicKey,
+		&i.PreviousKeyExpires,
+		&i.Created,
+		&i.LastUpdated,
+	)
+	return i, err
+}
+
+const getUserIdByApId = `-- name: GetUserIdByApId :one
+SELECT id FROM users WHERE ap_id = ?
+`
+
+func (q *Queries) GetUserIdByApId(ctx context.Context, apID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getUserIdByApId, apID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const hideUserComments = `-- name: HideUserComments :exec
+UPDATE comments SET hidden = true WHERE user_id = ?
+`
+
+func (q *Queries) HideUserComments(ctx context.Context, userID int64) error {
+	_, err := q.db.ExecContext(ctx, hideUserComments, userID)
+	return err
+}
+
+const importRevision = `-- name: ImportRevision :one
+INSERT INTO revisions (
+    article_id,
+    user_id,
+    summary,
+    diff,
+    reviewed,
+    published,
+    prev,
+    created
+) VALUES (?, ?, ?, ?, true, true, ?, ?) RETURNING id
+`
+
+type ImportRevisionParams struct {
+	ArticleID int64
+	UserID    int64
+	Summary   sql.NullString
+	Diff      string
+	Prev      sql.NullInt64
+	Created   int64
+}
+
+func (q *Queries) ImportRevision(ctx context.Context, arg ImportRevisionParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, importRevision,
+		arg.ArticleID,
+		arg.UserID,
+		arg.Summary,
+		arg.Diff,
+		arg.Prev,
+		arg.Created,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertArticleLink = `-- name: InsertArticleLink :exec
+INSERT INTO article_links (article_id, target) VALUES (?, ?)
+ON CONFLICT (article_id, target) DO NOTHING
+`
+
+type InsertArticleLinkParams struct {
+	ArticleID int64
+	Target    string
+}
+
+func (q *Queries) InsertArticleLink(ctx context.Context, arg InsertArticleLinkParams) error {
+	_, err := q.db.ExecContext(ctx, insertArticleLink, arg.ArticleID, arg.Target)
+	return err
+}
+
+const insertDelivery = `-- name: InsertDelivery :exec
+INSERT INTO deliveries (actor, inbox, activity) VALUES (?, ?, ?)
+`
+
+type InsertDeliveryParams struct {
+	Actor    string
+	Inbox    string
+	Activity string
+}
+
+func (q *Queries) InsertDelivery(ctx context.Context, arg InsertDeliveryParams) error {
+	_, err := q.db.ExecContext(ctx, insertDelivery, arg.Actor, arg.Inbox, arg.Activity)
+	return err
+}
+
+const insertFile = `-- name: InsertFile :one
+INSERT INTO files (
+    local,
+    digest,
+    path,
+    ap_id,
+    name,
+    filename,
+    type,
+    mime_type,
+    size_bytes,
+    uploaded_by,
+    url
+) VALUES
+    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id
+`
+
+type InsertFileParams struct {
+	Local      bool
+	Digest     string
+	Path       sql.NullString
+	ApID       string
+	Name       sql.NullString
+	Filename   sql.NullString
+	Type       string
+	MimeType   string
+	SizeBytes  sql.NullInt64
+	UploadedBy sql.NullInt64
+	Url        string
+}
+
+func (q *Queries) InsertFile(ctx context.Context, arg InsertFileParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertFile,
+		arg.Local,
+		arg.Digest,
+		arg.Path,
+		arg.ApID,
+		arg.Name,
+		arg.Filename,
+		arg.Type,
+		arg.MimeType,
+		arg.SizeBytes,
+		arg.UploadedBy,
+		arg.Url,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertForeignRevision = `-- name: InsertForeignRevision :exec
+INSERT INTO revisions (
+    ap_id,
+    article_id,
+    user_id,
+    summary,
+    diff,
+    reviewed,
+    published,
+    prev,
+    created
+) VALUES (?, ?, ?, ?, ?, true, true, ?, ?)
+ON CONFLICT (ap_id) DO NOTHING
+`
+
+type InsertForeignRevisionParams struct {
+	ApID      sql.NullString
+	ArticleID int64
+	UserID    int64
+	Summary   sql.NullString
+	Diff      string
+	Prev      sql.NullInt64
+	Created   int64
+}
+
+func (q *Queries) InsertForeignRevision(ctx context.Context, arg InsertForeignRevisionParams) error {
+	_, err := q.db.ExecContext(ctx, insertForeignRevision,
+		arg.ApID,
+		arg.ArticleID,
+		arg.UserID,
+		arg.Summary,
+		arg.Diff,
+		arg.Prev,
+		arg.Created,
+	)
+	return err
+}
+
+const insertIncomingProposal = `-- name: InsertIncomingProposal :exec
+INSERT INTO revisions (
+    ap_id,
+    article_id,
+    user_id,
+    summary,
+    diff,
+    proposal,
+    based_on,
+    created
+) VALUES (
+    ?1,
+    (SELECT a.id FROM articles a WHERE a.ap_id = ?2 AND a.local),
+    (SELECT u.id FROM users u WHERE u.ap_id = ?3),
+    ?4,
+    ?5,
+    TRUE,
+    (
+        SELECT b.id FROM revisions b
+        JOIN articles ba ON ba.id = b.article_id
+        WHERE b.ap_id = ?6 AND ba.ap_id = ?2 AND b.published
+    ),
+    ?7
+)
+ON CONFLICT (ap_id) DO NOTHING
+`
+
+type InsertIncomingProposalParams struct {
+	ApID    sql.NullString
+	Article string
+	Author  string
+	Summary sql.NullString
+	Diff    string
+	BasedOn sql.NullString
+	Created int64
+}
+
+func (q *Queries) InsertIncomingProposal(ctx context.Context, arg InsertIncomingProposalParams) error {
+	_, err := q.db.ExecContext(ctx, insertIncomingProposal,
+		arg.ApID,
+		arg.Article,
+		arg.Author,
+		arg.Summary,
+		arg.Diff,
+		arg.BasedOn,
+		arg.Created,
+	)
+	return err
+}
+
+const insertInstance = `-- name: InsertInstance :one
+INSERT INTO instances (hostname, public_key, inbox) VALUES (?, ?, ?) RETURNING id
+`
+
+type InsertInstanceParams struct {
+	Hostname  string
+	PublicKey sql.NullString
+	Inbox     sql.NullString
+}
+
+func (q *Queries) InsertInstance(ctx context.Context, arg InsertInstanceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertInstance, arg.Hostname, arg.PublicKey, arg.Inbox)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertLocalComment = `-- name: InsertLocalComment :one
+INSERT INTO comments (article_id, user_id, in_reply_to, content, local)
+VALUES (?1, ?2, ?3, ?4, TRUE)
+RETURNING id
+`
+
+type InsertLocalCommentParams struct {
+	ArticleID int64
+	UserID    int64
+	InReplyTo sql.NullInt64
+	Content   string
+}
+
+func (q *Queries) InsertLocalComment(ctx context.Context, arg InsertLocalCommentParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertLocalComment,
+		arg.ArticleID,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.Content,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertProposal = `-- name: InsertProposal :one
+INSERT INTO revisions (
+    article_id,
+    user_id,
+    summary,
+    diff,
+    proposal,
+    based_on
+) VALUES (?, ?, ?, ?, TRUE, ?) RETURNING id
+`
+
+type InsertProposalParams struct {
+	ArticleID int64
+	UserID    int64
+	Summary   sql.NullString
+	Diff      string
+	BasedOn   sql.NullInt64
+}
+
+func (q *Queries) InsertProposal(ctx context.Context, arg InsertProposalParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertProposal,
+		arg.ArticleID,
+		arg.UserID,
+		arg.Summary,
+		arg.Diff,
+		arg.BasedOn,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertRevision = `-- name: InsertRevision :one
+INSERT INTO revisions (
+    ap_id,
+    article_id,
+    user_id,
+    summary,
+    diff,
+    published,
+    prev
+) VALUES (?1, ?2, ?3, ?4, ?5, true, ?6) RETURNING id
+`
+
+type InsertRevisionParams struct {
+	ApID      sql.NullString
+	ArticleID int64
+	UserID    int64
+	Summary   sql.NullString
+	Diff      string
+	Prev      sql.NullInt64
+}
+
+func (q *Queries) InsertRevision(ctx context.Context, arg InsertRevisionParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertRevision,
+		arg.ApID,
+		arg.ArticleID,
+		arg.UserID,
+		arg.Summary,
+		arg.Diff,
+		arg.Prev,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertTombstone = `-- name: InsertTombstone :exec
+INSERT INTO tombstones (ap_id, former_type) VALUES (?, ?)
+ON CONFLICT (ap_id) DO UPDATE SET
+    former_type = excluded.former_type,
+    deleted = cast(strftime('%s','now') as int)
+`
+
+type InsertTombstoneParams struct {
+	ApID       string
+	FormerType string
+}
+
+func (q *Queries) InsertTombstone(ctx context.Context, arg InsertTombstoneParams) error {
+	_, err := q.db.ExecContext(ctx, insertTombstone, arg.ApID, arg.FormerType)
+	return err
+}
+
+const isUserAdmin = `-- name: IsUserAdmin :one
+SELECT a.admin FROM accounts a WHERE a.user_id = ? LIMIT 1
+`
+
+func (q *Queries) IsUserAdmin(ctx context.Context, userID int64) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isUserAdmin, userID)
+	var admin bool
+	err := row.Scan(&admin)
+	return admin, err
+}
+
+const isUserTrusted = `-- name: IsUserTrusted :one
+SELECT trusted FROM users where id = ?1 LIMIT 1
+`
+
+func (q *Queries) IsUserTrusted(ctx context.Context, id int64) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isUserTrusted, id)
+	var trusted bool
+	err := row.Scan(&trusted)
+	return trusted, err
+}
+
+const killDelivery = `-- name: KillDelivery :exec
+UPDATE deliveries
+SET
+    attempts = ?,
+    last_error = ?,
+    dead = true
+WHERE id = ?
+`
+
+type KillDeliveryParams struct {
+	Attempts  int64
+	LastError sql.NullString
+	ID        int64
+}
+
+func (q *Queries) KillDelivery(ctx context.Context, arg KillDeliveryParams) error {
+	_, err := q.db.ExecContext(ctx, killDelivery, arg.Attempts, arg.LastError, arg.ID)
+	return err
+}
+
+const leaseDelivery = `-- name: LeaseDelivery :exec
+UPDATE deliveries SET next_attempt = ? WHERE id = ?
+`
+
+type LeaseDeliveryParams struct {
+	NextAttempt int64
+	ID          int64
+}
+
+func (q *Queries) LeaseDelivery(ctx context.Context, arg LeaseDeliveryParams) error {
+	_, err := q.db.ExecContext(ctx, leaseDelivery, arg.NextAttempt, arg.ID)
+	return err
+}
+
+const listAnnounces = `-- name: ListAnnounces :many
+SELECT s.ap_id
+FROM announces s
+JOIN articles a ON a.id = s.article_id
+WHERE a.ap_id = ?
+ORDER BY s.id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListAnnouncesParams struct {
+	ApID   string
+	Limit  int64
+	Offset int64
+}
+
+func (q *Queries) ListAnnounces(ctx context.Context, arg ListAnnouncesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listAnnounces, arg.ApID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var ap_id string
+		if err := rows.Scan(&ap_id); err != nil {
+			return nil, err
+		}
+		items = append(items, ap_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listArticleAuthors = `-- name: ListArticleAuthors :many
+SELECT u.ap_id
+FROM revisions r
+JOIN users u ON u.id = r.user_id
+WHERE r.article_id = ? AND r.published
+GROUP BY u.ap_id
+ORDER BY MIN(r.id)
+`
+
+func (q *Queries) ListArticleAuthors(ctx context.Context, articleID int64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listArticleAuthors, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var ap_id string
+		if err := rows.Scan(&ap_id); err != nil {
+			return nil, err
+		}
+		items = append(items, ap_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listArticleComments = `-- name: ListArticleComments :many
+SELECT
+    c.id,
+    c.in_reply_to,
+    c.content,
+    c.hidden,
+    c.created,
+    u.username,
+    u.domain
+FROM comments c
+JOIN articles a ON a.id = c.article_id
+JOIN users u ON u.id = c.user_id
+WHERE a.ap_id = ?
+ORDER BY c.id
+`
+
+type ListArticleCommentsRow struct {
+	ID        int64
+	InReplyTo sql.NullInt64
+	Content   string
+	Hidden    bool
+	Created   int64
+	Username  string
+	Domain    sql.NullString
+}
+
+func (q *Queries) ListArticleComments(ctx context.Context, apID string) ([]ListArticleCommentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listArticleComments, apID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListArticleCommentsRow
+	for rows.Next() {
+		var i ListArticleCommentsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.InReplyTo,
+			&i.Content,
+			&i.Hidden,
+			&i.Created,
+			&i.Username,
+			&i.Domain,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listArticleFiles = `-- name: ListArticleFiles :many
+SELECT
+    f.ap_id,
+    f.name,
+    f.filename,
+    f.type,
+    f.mime_type,
+    f.size_bytes,
+    f.local,
+    f.url,
+    f.created,
+    u.ap_id AS uploader
+FROM article_files af
+JOIN files f ON f.id = af.file_id
+LEFT JOIN users u ON u.id = f.uploaded_by
+WHERE af.article_id = ?
+ORDER BY f.id
+`
+
+type ListArticleFilesRow struct {
+	ApID      string
+	Name      sql.NullString
+	Filename  sql.NullString
+	Type      string
+	MimeType  string
+	SizeBytes sql.NullInt64
+	Local     bool
+	Url       string
+	Created   int64
+	Uploader  sql.NullString
+}
+
+func (q *Queries) ListArticleFiles(ctx context.Context, articleID int64) ([]ListArticleFilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listArticleFiles, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListArticleFilesRow
+	for rows.Next() {
+		var i ListArticleFilesRow
+		if err := rows.Scan(
+			&i.ApID,
+			&i.Name,
+			&i.Filename,
+			&i.Type,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.Local,
+			&i.Url,
+			&i.Created,
+			&i.Uploader,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listArticleRevisions = `-- name: ListArticleRevisions :many
+SELECT
+    r.id,
+    r.ap_id,
+    r.summary,
+    r.diff,
+    r.prev IS NULL AND NOT r.proposal AS initial,
+    r.created,
+    u.ap_id AS author
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+WHERE a.ap_id = ? AND r.published
+ORDER BY r.created, r.id
+`
+
+type ListArticleRevisionsRow struct {
+	ID      int64
+	ApID    sql.NullString
+	Summary sql.NullString
+	Diff    string
+	Initial sql.NullBool
+	Created int64
+	Author  string
+}
+
+func (q *Queries) ListArticleRevisions(ctx context.Context, apID string) ([]ListArticleRevisionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listArticleRevisions, apID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListArticleRevisionsRow
+	for rows.Next() {
+		var i ListArticleRevisionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ApID,
+			&i.Summary,
+			&i.Diff,
+			&i.Initial,
+			&i.Created,
+			&i.Author,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listArticlesWithoutKey = `-- name: ListArticlesWithoutKey :many
+SELECT ap_id FROM articles WHERE local AND private_key IS NULL
+`
+
+func (q *Queries) ListArticlesWithoutKey(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listArticlesWithoutKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var ap_id string
+		if err := rows.Scan(&ap_id); err != nil {
+			return nil, err
+		}
+		items = append(items, ap_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBacklinks = `-- name: ListBacklinks :many
+SELECT a.title
+FROM article_links l
+JOIN articles a ON a.id = l.article_id
+WHERE l.target = ? AND a.local
+ORDER BY a.title
+`
+
+func (q *Queries) ListBacklinks(ctx context.Context, target string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listBacklinks, target)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		items = append(items, title)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueDeliveries = `-- name: ListDueDeliveries :many
+SELECT
+    id,
+    actor,
+    inbox,
+    activity,
+    attempts
+FROM deliveries
+WHERE NOT dead AND next_attempt <= ?1
+ORDER BY next_attempt
+LIMIT ?2
+`
+
+type ListDueDeliveriesParams struct {
+	Now   int64
+	Limit int64
+}
+
+type ListDueDeliveriesRow struct {
+	ID       int64
+	Actor    string
+	Inbox    string
+	Activity string
+	Attempts int64
+}
+
+func (q *Queries) ListDueDeliveries(ctx context.Context, arg ListDueDeliveriesParams) ([]ListDueDeliveriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDueDeliveries, arg.Now, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDueDeliveriesRow
+	for rows.Next() {
+		var i ListDueDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.Inbox,
+			&i.Activity,
+			&i.Attempts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExistingArticles = `-- name: ListExistingArticles :many
+SELECT title FROM articles WHERE local AND title IN (/*SLICE:titles*/?)
+`
+
+func (q *Queries) ListExistingArticles(ctx context.Context, titles []string) ([]string, error) {
+	query := listExistingArticles
+	var queryParams []interface{}
+	if len(titles) > 0 {
+		for _, v := range titles {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:titles*/?", strings.Repeat(",?", len(titles))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:titles*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		items = append(items, title)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowerInboxes = `-- name: ListFollowerInboxes :many
+SELECT DISTINCT u.inbox
+FROM follows f
+JOIN users u ON u.id = f.follower
+WHERE f.followee IN (/*SLICE:followees*/?) AND f.accepted AND NOT u.local AND u.inbox IS NOT NULL
+`
+
+func (q *Queries) ListFollowerInboxes(ctx context.Context, followees []string) ([]string, error) {
+	query := listFollowerInboxes
+	var queryParams []interface{}
+	if len(followees) > 0 {
+		for _, v := range followees {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:followees*/?", strings.Repeat(",?", len(followees))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:followees*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var inbox string
+		if err := rows.Scan(&inbox); err != nil {
+			return nil, err
+		}
+		items = append(items, inbox)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowers = `-- name: ListFollowers :many
+SELECT u.ap_id
+FROM follows f
+JOIN users u ON u.id = f.follower
+WHERE f.followee = ? AND f.accepted
+ORDER BY f.id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListFollowersParams struct {
+	Followee string
+	Limit    int64
+	Offset   int64
+}
+
+func (q *Queries) ListFollowers(ctx context.Context, arg ListFollowersParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowers, arg.Followee, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var ap_id string
+		if err := rows.Scan(&ap_id); err != nil {
+			return nil, err
+		}
+		items = append(items, ap_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowing = `-- name: ListFollowing :many
+SELECT f.followee
+FROM follows f
+JOIN users u ON u.id = f.follower
+WHERE u.ap_id = ? AND f.accepted
+ORDER BY f.id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListFollowingParams struct {
+	ApID   string
+	Limit  int64
+	Offset int64
+}
+
+func (q *Queries) ListFollowing(ctx context.Context, arg ListFollowingParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowing, arg.ApID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var followee string
+		if err := rows.Scan(&followee); err != nil {
+			return nil, err
+		}
+		items = append(items, followee)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstances = `-- name: ListInstances :many
+SELECT
+    i.hostname,
+    i.policy,
+    i.interwiki_prefix,
+    i.created,
+    (SELECT COUNT(*) FROM users u WHERE NOT u.local AND u.domain = i.hostname) AS users,
+    (SELECT COUNT(*) FROM articles a WHERE a.instance_id = i.id) AS articles
+FROM instances i
+ORDER BY i.hostname
+`
+
+type ListInstancesRow struct {
+	Hostname        string
+	Policy          sql.NullString
+	InterwikiPrefix sql.NullString
+	Created         int64
+	Users           int64
+	Articles        int64
+}
+
+func (q *Queries) ListInstances(ctx context.Context) ([]ListInstancesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listInstances)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListInstancesRow
+	for rows.Next() {
+		var i ListInstancesRow
+		if err := rows.Scan(
+			&i.Hostname,
+			&i.Policy,
+			&i.InterwikiPrefix,
+			&i.Created,
+			&i.Users,
+			&i.Articles,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInterwikiPrefixes = `-- name: ListInterwikiPrefixes :many
+SELECT interwiki_prefix, hostname FROM instances WHERE interwiki_prefix IS NOT NULL
+`
+
+type ListInterwikiPrefixesRow struct {
+	InterwikiPrefix sql.NullString
+	Hostname        string
+}
+
+func (q *Queries) ListInterwikiPrefixes(ctx context.Context) ([]ListInterwikiPrefixesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listInterwikiPrefixes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListInterwikiPrefixesRow
+	for rows.Next() {
+		var i ListInterwikiPrefixesRow
+		if err := rows.Scan(&i.InterwikiPrefix, &i.Hostname); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLikes = `-- name: ListLikes :many
+SELECT l.ap_id
+FROM likes l
+JOIN articles a ON a.id = l.article_id
+WHERE a.ap_id = ?
+ORDER BY l.id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListLikesParams struct {
+	ApID   string
+	Limit  int64
+	Offset int64
+}
+
+func (q *Queries) ListLikes(ctx context.Context, arg ListLikesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listLikes, arg.ApID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var ap_id string
+		if err := rows.Scan(&ap_id); err != nil {
+			return nil, err
+		}
+		items = append(items, ap_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLocalRecipients = `-- name: ListLocalRecipients :many
+SELECT u.inbox FROM users u
+WHERE u.local AND (u.ap_id = ?1 OR u.followers = ?1)
+UNION
+SELECT a.ap_id || '/inbox' FROM articles a
+WHERE a.local AND (a.ap_id = ?1 OR a.ap_id || '/followers' = ?1)
+UNION
+SELECT lu.inbox FROM follows f
+JOIN users lu ON lu.id = f.follower
+WHERE lu.local AND f.accepted AND (
+    f.followee = ?1
+    OR EXISTS (SELECT 1 FROM users ru WHERE ru.ap_id = f.followee AND ru.followers = ?1)
+)
+`
+
+func (q *Queries) ListLocalRecipients(ctx context.Context, apID string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listLocalRecipients, apID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var inbox string
+		if err := rows.Scan(&inbox); err != nil {
+			return nil, err
+		}
+		items = append(items, inbox)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLocalRevisions = `-- name: ListLocalRevisions :many
+SELECT
+    r.id,
+    r.ap_id,
+    r.summary,
+    r.diff,
+    r.prev IS NULL AND NOT r.proposal AS initial,
+    r.created,
+    a.ap_id AS article,
+    u.ap_id AS author
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+WHERE a.local AND r.published AND (?1 IS NULL OR a.ap_id = ?1)
+ORDER BY r.id DESC
+LIMIT ?3 OFFSET ?2
+`
+
+type ListLocalRevisionsParams struct {
+	Article interface{}
+	Offset  int64
+	Limit   int64
+}
+
+type ListLocalRevisionsRow struct {
+	ID      int64
+	ApID    sql.NullString
+	Summary sql.NullString
+	Diff    string
+	Initial sql.NullBool
+	Created int64
+	Article string
+	Author  string
+}
+
+func (q *Queries) ListLocalRevisions(ctx context.Context, arg ListLocalRevisionsParams) ([]ListLocalRevisionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLocalRevisions, arg.Article, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLocalRevisionsRow
+	for rows.Next() {
+		var i ListLocalRevisionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ApID,
+			&i.Summary,
+			&i.Diff,
+			&i.Initial,
+			&i.Created,
+			&i.Article,
+			&i.Author,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMissingLinks = `-- name: ListMissingLinks :many
+SELECT l.target
+FROM article_links l
+JOIN articles a ON a.id = l.article_id
+WHERE a.ap_id = ? AND NOT EXISTS (
+    SELECT 1 FROM articles t WHERE t.local AND t.title = l.target
+)
+ORDER BY l.target
+`
+
+func (q *Queries) ListMissingLinks(ctx context.Context, apID string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listMissingLinks, apID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var target string
+		if err := rows.Scan(&target); err != nil {
+			return nil, err
+		}
+		items = append(items, target)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingProposals = `-- name: ListPendingProposals :many
+SELECT
+    r.id,
+    r.summary,
+    r.diff,
+    a.title,
+    u.username,
+    u.domain,
+    r.created
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+WHERE a.local AND r.proposal AND NOT r.reviewed
+ORDER BY r.id
+`
+
+type ListPendingProposalsRow struct {
+	ID       int64
+	Summary  sql.NullString
+	Diff     string
+	Title    string
+	Username string
+	Domain   sql.NullString
+	Created  int64
+}
+
+func (q *Queries) ListPendingProposals(ctx context.Context) ([]ListPendingProposalsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingProposals)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPendingProposalsRow
+	for rows.Next() {
+		var i ListPendingProposalsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Summary,
+			&i.Diff,
+			&i.Title,
+			&i.Username,
+			&i.Domain,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSharedInboxes = `-- name: ListSharedInboxes :many
+SELECT inbox, shared_inbox FROM users WHERE inbox IN (/*SLICE:inboxes*/?) AND shared_inbox IS NOT NULL
+`
+
+type ListSharedInboxesRow struct {
+	Inbox       string
+	SharedInbox sql.NullString
+}
+
+func (q *Queries) ListSharedInboxes(ctx context.Context, inboxes []string) ([]ListSharedInboxesRow, error) {
+	query := listSharedInboxes
+	var queryParams []interface{}
+	if len(inboxes) > 0 {
+		for _, v := range inboxes {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:inboxes*/?", strings.Repeat(",?", len(inboxes))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:inboxes*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSharedInboxesRow
+	for rows.Next() {
+		var i ListSharedInboxesRow
+		if err := rows.Scan(&i.Inbox, &i.SharedInbox); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnlinkedArticles = `-- name: ListUnlinkedArticles :many
+SELECT ap_id, media_type, content
+FROM articles a
+WHERE a.local AND NOT a.links_indexed AND a.content LIKE '%[[%'
+`
+
+type ListUnlinkedArticlesRow struct {
+	ApID      string
+	MediaType string
+	Content   string
+}
+
+func (q *Queries) ListUnlinkedArticles(ctx context.Context) ([]ListUnlinkedArticlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUnlinkedArticles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnlinkedArticlesRow
+	for rows.Next() {
+		var i ListUnlinkedArticlesRow
+		if err := rows.Scan(&i.ApID, &i.MediaType, &i.Content); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserRevisions = `-- name: ListUserRevisions :many
+SELECT
+    r.id,
+    r.ap_id,
+    r.summary,
+    r.diff,
+    r.prev IS NULL AND NOT r.proposal AS initial,
+    r.created,
+    a.ap_id AS article
+FROM revisions r
+JOIN articles a ON a.id = r.article_id
+JOIN users u ON u.id = r.user_id
+WHERE u.ap_id = ? AND r.published
+ORDER BY r.id DESC
+LIMIT ? OFFSET ?
+`
+
+type ListUserRevisionsParams struct {
+	ApID   string
+	Limit  int64
+	Offset int64
+}
+
+type ListUserRevisionsRow struct {
+	ID      int64
+	ApID    sql.NullString
+	Summary sql.NullString
+	Diff    string
+	Initial sql.NullBool
+	Created int64
+	Article string
+}
+
+func (q *Queries) ListUserRevisions(ctx context.Context, arg ListUserRevisionsParams) ([]ListUserRevisionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserRevisions, arg.ApID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserRevisionsRow
+	for rows.Next() {
+		var i ListUserRevisionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ApID,
+			&i.Summary,
+			&i.Diff,
+			&i.Initial,
+			&i.Created,
+			&i.Article,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const objectExists = `-- name: ObjectExists :one
+SELECT
+    EXISTS (SELECT 1 FROM users u WHERE u.ap_id = ?1)
+    OR EXISTS (SELECT 1 FROM articles a WHERE a.ap_id = ?1)
+    OR EXISTS (SELECT 1 FROM revisions r WHERE r.ap_id = ?1)
+    OR EXISTS (SELECT 1 FROM files f WHERE f.ap_id = ?1)
+`
+
+func (q *Queries) ObjectExists(ctx context.Context, apID string) (sql.NullBool, error) {
+	row := q.db.QueryRowContext(ctx, objectExists, apID)
+	var column_1 sql.NullBool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const outboxForInbox = `-- name: OutboxForInbox :one
+SELECT outbox from users where inbox = ?
+`
+
+func (q *Queries) OutboxForInbox(ctx context.Context, inbox string) (sql.NullString, error) {
+	row := q.db.QueryRowContext(ctx, outboxForInbox, inbox)
+	var outbox sql.NullString
+	err := row.Scan(&outbox)
+	return outbox, err
+}
+
+const reparentReplies = `-- name: ReparentReplies :exec
+UPDATE comments
+SET in_reply_to = (SELECT p.in_reply_to FROM comments p WHERE p.id = ?1)
+WHERE in_reply_to = ?1
+`
+
+func (q *Queries) ReparentReplies(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, reparentReplies, id)
+	return err
+}
+
+const rescheduleDelivery = `-- name: RescheduleDelivery :exec
+UPDATE deliveries
+SET
+    attempts = ?,
+    next_attempt = ?,
+    last_error = ?
+WHERE id = ?
+`
+
+type RescheduleDeliveryParams struct {
+	Attempts    int64
+	NextAttempt int64
+	LastError   sql.NullString
+	ID          int64
+}
+
+func (q *Queries) RescheduleDelivery(ctx context.Context, arg RescheduleDeliveryParams) error {
+	_, err := q.db.ExecContext(ctx, rescheduleDelivery,
+		arg.Attempts,
+		arg.NextAttempt,
+		arg.LastError,
+		arg.ID,
+	)
+	return err
+}
+
+const reviewRevision = `-- name: ReviewRevision :execrows
+UPDATE revisions
+SET
+    reviewed = TRUE,
+    published = ?1,
+    reviewer = ?2,
+    reviewed_at = datetime('now')
+WHERE ap_id = ?3 AND proposal AND NOT reviewed
+`
+
+type ReviewRevisionParams struct {
+	Accepted bool
+	Reviewer sql.NullInt64
+	ApID     sql.NullString
+}
+
+func (q *Queries) ReviewRevision(ctx context.Context, arg ReviewRevisionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reviewRevision, arg.Accepted, arg.Reviewer, arg.ApID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateUserKey = `-- name: RotateUserKey :execrows
+UPDATE users SET
+    previous_key_id = key_id,
+    previous_public_key = public_key,
+    previous_key_expires = ?1,
+    key_id = ?2,
+    public_key = ?3,
+    private_key = ?4,
+    last_updated = cast(strftime('%s','now') as int)
+WHERE local AND ap_id = ?5
+`
+
+type RotateUserKeyParams struct {
+	PreviousKeyExpires sql.NullInt64
+	KeyID              sql.NullString
+	PublicKey          string
+	PrivateKey         string
+	ApID               string
+}
+
+func (q *Queries) RotateUserKey(ctx context.Context, arg RotateUserKeyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rotateUserKey,
+		arg.PreviousKeyExpires,
+		arg.KeyID,
+		arg.PublicKey,
+		arg.PrivateKey,
+		arg.ApID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setArticleKeys = `-- name: SetArticleKeys :exec
+UPDATE articles SET public_key = ?, private_key = ? WHERE local AND ap_id = ?
 `
 
-type InsertFileParams struct {
-	Local      bool
-	Digest     string
-	Path       sql.NullString
+type SetArticleKeysParams struct {
+	PublicKey  sql.NullString
+	PrivateKey sql.NullString
 	ApID       string
-	Name       sql.NullString
-	Filename   sql.NullString
-	Type       string
-	MimeType   string
-	SizeBytes  sql.NullInt64
-	UploadedBy sql.NullInt64
-	Url        string
 }
 
-func (q *Queries) InsertFile(ctx context.Context, arg InsertFileParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, insertFile,
-		arg.Local,
-		arg.Digest,
-		arg.Path,
-		arg.ApID,
-		arg.Name,
-		arg.Filename,
-		arg.Type,
-		arg.MimeType,
-		arg.SizeBytes,
-		arg.UploadedBy,
-		arg.Url,
-	)
-	var id int64
-	err := row.Scan(&id)
-	return id, err
+func (q *Queries) SetArticleKeys(ctx context.Context, arg SetArticleKeysParams) error {
+	_, err := q.db.ExecContext(ctx, setArticleKeys, arg.PublicKey, arg.PrivateKey, arg.ApID)
+	return err
 }
 
-const insertInstance = `-- name: InsertInstance :one
-INSERT INTO instances (hostname, public_key, inbox) VALUES (?, ?, ?) RETURNING id
+const setCommentApId = `-- name: SetCommentApId :exec
+UPDATE comments SET ap_id = ?1, url = ?2 WHERE id = ?3
 `
 
-type InsertInstanceParams struct {
-	Hostname  string
+type SetCommentApIdParams struct {
+	ApID sql.NullString
+	Url  sql.NullString
+	ID   int64
+}
+
+func (q *Queries) SetCommentApId(ctx context.Context, arg SetCommentApIdParams) error {
+	_, err := q.db.ExecContext(ctx, setCommentApId, arg.ApID, arg.Url, arg.ID)
+	return err
+}
+
+const setCommentHidden = `-- name: SetCommentHidden :execrows
+UPDATE comments SET hidden = ?1 WHERE id = ?2
+`
+
+type SetCommentHiddenParams struct {
+	Hidden bool
+	ID     int64
+}
+
+func (q *Queries) SetCommentHidden(ctx context.Context, arg SetCommentHiddenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setCommentHidden, arg.Hidden, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setInstanceKey = `-- name: SetInstanceKey :exec
+UPDATE instances
+SET
+    public_key = ?,
+    inbox = ?,
+    updated = cast(strftime('%s','now') as int)
+WHERE hostname = ?
+`
+
+type SetInstanceKeyParams struct {
 	PublicKey sql.NullString
 	Inbox     sql.NullString
+	Hostname  string
 }
 
-func (q *Queries) InsertInstance(ctx context.Context, arg InsertInstanceParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, insertInstance, arg.Hostname, arg.PublicKey, arg.Inbox)
-	var id int64
-	err := row.Scan(&id)
-	return id, err
+func (q *Queries) SetInstanceKey(ctx context.Context, arg SetInstanceKeyParams) error {
+	_, err := q.db.ExecContext(ctx, setInstanceKey, arg.PublicKey, arg.Inbox, arg.Hostname)
+	return err
 }
 
-const insertRevision = `-- name: InsertRevision :exec
-INSERT INTO revisions (
-    ap_id,
-    article_id,
-    user_id,
-    summary,
-    diff,
-    published,
-    prev
-) VALUES (?1, ?2, ?3, ?4, ?5, true, ?6)
+const setInstancePolicy = `-- name: SetInstancePolicy :exec
+UPDATE instances
+SET
+    policy = ?,
+    updated = cast(strftime('%s','now') as int)
+WHERE hostname = ?
 `
 
-type InsertRevisionParams struct {
-	ApID      sql.NullString
-	ArticleID int64
-	UserID    int64
-	Summary   sql.NullString
-	Diff      string
-	Prev      sql.NullInt64
+type SetInstancePolicyParams struct {
+	Policy   sql.NullString
+	Hostname string
 }
 
-func (q *Queries) InsertRevision(ctx context.Context, arg InsertRevisionParams) error {
-	_, err := q.db.ExecContext(ctx, insertRevision,
-		arg.ApID,
-		arg.ArticleID,
-		arg.UserID,
-		arg.Summary,
-		arg.Diff,
-		arg.Prev,
-	)
+func (q *Queries) SetInstancePolicy(ctx context.Context, arg SetInstancePolicyParams) error {
+	_, err := q.db.ExecContext(ctx, setInstancePolicy, arg.Policy, arg.Hostname)
 	return err
 }
 
-const isUserTrusted = `-- name: IsUserTrusted :one
-SELECT trusted FROM users where id = ?1 LIMIT 1
+const setInterwikiPrefix = `-- name: SetInterwikiPrefix :exec
+UPDATE instances
+SET
+    interwiki_prefix = ?,
+    updated = cast(strftime('%s','now') as int)
+WHERE hostname = ?
 `
 
-func (q *Queries) IsUserTrusted(ctx context.Context, id int64) (bool, error) {
-	row := q.db.QueryRowContext(ctx, isUserTrusted, id)
-	var trusted bool
-	err := row.Scan(&trusted)
-	return trusted, err
+type SetInterwikiPrefixParams struct {
+	InterwikiPrefix sql.NullString
+	Hostname        string
 }
 
-const outboxForInbox = `-- name: OutboxForInbox :one
-SELECT outbox from users where inbox = ?
+func (q *Queries) SetInterwikiPrefix(ctx context.Context, arg SetInterwikiPrefixParams) error {
+	_, err := q.db.ExecContext(ctx, setInterwikiPrefix, arg.InterwikiPrefix, arg.Hostname)
+	return err
+}
+
+const setLinksIndexed = `-- name: SetLinksIndexed :exec
+UPDATE articles SET links_indexed = TRUE WHERE id = ?
 `
 
-func (q *Queries) OutboxForInbox(ctx context.Context, inbox string) (string, error) {
-	row := q.db.QueryRowContext(ctx, outboxForInbox, inbox)
-	var outbox string
-	err := row.Scan(&outbox)
-	return outbox, err
+func (q *Queries) SetLinksIndexed(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, setLinksIndexed, id)
+	return err
+}
+
+const setLocalRevisionApId = `-- name: SetLocalRevisionApId :one
+UPDATE revisions
+SET ap_id = (SELECT a.ap_id FROM articles a WHERE a.id = revisions.article_id) || '/history/' || revisions.id
+WHERE revisions.id = ?1
+RETURNING ap_id
+`
+
+func (q *Queries) SetLocalRevisionApId(ctx context.Context, id int64) (sql.NullString, error) {
+	row := q.db.QueryRowContext(ctx, setLocalRevisionApId, id)
+	var ap_id sql.NullString
+	err := row.Scan(&ap_id)
+	return ap_id, err
+}
+
+const setRevisionApId = `-- name: SetRevisionApId :exec
+UPDATE revisions SET ap_id = ? WHERE id = ?
+`
+
+type SetRevisionApIdParams struct {
+	ApID sql.NullString
+	ID   int64
+}
+
+func (q *Queries) SetRevisionApId(ctx context.Context, arg SetRevisionApIdParams) error {
+	_, err := q.db.ExecContext(ctx, setRevisionApId, arg.ApID, arg.ID)
+	return err
 }
 
 const updateArticle = `-- name: UpdateArticle :exec
@@ -756,6 +3376,281 @@ func (q *Queries) UpdateArticle(ctx context.Context, arg UpdateArticleParams) er
 	return err
 }
 
+const upsertAnnounce = `-- name: UpsertAnnounce :exec
+INSERT INTO announces (ap_id, user_id, article_id)
+VALUES (
+    ?1,
+    (SELECT u.id FROM users u WHERE u.ap_id = ?2),
+    (SELECT a.id FROM articles a WHERE a.ap_id = ?3)
+)
+ON CONFLICT (user_id, article_id) DO UPDATE SET ap_id = excluded.ap_id
+`
+
+type UpsertAnnounceParams struct {
+	ApID    string
+	Actor   string
+	Article string
+}
+
+func (q *Queries) UpsertAnnounce(ctx context.Context, arg UpsertAnnounceParams) error {
+	_, err := q.db.ExecContext(ctx, upsertAnnounce, arg.ApID, arg.Actor, arg.Article)
+	return err
+}
+
+const upsertFollow = `-- name: UpsertFollow :exec
+INSERT INTO follows (ap_id, follower, followee, accepted)
+VALUES (?, (SELECT id FROM users WHERE users.ap_id = ?), ?, ?)
+ON CONFLICT (follower, followee) DO UPDATE SET
+    ap_id = excluded.ap_id,
+    accepted = excluded.accepted
+`
+
+type UpsertFollowParams struct {
+	ApID     sql.NullString
+	Follower string
+	Followee string
+	Accepted bool
+}
+
+func (q *Queries) UpsertFollow(ctx context.Context, arg UpsertFollowParams) error {
+	_, err := q.db.ExecContext(ctx, upsertFollow,
+		arg.ApID,
+		arg.Follower,
+		arg.Followee,
+		arg.Accepted,
+	)
+	return err
+}
+
+const upsertForeignArticle = `-- name: UpsertForeignArticle :one
+INSERT INTO articles (
+    local,
+    ap_id,
+    url,
+    instance_id,
+    language,
+    media_type,
+    title,
+    summary,
+    content,
+    forked_from,
+    last_fetched
+) VALUES (false, ?, ?, ?, ?, ?, ?, ?, ?, ?, cast(strftime('%s','now') as int))
+ON CONFLICT (ap_id) DO UPDATE SET
+    url = excluded.url,
+    language = excluded.language,
+    media_type = excluded.media_type,
+    title = excluded.title,
+    summary = excluded.summary,
+    content = excluded.content,
+    forked_from = excluded.forked_from,
+    last_updated = cast(strftime('%s','now') as int),
+    last_fetched = excluded.last_fetched
+WHERE NOT local
+RETURNING id
+`
+
+type UpsertForeignArticleParams struct {
+	ApID       string
+	Url        sql.NullString
+	InstanceID sql.NullInt64
+	Language   string
+	MediaType  string
+	Title      string
+	Summary    sql.NullString
+	Content    string
+	ForkedFrom sql.NullString
+}
+
+func (q *Queries) UpsertForeignArticle(ctx context.Context, arg UpsertForeignArticleParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, upsertForeignArticle,
+		arg.ApID,
+		arg.Url,
+		arg.InstanceID,
+		arg.Language,
+		arg.MediaType,
+		arg.Title,
+		arg.Summary,
+		arg.Content,
+		arg.ForkedFrom,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const upsertForeignComment = `-- name: UpsertForeignComment :exec
+INSERT INTO comments (ap_id, url, article_id, user_id, in_reply_to, content, hidden, created)
+VALUES (
+    ?1,
+    ?2,
+    (SELECT a.id FROM articles a WHERE a.ap_id = ?3),
+    (SELECT u.id FROM users u WHERE u.ap_id = ?4),
+    (SELECT p.id FROM comments p WHERE p.ap_id = ?5),
+    ?6,
+    ?7,
+    ?8
+)
+ON CONFLICT (ap_id) DO UPDATE SET
+    url = excluded.url,
+    content = excluded.content
+`
+
+type UpsertForeignCommentParams struct {
+	ApID      sql.NullString
+	Url       sql.NullString
+	Article   string
+	Author    string
+	InReplyTo sql.NullString
+	Content   string
+	Hidden    bool
+	Created   int64
+}
+
+func (q *Queries) UpsertForeignComment(ctx context.Context, arg UpsertForeignCommentParams) error {
+	_, err := q.db.ExecContext(ctx, upsertForeignComment,
+		arg.ApID,
+		arg.Url,
+		arg.Article,
+		arg.Author,
+		arg.InReplyTo,
+		arg.Content,
+		arg.Hidden,
+		arg.Created,
+	)
+	return err
+}
+
+const upsertForeignFile = `-- name: UpsertForeignFile :exec
+INSERT INTO files (
+    local,
+    ap_id,
+    name,
+    type,
+    mime_type,
+    size_bytes,
+    url
+) VALUES (false, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (ap_id) DO UPDATE SET
+    name = excluded.name,
+    type = excluded.type,
+    mime_type = excluded.mime_type,
+    size_bytes = excluded.size_bytes,
+    url = excluded.url
+WHERE NOT local
+`
+
+type UpsertForeignFileParams struct {
+	ApID      string
+	Name      sql.NullString
+	Type      string
+	MimeType  string
+	SizeBytes sql.NullInt64
+	Url       string
+}
+
+func (q *Queries) UpsertForeignFile(ctx context.Context, arg UpsertForeignFileParams) error {
+	_, err := q.db.ExecContext(ctx, upsertForeignFile,
+		arg.ApID,
+		arg.Name,
+		arg.Type,
+		arg.MimeType,
+		arg.SizeBytes,
+		arg.Url,
+	)
+	return err
+}
+
+const upsertForeignUser = `-- name: UpsertForeignUser :one
+INSERT INTO users (
+    local,
+    bot,
+    ap_id,
+    url,
+    username,
+    name,
+    domain,
+    summary,
+    inbox,
+    outbox,
+    followers,
+    public_key,
+    shared_inbox,
+    trusted,
+    last_fetched
+) VALUES (false, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, false, cast(strftime('%s','now') as int))
+ON CONFLICT (ap_id) DO UPDATE SET
+    bot = excluded.bot,
+    url = excluded.url,
+    username = excluded.username,
+    name = excluded.name,
+    summary = excluded.summary,
+    inbox = excluded.inbox,
+    outbox = excluded.outbox,
+    followers = excluded.followers,
+    public_key = excluded.public_key,
+    shared_inbox = excluded.shared_inbox,
+    last_updated = cast(strftime('%s','now') as int),
+    last_fetched = excluded.last_fetched
+RETURNING id
+`
+
+type UpsertForeignUserParams struct {
+	Bot         bool
+	ApID        string
+	Url         sql.NullString
+	Username    string
+	Name        string
+	Domain      sql.NullString
+	Summary     sql.NullString
+	Inbox       string
+	Outbox      sql.NullString
+	Followers   sql.NullString
+	PublicKey   string
+	SharedInbox sql.NullString
+}
+
+func (q *Queries) UpsertForeignUser(ctx context.Context, arg UpsertForeignUserParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, upsertForeignUser,
+		arg.Bot,
+		arg.ApID,
+		arg.Url,
+		arg.Username,
+		arg.Name,
+		arg.Domain,
+		arg.Summary,
+		arg.Inbox,
+		arg.Outbox,
+		arg.Followers,
+		arg.PublicKey,
+		arg.SharedInbox,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const upsertLike = `-- name: UpsertLike :exec
+INSERT INTO likes (ap_id, user_id, article_id)
+VALUES (
+    ?1,
+    (SELECT u.id FROM users u WHERE u.ap_id = ?2),
+    (SELECT a.id FROM articles a WHERE a.ap_id = ?3)
+)
+ON CONFLICT (user_id, article_id) DO UPDATE SET ap_id = excluded.ap_id
+`
+
+type UpsertLikeParams struct {
+	ApID    string
+	Actor   string
+	Article string
+}
+
+func (q *Queries) UpsertLike(ctx context.Context, arg UpsertLikeParams) error {
+	_, err := q.db.ExecContext(ctx, upsertLike, arg.ApID, arg.Actor, arg.Article)
+	return err
+}
+
 const userExists = `-- name: UserExists :one
 SELECT COUNT(id) == 1 FROM users WHERE ap_id = ?
 `
@@ -768,7 +3663,9 @@ func (q *Queries) UserExists(ctx context.Context, apID string) (bool, error) {
 }
 
 const userIdByInbox = `-- name: UserIdByInbox :one
-SELECT ap_id from users where inbox = ?
+SELECT ap_id from users where inbox = ?1
+UNION ALL
+SELECT ap_id from articles where local AND ap_id || '/inbox' = ?1
 `
 
 func (q *Queries) UserIdByInbox(ctx context.Context, inbox string) (string, error) {
@@ -782,7 +3679,7 @@ const userIdByOutbox = `-- name: UserIdByOutbox :one
 SELECT ap_id from users where outbox = ?
 `
 
-func (q *Queries) UserIdByOutbox(ctx context.Context, outbox string) (string, error) {
+func (q *Queries) UserIdByOutbox(ctx context.Context, outbox sql.NullString) (string, error) {
 	row := q.db.QueryRowContext(ctx, userIdByOutbox, outbox)
 	var ap_id string
 	err := row.Scan(&ap_id)
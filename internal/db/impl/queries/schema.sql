@@ -17,6 +17,14 @@ CREATE TABLE users (
     created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
     last_updated INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
     last_fetched INT,
+    -- The ID of the current key of a local user, NULL for the default, and the key replaced by its last rotation,
+    -- which is still published until it expires.
+    key_id VARCHAR,
+    previous_key_id VARCHAR,
+    previous_public_key TEXT,
+    previous_key_expires INT,
+    -- The inbox shared by the actors of a foreign server, to which activities for several of them may be posted.
+    shared_inbox VARCHAR,
 
     UNIQUE (username, domain),
     UNIQUE (ap_id),
@@ -82,6 +90,13 @@ CREATE TABLE articles (
     created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
     last_updated INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
     last_fetched INT,
+    -- The keys of local articles, which are actors that can be followed.
+    public_key TEXT,
+    private_key TEXT,
+    -- The article this one was forked from, which is usually hosted by another wiki.
+    forked_from VARCHAR,
+    -- Whether the wiki links of a local article were stored, even if it has none.
+    links_indexed BOOLEAN DEFAULT FALSE NOT NULL,
 
     UNIQUE (ap_id),
     UNIQUE (title, instance_id),
@@ -104,6 +119,8 @@ CREATE TABLE revisions (
     prev INTEGER,
     based_on INTEGER,
     created INTEGER DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+    -- A proposal is reviewed by the server that hosts the article before being published.
+    proposal BOOLEAN DEFAULT FALSE NOT NULL,
 
     UNIQUE (ap_id),
     FOREIGN KEY (user_id) REFERENCES users (id),
@@ -119,12 +136,16 @@ CREATE TABLE instances (
     public_key TEXT,
     inbox VARCHAR(255),
     created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
-    updated INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL
+    updated INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+    -- The federation policy chosen by an admin; NULL if the default applies.
+    policy VARCHAR(16),
+    -- The prefix of the interwiki links to the articles of the instance, as in [[prefix:Title]].
+    interwiki_prefix VARCHAR(32)
 );
 
 CREATE TABLE files (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
-    digest CHAR(64) NOT NULL,
+    digest CHAR(64),
     path VARCHAR(255),
     ap_id VARCHAR(255) NOT NULL,
     name VARCHAR(255),
@@ -149,4 +170,93 @@ CREATE TABLE article_files (
     FOREIGN KEY (article_id) REFERENCES articles (id),
     FOREIGN KEY (file_id) REFERENCES files (id),
     PRIMARY KEY (article_id, file_id)
-);
\ No newline at end of file
+);
+
+CREATE TABLE deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor VARCHAR(255) NOT NULL,
+    inbox VARCHAR(255) NOT NULL,
+    activity TEXT NOT NULL,
+    attempts INTEGER DEFAULT 0 NOT NULL,
+    next_attempt INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+    last_error TEXT,
+    dead BOOLEAN DEFAULT FALSE NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL
+);
+
+CREATE TABLE follows (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ap_id VARCHAR(255),
+    follower INTEGER NOT NULL,
+    followee VARCHAR(255) NOT NULL,
+    accepted BOOLEAN DEFAULT FALSE NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id),
+    UNIQUE (follower, followee),
+    FOREIGN KEY (follower) REFERENCES users (id)
+);
+
+CREATE TABLE likes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ap_id VARCHAR(255) NOT NULL,
+    user_id INTEGER NOT NULL,
+    article_id INTEGER NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id),
+    UNIQUE (user_id, article_id),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (article_id) REFERENCES articles (id)
+);
+
+CREATE TABLE announces (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ap_id VARCHAR(255) NOT NULL,
+    user_id INTEGER NOT NULL,
+    article_id INTEGER NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id),
+    UNIQUE (user_id, article_id),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (article_id) REFERENCES articles (id)
+);
+
+CREATE TABLE comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ap_id VARCHAR(255),
+    url VARCHAR(255),
+    article_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    in_reply_to INTEGER,
+    content TEXT NOT NULL,
+    local BOOLEAN DEFAULT FALSE NOT NULL,
+    hidden BOOLEAN DEFAULT FALSE NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id),
+    FOREIGN KEY (article_id) REFERENCES articles (id),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (in_reply_to) REFERENCES comments (id)
+);
+
+-- The IDs of deleted local objects and foreign users.
+CREATE TABLE tombstones (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ap_id VARCHAR(255) NOT NULL,
+    former_type VARCHAR(32) NOT NULL,
+    deleted INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id)
+);
+
+-- The titles of the articles each local article links to, which may not exist yet.
+CREATE TABLE article_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    article_id INTEGER NOT NULL,
+    target VARCHAR(255) NOT NULL,
+
+    UNIQUE (article_id, target),
+    FOREIGN KEY (article_id) REFERENCES articles (id)
+);
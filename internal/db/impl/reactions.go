@@ -0,0 +1,83 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+
+	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
+)
+
+func (d *dbImpl) AddLike(ctx context.Context, id, actor, article *url.URL) error {
+	err := d.queries.UpsertLike(ctx, queries.UpsertLikeParams{
+		ApID:    id.String(),
+		Actor:   actor.String(),
+		Article: article.String(),
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) RemoveLike(ctx context.Context, actor, article *url.URL) error {
+	n, err := d.queries.DeleteLike(ctx, queries.DeleteLikeParams{
+		Actor:   actor.String(),
+		Article: article.String(),
+	})
+	if err == nil && n == 0 {
+		err = sql.ErrNoRows
+	}
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) ListLikes(ctx context.Context, article *url.URL, limit, offset int) ([]*url.URL, error) {
+	ids, err := d.queries.ListLikes(ctx, queries.ListLikesParams{
+		ApID:   article.String(),
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+	return d.parseAll(ids)
+}
+
+func (d *dbImpl) CountLikes(ctx context.Context, article *url.URL) (int64, error) {
+	n, err := d.queries.CountLikes(ctx, article.String())
+	return n, d.HandleError(err)
+}
+
+func (d *dbImpl) AddAnnounce(ctx context.Context, id, actor, article *url.URL) error {
+	err := d.queries.UpsertAnnounce(ctx, queries.UpsertAnnounceParams{
+		ApID:    id.String(),
+		Actor:   actor.String(),
+		Article: article.String(),
+	})
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) RemoveAnnounce(ctx context.Context, actor, article *url.URL) error {
+	n, err := d.queries.DeleteAnnounce(ctx, queries.DeleteAnnounceParams{
+		Actor:   actor.String(),
+		Article: article.String(),
+	})
+	if err == nil && n == 0 {
+		err = sql.ErrNoRows
+	}
+	return d.HandleError(err)
+}
+
+func (d *dbImpl) ListAnnounces(ctx context.Context, article *url.URL, limit, offset int) ([]*url.URL, error) {
+	ids, err := d.queries.ListAnnounces(ctx, queries.ListAnnouncesParams{
+		ApID:   article.String(),
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		return nil, d.HandleError(err)
+	}
+	return d.parseAll(ids)
+}
+
+func (d *dbImpl) CountAnnounces(ctx context.Context, article *url.URL) (int64, error) {
+	n, err := d.queries.CountAnnounces(ctx, article.String())
+	return n, d.HandleError(err)
+}
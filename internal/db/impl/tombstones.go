@@ -0,0 +1,22 @@
+package impl
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+func (d *dbImpl) GetTombstone(ctx context.Context, id *url.URL) (domain.Tombstone, error) {
+	t, err := d.queries.GetTombstone(ctx, id.String())
+	if err != nil {
+		return domain.Tombstone{}, d.HandleError(err)
+	}
+
+	return domain.Tombstone{
+		ApId:       id,
+		FormerType: t.FormerType,
+		Deleted:    time.Unix(t.Deleted, 0),
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"net/url"
+	"time"
 
 	"github.com/sidereusnuntius/gowiki/internal/db/impl/queries"
 	"github.com/sidereusnuntius/gowiki/internal/domain"
@@ -48,30 +49,69 @@ func (d *dbImpl) GetUser(ctx context.Context, username, hostname string) (user d
 	return
 }
 
+// GetProfile returns the profile of a local user if hostname is empty, or of a foreign one otherwise, along with
+// the revisions of the user we have stored.
 func (d *dbImpl) GetProfile(ctx context.Context, username, hostname string) (p domain.Profile, err error) {
-	u, err := d.GetUser(ctx, username, hostname)
+	if hostname == "" {
+		p.UserCore, err = d.GetUser(ctx, username, hostname)
+	} else {
+		p, err = d.foreignProfile(ctx, username, hostname)
+	}
+	if err != nil {
+		return
+	}
+
+	r, err := d.queries.GetRevisionsByUserId(ctx, p.ID)
 	if err != nil {
+		err = d.HandleError(err)
 		return
 	}
 
-	r, err := d.queries.GetRevisionsByUserId(ctx, u.ID)
-	edits := make([]domain.Revision, 0, len(r))
+	p.Edits = make([]domain.Revision, 0, len(r))
 	for _, r := range r {
-		edits = append(edits, domain.Revision{
-			ID:       r.ID,
-			Title:    r.Title,
-			Reviewed: r.Reviewed,
-			Summary:  r.Summary.String,
-			Created:  r.Created,
+		p.Edits = append(p.Edits, domain.Revision{
+			ID:          r.ID,
+			Title:       r.Title,
+			ArticleHost: r.Hostname.String,
+			Reviewed:    r.Reviewed,
+			Published:   r.Published,
+			Proposal:    r.Proposal,
+			Summary:     r.Summary.String,
+			Created:     r.Created,
 		})
 	}
-	p = domain.Profile{
-		UserCore: u,
-		Edits:    edits,
-	}
+	return
+}
 
+func (d *dbImpl) foreignProfile(ctx context.Context, username, hostname string) (p domain.Profile, err error) {
+	u, err := d.queries.GetForeignUserData(
+		ctx,
+		queries.GetForeignUserDataParams{LOWER: username, Domain: sql.NullString{String: hostname, Valid: true}},
+	)
 	if err != nil {
 		err = d.HandleError(err)
+		return
+	}
+
+	p.UserCore = domain.UserCore{
+		ID:       u.ID,
+		Username: u.Username,
+		Name:     u.Name,
+		Domain:   u.Domain.String,
+		Summary:  u.Summary.String,
+	}
+	if u.Url.Valid {
+		p.URL, _ = url.Parse(u.Url.String)
+	}
+
+	if p.ApId, err = url.Parse(u.ApID); err != nil {
+		return
+	}
+	if u.Outbox.Valid {
+		p.Outbox, _ = url.Parse(u.Outbox.String)
+	}
+	if u.LastFetched.Valid {
+		p.LastFetched = time.Unix(u.LastFetched.Int64, 0)
 	}
 	return
 }
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// Instances stores the other servers we know of and the federation policy chosen for each of them.
+type Instances interface {
+	// ListInstances returns the known instances, ordered by hostname.
+	ListInstances(ctx context.Context) ([]domain.Instance, error)
+	// InstanceExists reports whether the instance with the given hostname is known.
+	InstanceExists(ctx context.Context, hostname string) (bool, error)
+	// GetInstancePolicy returns the policy chosen for the instance with the given hostname, which is empty if
+	// none was chosen or the instance is unknown.
+	GetInstancePolicy(ctx context.Context, hostname string) (domain.Policy, error)
+	// SetInstancePolicy chooses the policy of an instance, storing the instance if it is unknown. An empty policy
+	// restores the default one.
+	SetInstancePolicy(ctx context.Context, hostname string, policy domain.Policy) error
+	// SetInterwikiPrefix chooses the interwiki prefix of an instance, storing the instance if it is unknown. An
+	// empty prefix removes it.
+	SetInterwikiPrefix(ctx context.Context, hostname, prefix string) error
+	// InterwikiPrefixes maps the interwiki prefixes to the hostnames of the instances they were chosen for.
+	InterwikiPrefixes(ctx context.Context) (map[string]string, error)
+	// GetStats counts the local users, articles, edits and comments; users are active if they edited or
+	// commented since the given times.
+	GetStats(ctx context.Context, month, halfyear time.Time) (domain.Stats, error)
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// Links stores the wiki links of local articles, which are used to find the links to missing articles and the
+// articles that link to a given one.
+type Links interface {
+	// SetArticleLinks replaces the titles of the articles a local article links to, and records that its links
+	// were stored.
+	SetArticleLinks(ctx context.Context, article *url.URL, targets []string) error
+	// MissingLinks returns the titles, linked to by a local article, of the local articles that do not exist.
+	MissingLinks(ctx context.Context, article *url.URL) ([]string, error)
+	// ExistingArticles returns which of titles are the titles of local articles.
+	ExistingArticles(ctx context.Context, titles []string) ([]string, error)
+	// Backlinks returns the titles of the local articles that link to the article with the given title, which
+	// may not exist.
+	Backlinks(ctx context.Context, title string) ([]string, error)
+	// ListUnlinkedArticles returns the local articles that may have wiki links but whose links were never
+	// stored, such as those saved before links were. Articles whose links were stored are not listed again,
+	// even if they have none.
+	ListUnlinkedArticles(ctx context.Context) ([]domain.ArticleFed, error)
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"context"
+	"net/url"
+)
+
+// Reactions stores the Likes and Announces of articles. The actor and the article must already be stored.
+type Reactions interface {
+	// AddLike records that an actor liked an article, replacing their previous Like of it.
+	AddLike(ctx context.Context, id, actor, article *url.URL) error
+	RemoveLike(ctx context.Context, actor, article *url.URL) error
+	// ListLikes returns the IDs of the Likes of an article, most recent first.
+	ListLikes(ctx context.Context, article *url.URL, limit, offset int) ([]*url.URL, error)
+	CountLikes(ctx context.Context, article *url.URL) (int64, error)
+	// AddAnnounce records that an actor announced an article, replacing their previous Announce of it.
+	AddAnnounce(ctx context.Context, id, actor, article *url.URL) error
+	RemoveAnnounce(ctx context.Context, actor, article *url.URL) error
+	// ListAnnounces returns the IDs of the Announces of an article, most recent first.
+	ListAnnounces(ctx context.Context, article *url.URL, limit, offset int) ([]*url.URL, error)
+	CountAnnounces(ctx context.Context, article *url.URL) (int64, error)
+}
@@ -0,0 +1,14 @@
+package db
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+type Tombstones interface {
+	// GetTombstone returns the tombstone of the deleted object with the given ID, or ErrNotFound if no such object
+	// was deleted.
+	GetTombstone(ctx context.Context, id *url.URL) (domain.Tombstone, error)
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "net/url"
+import (
+	"net/url"
+	"time"
+)
 
 type ArticleCore struct {
 	Title     string
@@ -10,22 +13,73 @@ type ArticleCore struct {
 	MediaType string
 	License   string
 	Language  string
+	// Likes and Shares count the Likes and Announces of the article received from other servers.
+	Likes  int64
+	Shares int64
+	// ForkedFrom is the ActivityPub ID of the article this one was forked from, if any.
+	ForkedFrom *url.URL
+	// Revision is the ID of the current revision of a local article.
+	Revision int64
 }
 
 type ArticleFed struct {
 	ArticleCore
 	ApID *url.URL
 	Url  *url.URL
+	// Host is the hostname of the server the article belongs to; it is empty for local articles.
+	Host        string
+	Local       bool
+	Created     time.Time
+	LastUpdated time.Time
+	// LastFetched is when the copy of a foreign article was last refreshed from its home server.
+	LastFetched time.Time
+	// AttributedTo lists the authors of the published revisions of the article, in the order of their first
+	// contribution.
+	AttributedTo []*url.URL
+	// Attachments are the files linked to the article.
+	Attachments []File
+	// PublicKey is the key of a local article, which is an actor that can be followed; it is empty for foreign
+	// articles.
+	PublicKey string
 }
 
 type Revision struct {
-	ID       int64
-	Title    string
-	Reviewed bool
+	ID    int64
+	Title string
+	// ArticleHost is the host of the article, if it is a foreign one.
+	ArticleHost string
+	Reviewed    bool
+	Published   bool
+	// Proposal is true for revisions that must be reviewed by the server hosting the article.
+	Proposal bool
 	Diff     string
 	Summary  string
 	Username string
-	Created  int64
+	// Domain is the domain of the author, if they are a foreign user.
+	Domain  string
+	Created int64
+}
+
+// RevisionFed is a revision as it is exchanged with other servers, identified by ActivityPub IDs instead of
+// database IDs.
+type RevisionFed struct {
+	ApID    *url.URL
+	Article *url.URL
+	Author  *url.URL
+	Summary string
+	// Diff is the diff-match-patch patch that turns the previous revision of the article into this one.
+	Diff      string
+	Published bool
+	Reviewed  bool
+	// Proposal is true for revisions that must be reviewed by the server hosting the article.
+	Proposal bool
+	// BasedOn is the revision the proposal was based on, if known.
+	BasedOn *url.URL
+	// Reviewer is the user who reviewed the proposal, if known.
+	Reviewer *url.URL
+	// Initial is true for the revision that created the article.
+	Initial bool
+	Created time.Time
 }
 
 //InstanceID sql.NullInt64
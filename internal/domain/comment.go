@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"net/url"
+	"time"
+)
+
+// Comment is a comment on the discussion page of an article, as shown to readers.
+type Comment struct {
+	ID int64
+	// Username and Domain identify the author; Domain is empty for local users.
+	Username string
+	Domain   string
+	// Content is the HTML content of the comment.
+	Content string
+	Hidden  bool
+	Created time.Time
+	// Replies are the comments made in reply to this one, oldest first.
+	Replies []Comment
+}
+
+// CommentFed is a comment as exchanged with other servers, where it is a Note in reply to an article or to
+// another comment.
+type CommentFed struct {
+	ApID    *url.URL
+	Url     *url.URL
+	Article *url.URL
+	Author  *url.URL
+	// InReplyTo is the comment this one replies to; it is nil for comments made on the article itself.
+	InReplyTo *url.URL
+	Content   string
+	Local     bool
+	Hidden    bool
+	Created   time.Time
+}
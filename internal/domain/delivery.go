@@ -0,0 +1,13 @@
+package domain
+
+import "net/url"
+
+// Delivery is an activity waiting to be posted to a remote inbox.
+type Delivery struct {
+	ID    int64
+	Actor *url.URL
+	Inbox *url.URL
+	// Activity is the serialized activity, ready to be sent.
+	Activity []byte
+	Attempts int
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "net/url"
+import (
+	"net/url"
+	"time"
+)
 
 const (
 	ImageType = "Image"
@@ -23,4 +26,7 @@ type File struct {
     Path string
     ApId *url.URL
     Url *url.URL
+    // Uploader is the ActivityPub ID of the user who uploaded the file, if known.
+    Uploader *url.URL
+    Created time.Time
 }
\ No newline at end of file
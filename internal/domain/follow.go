@@ -0,0 +1,12 @@
+package domain
+
+import "net/url"
+
+// Follow is a follow relationship between two actors.
+type Follow struct {
+	// ApID is the ID of the Follow activity, which may be nil if the activity is not known.
+	ApID     *url.URL
+	Follower *url.URL
+	Followee *url.URL
+	Accepted bool
+}
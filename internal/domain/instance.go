@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Policy is how we federate with another server, as chosen by an admin.
+type Policy string
+
+const (
+	// PolicyAllow federates normally with the server.
+	PolicyAllow Policy = "allow"
+	// PolicyRejectMedia federates with the server, but neither stores its files nor shows its images.
+	PolicyRejectMedia Policy = "reject_media"
+	// PolicySilence accepts the activities of the server, but hides its comments until a moderator shows them
+	// and does not count its Likes and Announces.
+	PolicySilence Policy = "silence"
+	// PolicySuspend stops all federation with the server: its requests are refused, and nothing is delivered
+	// to it nor fetched from it.
+	PolicySuspend Policy = "suspend"
+)
+
+// Policies lists the valid policies, from the most to the least permissive.
+var Policies = []Policy{PolicyAllow, PolicyRejectMedia, PolicySilence, PolicySuspend}
+
+// Instance is another server we know of.
+type Instance struct {
+	Hostname string
+	// Policy is the policy chosen by an admin for the server; it is empty if none was chosen, in which case
+	// the default policy of the wiki applies.
+	Policy Policy
+	// InterwikiPrefix is the prefix of the interwiki links to the articles of the server, as in [[prefix:Title]];
+	// it is empty if an admin chose none.
+	InterwikiPrefix string
+	// Users and Articles count the users and articles of the server we have stored.
+	Users    int64
+	Articles int64
+	Created  time.Time
+}
+
+// Stats summarizes the activity of the local wiki, as published through NodeInfo.
+type Stats struct {
+	// Users counts the local accounts; ActiveMonth and ActiveHalfyear count the local users who edited or
+	// commented in the last 30 and 180 days.
+	Users          int64
+	ActiveMonth    int64
+	ActiveHalfyear int64
+	Articles       int64
+	// Revisions counts the published edits of local users, and Comments the comments they posted.
+	Revisions int64
+	Comments  int64
+}
@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"net/url"
+	"time"
+)
+
+// Tombstone stands for a deleted object, such as a local article or a foreign user.
+type Tombstone struct {
+	ApId *url.URL
+	// FormerType is the ActivityStreams type of the deleted object.
+	FormerType string
+	Deleted    time.Time
+}
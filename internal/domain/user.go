@@ -25,11 +25,23 @@ type UserCore struct {
 
 type UserFed struct {
 	UserCore
-	ApId        *url.URL
-	Inbox       *url.URL
-	Outbox      *url.URL
-	Followers   *url.URL
+	ApId      *url.URL
+	Inbox     *url.URL
+	Outbox    *url.URL
+	Followers *url.URL
+	// SharedInbox is the inbox shared by the actors of the user's server, if it has one.
+	SharedInbox *url.URL
 	PublicKey   string
+	// KeyId is the ID of PublicKey if it differs from the default one, as it does once the key of a local user
+	// is rotated.
+	KeyId *url.URL
+	// PreviousKey is the key of a local user replaced by the last rotation, which is still published during its
+	// grace period so that the signatures made with it can be verified; PreviousKeyId is its ID, nil if it was
+	// the default one.
+	PreviousKey   string
+	PreviousKeyId *url.URL
+	// Bot is true for automated actors, such as those of type Service and Application.
+	Bot         bool
 	Created     time.Time
 	LastUpdated time.Time
 }
@@ -42,7 +54,16 @@ type UserFedInternal struct {
 
 type Profile struct {
 	UserCore
+	// ApId, Outbox and LastFetched are only set for foreign users; LastFetched is when we last refreshed our
+	// copy of the user from their server.
+	ApId        *url.URL
+	Outbox      *url.URL
+	LastFetched time.Time
+	// Edits are the revisions by the user that we have stored, whether of local or foreign articles.
 	Edits []Revision
+	// RemoteEdits are the revisions listed in the outbox of a foreign user, which were made on their own
+	// wiki.
+	RemoteEdits []RevisionFed
 }
 
 type UserInternal struct {
@@ -0,0 +1,306 @@
+package federation
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/config"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
+)
+
+// AcceptHeader is sent when fetching objects from other servers.
+const AcceptHeader = `application/activity+json, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+
+var ErrFetch = errors.New("failed to fetch remote object")
+
+// newClient returns the client of our requests to other servers. Unless Config.AllowPrivateAddresses is set, it
+// refuses to connect to addresses that are not public, whatever the hostname of the server resolved to, and
+// ignores the proxy of the environment, through which it could not tell.
+func newClient(conf config.Configuration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !conf.AllowPrivateAddresses {
+		dialer := &net.Dialer{
+			Timeout: RequestTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				addr, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				if !validate.PublicAddr(addr.Addr()) {
+					return fmt.Errorf("%w: %s", validate.ErrInternalAddress, address)
+				}
+				return nil
+			},
+		}
+		transport.Proxy = nil
+		transport.DialContext = dialer.DialContext
+	}
+	return &http.Client{Timeout: RequestTimeout, Transport: transport}
+}
+
+// Dereference fetches the ActivityStreams object with the given ID from its home server. The request is signed
+// by the instance actor, as servers that require authorized fetch only answer signed requests; it is only sent
+// unsigned if the instance actor was not created yet.
+func (f *FedProto) Dereference(ctx context.Context, iri *url.URL) (vocab.Type, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, iri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", AcceptHeader)
+
+	err = f.signRequest(ctx, req, InstanceActorId(f.Config.Url), nil)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err = f.fetch(req, &m); err != nil {
+		return nil, err
+	}
+
+	return streams.ToType(ctx, m)
+}
+
+// Finger resolves the handle name@host through the WebFinger endpoint of host, returning the ActivityPub ID the
+// handle links to.
+func (f *FedProto) Finger(ctx context.Context, name, host string) (*url.URL, error) {
+	u := url.URL{
+		Scheme:   f.scheme(),
+		Host:     host,
+		Path:     WebfingerPath,
+		RawQuery: url.Values{"resource": {"acct:" + name + "@" + host}}.Encode(),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", JRDContentType)
+
+	var jrd JRD
+	if err = f.fetch(req, &jrd); err != nil {
+		return nil, err
+	}
+
+	for _, link := range jrd.Links {
+		if link.Rel == RelSelf && link.Href != "" {
+			return url.Parse(link.Href)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s has no ActivityPub ID", ErrFetch, name+"@"+host)
+}
+
+// fetch performs req and decodes the JSON body of the response into v. Objects that are missing from the remote
+// server are reported as db.ErrNotFound, as well as ErrFetch. Nothing is fetched from suspended servers.
+func (f *FedProto) fetch(req *http.Request, v any) error {
+	if err := f.checkHost(req.Context(), req.URL.Host); err != nil {
+		return fmt.Errorf("%w: %w", ErrFetch, err)
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrFetch, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return fmt.Errorf("%w: %s returned %s: %w", ErrFetch, req.URL, resp.Status, db.ErrNotFound)
+	default:
+		return fmt.Errorf("%w: %s returned %s", ErrFetch, req.URL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxBodySize))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrFetch, err)
+	}
+
+	if err = json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: %s", ErrFetch, err)
+	}
+	return nil
+}
+
+// scheme returns the scheme used to reach other servers: plain HTTP is only used when this server is not served
+// over HTTPS either, such as during development.
+func (f *FedProto) scheme() string {
+	if f.Config.Https {
+		return "https"
+	}
+	return "http"
+}
+
+// FetchArticle dereferences a foreign article and stores a copy of it, updating our copy if we already had one.
+func (f *FedProto) FetchArticle(ctx context.Context, iri *url.URL) (article domain.ArticleFed, err error) {
+	t, err := f.Dereference(ctx, iri)
+	if err != nil {
+		return
+	}
+
+	article, err = conversions.ObjectToArticle(t)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrFetch, err)
+		return
+	}
+
+	// An article can only be served by its own server.
+	if article.ApID.Host != iri.Host || article.ApID.Host == f.Config.Domain {
+		err = fmt.Errorf("%w: article %s served by %s", ErrFetch, article.ApID, iri.Host)
+		return
+	}
+
+	if _, err = f.DB.UpsertForeignArticle(ctx, article); err != nil {
+		return
+	}
+
+	article.LastFetched = time.Now()
+	return
+}
+
+// FetchActor dereferences a foreign actor and stores it, updating our copy if we already knew it.
+func (f *FedProto) FetchActor(ctx context.Context, iri *url.URL) (user domain.UserFed, err error) {
+	t, err := f.Dereference(ctx, iri)
+	if err != nil {
+		return
+	}
+
+	user, err = conversions.ActorToUser(t)
+	if err != nil {
+		return
+	}
+
+	// An actor can only be served by its own server.
+	if user.ApId.Host != iri.Host {
+		err = fmt.Errorf("%w: actor %s served by %s", ErrFetch, user.ApId, iri.Host)
+		return
+	}
+
+	if _, err = f.DB.UpsertForeignUser(ctx, user); err != nil {
+		return
+	}
+
+	if isInstanceActor(user) {
+		err = f.DB.SetInstanceKey(ctx, user.ApId.Host, user.PublicKey, user.Inbox)
+	}
+	return
+}
+
+// FetchRevisions dereferences the outbox of a foreign actor and returns the revisions listed on its first page,
+// skipping the activities that do not carry a patch, such as the posts of microblogging software. The revisions
+// are not stored.
+func (f *FedProto) FetchRevisions(ctx context.Context, outbox *url.URL) ([]domain.RevisionFed, error) {
+	t, err := f.Dereference(ctx, outbox)
+	if err != nil {
+		return nil, err
+	}
+
+	// The items are usually not embedded in the collection, but in its first page.
+	if c, ok := t.(vocab.ActivityStreamsOrderedCollection); ok && c.GetActivityStreamsOrderedItems() == nil {
+		first := c.GetActivityStreamsFirst()
+		switch {
+		case first == nil:
+			return nil, nil
+		case first.IsIRI():
+			if first.GetIRI().Host != outbox.Host {
+				return nil, fmt.Errorf("%w: page %s of outbox %s", ErrFetch, first.GetIRI(), outbox)
+			}
+			if t, err = f.Dereference(ctx, first.GetIRI()); err != nil {
+				return nil, err
+			}
+		default:
+			t = first.GetType()
+		}
+	}
+
+	var items vocab.ActivityStreamsOrderedItemsProperty
+	switch c := t.(type) {
+	case vocab.ActivityStreamsOrderedCollection:
+		items = c.GetActivityStreamsOrderedItems()
+	case vocab.ActivityStreamsOrderedCollectionPage:
+		items = c.GetActivityStreamsOrderedItems()
+	default:
+		return nil, fmt.Errorf("%w: outbox %s is a %s", ErrFetch, outbox, t.GetTypeName())
+	}
+	if items == nil {
+		return nil, nil
+	}
+
+	revisions := make([]domain.RevisionFed, 0, items.Len())
+	for it := items.Begin(); it != items.End(); it = it.Next() {
+		if it.GetType() == nil {
+			continue
+		}
+		r, err := conversions.ActivityToRevision(it.GetType())
+		// Activities forged on behalf of other servers are ignored.
+		if err != nil || r.ApID.Host != outbox.Host {
+			continue
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
+// isInstanceActor reports whether user is the automated actor that represents a whole server, which software
+// such as Mastodon uses to sign requests made on behalf of the instance.
+func isInstanceActor(user domain.UserFed) bool {
+	if !user.Bot {
+		return false
+	}
+
+	switch user.ApId.Path {
+	case "", "/", "/actor":
+		return true
+	}
+	return user.Username == user.ApId.Host
+}
+
+// publicKey returns the public key with the given ID and the ID of the actor that owns it. Keys are read from the
+// database unless refresh is true or the owner is unknown, in which case the owner is fetched from its server.
+func (f *FedProto) publicKey(ctx context.Context, keyId *url.URL, refresh bool) (key crypto.PublicKey, owner *url.URL, err error) {
+	o := *keyId
+	o.Fragment = ""
+	owner = &o
+
+	var pemKey string
+	if !refresh {
+		pemKey, err = f.DB.GetPublicKey(ctx, owner)
+	}
+
+	if refresh || errors.Is(err, db.ErrNotFound) {
+		if owner.Host == f.Config.Domain {
+			// Our own actors are never fetched.
+			return nil, nil, fmt.Errorf("%w: unknown local key %s", ErrBadSignature, keyId)
+		}
+
+		var user domain.UserFed
+		user, err = f.FetchActor(ctx, owner)
+		if err != nil {
+			log.Debug().Err(err).Str("key", keyId.String()).Msg("failed to fetch key owner")
+			return nil, nil, fmt.Errorf("%w: %s", ErrBadSignature, err)
+		}
+		pemKey, owner = user.PublicKey, user.ApId
+	}
+
+	if err != nil {
+		return
+	}
+
+	key, err = ParsePublicKey(pemKey)
+	return
+}
@@ -0,0 +1,137 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// commented stores a Note in reply to a local article or to one of its comments, which joins the discussion of the
+// article. Notes replying to anything else are ignored.
+func (f *FedProto) commented(ctx context.Context, activity Activity, note vocab.Type) error {
+	comment, err := conversions.NoteToComment(note)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidActivity, err)
+	}
+
+	if comment.Author.String() != ActorId(activity).String() {
+		return fmt.Errorf("%w: actors can only create their own notes", ErrInvalidActivity)
+	}
+	if comment.ApID.Host != comment.Author.Host {
+		return fmt.Errorf("%w: %s does not belong to the server of its author", ErrInvalidActivity, comment.ApID)
+	}
+
+	article, err := f.DB.GetArticleFed(ctx, comment.InReplyTo)
+	switch {
+	case err == nil:
+		comment.Article, comment.InReplyTo = article.ApID, nil
+	case errors.Is(err, db.ErrNotFound):
+		parent, err := f.DB.GetCommentFed(ctx, comment.InReplyTo)
+		if errors.Is(err, db.ErrNotFound) {
+			log.Debug().Str("note", comment.ApID.String()).Msg("ignoring note that is not part of a discussion")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		comment.Article = parent.Article
+		article.Local = parent.Article.Host == f.Config.Domain
+	default:
+		return err
+	}
+	if !article.Local {
+		return nil
+	}
+
+	if _, err := f.actor(ctx, comment.Author); err != nil {
+		return err
+	}
+
+	// Comments from silenced servers wait for a moderator to show them.
+	policy, err := f.Policy(ctx, comment.Author.Host)
+	if err != nil {
+		return err
+	}
+	comment.Hidden = policy == domain.PolicySilence
+	return f.DB.UpsertForeignComment(ctx, comment)
+}
+
+// PublishComment delivers a local comment, wrapped in a Create, to the followers of its author and of its article,
+// and to the author of the comment it replies to.
+func (f *FedProto) PublishComment(ctx context.Context, id *url.URL) error {
+	comment, err := f.DB.GetCommentFed(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	author, err := f.DB.GetUserFed(ctx, comment.Author)
+	if err != nil {
+		return err
+	}
+
+	inboxes, err := f.DB.FollowerInboxes(ctx, comment.Author, comment.Article)
+	if err != nil {
+		return err
+	}
+
+	if comment.InReplyTo != nil {
+		parent, err := f.DB.GetCommentFed(ctx, comment.InReplyTo)
+		if err != nil {
+			return err
+		}
+		if !parent.Local {
+			user, err := f.DB.GetForeignUserFed(ctx, parent.Author)
+			if err != nil {
+				return err
+			}
+			inboxes = appendInbox(inboxes, user.Inbox)
+		}
+	}
+
+	create := streams.NewActivityStreamsCreate()
+
+	activityId := streams.NewJSONLDIdProperty()
+	activityId.SetIRI(comment.ApID.JoinPath("activity"))
+	create.SetJSONLDId(activityId)
+
+	actor := streams.NewActivityStreamsActorProperty()
+	actor.AppendIRI(comment.Author)
+	create.SetActivityStreamsActor(actor)
+
+	to := streams.NewActivityStreamsToProperty()
+	to.AppendIRI(conversions.PublicCollection)
+	create.SetActivityStreamsTo(to)
+
+	if author.Followers != nil {
+		cc := streams.NewActivityStreamsCcProperty()
+		cc.AppendIRI(author.Followers)
+		create.SetActivityStreamsCc(cc)
+	}
+
+	object := streams.NewActivityStreamsObjectProperty()
+	object.AppendActivityStreamsNote(conversions.CommentToNote(comment))
+	create.SetActivityStreamsObject(object)
+
+	return f.Deliver(ctx, comment.Author, create, inboxes)
+}
+
+// appendInbox adds inbox to inboxes, unless it is nil or already there.
+func appendInbox(inboxes []*url.URL, inbox *url.URL) []*url.URL {
+	if inbox == nil {
+		return inboxes
+	}
+	for _, i := range inboxes {
+		if i.String() == inbox.String() {
+			return inboxes
+		}
+	}
+	return append(inboxes, inbox)
+}
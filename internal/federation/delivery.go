@@ -0,0 +1,276 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+const (
+	// DeliveryWorkers is the number of deliveries attempted at the same time.
+	DeliveryWorkers = 8
+	// MaxDeliveriesPerHost limits how many of those may be directed to the same server.
+	MaxDeliveriesPerHost = 2
+	// MaxDeliveryAttempts is the number of failed attempts after which a delivery is given up on.
+	MaxDeliveryAttempts = 10
+	// DeliveryBackoff is the delay before the first retry; it doubles after each failure, up to MaxDeliveryBackoff.
+	DeliveryBackoff    = 30 * time.Second
+	MaxDeliveryBackoff = 12 * time.Hour
+	// DeliveryPollInterval is how often the queue is checked for due deliveries when idle.
+	DeliveryPollInterval = 10 * time.Second
+	// deliveryLease is how long a claimed delivery is hidden from the queue while being attempted.
+	deliveryLease = 2 * RequestTimeout
+	// busyHostDelay postpones, without counting an attempt, deliveries to a server that is already busy.
+	busyHostDelay = 5 * time.Second
+)
+
+// deliveryQueue holds the state of the delivery workers.
+type deliveryQueue struct {
+	// wake is signaled when new deliveries are enqueued, so they are attempted without waiting for the next poll.
+	wake  chan struct{}
+	mu    sync.Mutex
+	hosts map[string]int
+}
+
+// Deliver schedules activity, sent on behalf of the local actor, to be posted to each of the inboxes. The
+// deliveries are persisted, so they survive restarts, and are attempted by the workers started by RunDelivery.
+// Recipients on the same server are reached through its shared inbox when they have one, and inboxes on
+// suspended servers are skipped.
+func (f *FedProto) Deliver(ctx context.Context, actor *url.URL, activity vocab.Type, inboxes []*url.URL) error {
+	inboxes, err := f.collapseInboxes(ctx, inboxes)
+	if err != nil {
+		return err
+	}
+
+	inboxes = slices.DeleteFunc(inboxes, func(inbox *url.URL) bool {
+		return errors.Is(f.checkHost(ctx, inbox.Host), ErrSuspended)
+	})
+	if len(inboxes) == 0 {
+		return nil
+	}
+
+	m, err := streams.Serialize(activity)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if err = f.DB.EnqueueDeliveries(ctx, actor, body, inboxes); err != nil {
+		return err
+	}
+
+	select {
+	case f.queue.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// collapseInboxes returns inboxes without duplicates, with the inboxes of the actors of servers that are sent
+// the activity more than once replaced by the shared inboxes those actors advertised, so that such a server
+// receives the activity a single time.
+func (f *FedProto) collapseInboxes(ctx context.Context, inboxes []*url.URL) ([]*url.URL, error) {
+	perHost := make(map[string]int)
+	for _, inbox := range inboxes {
+		perHost[inbox.Host]++
+	}
+
+	var crowded []*url.URL
+	for _, inbox := range inboxes {
+		if perHost[inbox.Host] > 1 {
+			crowded = append(crowded, inbox)
+		}
+	}
+
+	var shared map[string]*url.URL
+	if len(crowded) > 0 {
+		var err error
+		if shared, err = f.DB.SharedInboxes(ctx, crowded); err != nil {
+			return nil, err
+		}
+	}
+
+	collapsed := make([]*url.URL, 0, len(inboxes))
+	for _, inbox := range inboxes {
+		if s, ok := shared[inbox.String()]; ok {
+			inbox = s
+		}
+		collapsed = appendInbox(collapsed, inbox)
+	}
+	return collapsed, nil
+}
+
+// RunDelivery attempts the due deliveries until ctx is canceled. Each of the DeliveryWorkers attempts a
+// delivery at a time, and a new one is claimed as soon as a worker is idle, so that a slow server does not
+// hold back the deliveries to the others.
+func (f *FedProto) RunDelivery(ctx context.Context) {
+	ticker := time.NewTicker(DeliveryPollInterval)
+	defer ticker.Stop()
+
+	// Deliveries are only claimed for idle workers, so neither channel is ever full.
+	jobs := make(chan domain.Delivery, DeliveryWorkers)
+	done := make(chan struct{}, DeliveryWorkers)
+	var wg sync.WaitGroup
+	for range DeliveryWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range jobs {
+				f.attemptDelivery(ctx, d)
+				done <- struct{}{}
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	idle := DeliveryWorkers
+	for {
+		if idle > 0 {
+			deliveries, err := f.DB.ClaimDeliveries(ctx, idle, deliveryLease)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to claim deliveries")
+			}
+			for _, d := range deliveries {
+				jobs <- d
+			}
+			idle -= len(deliveries)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-f.queue.wake:
+		case <-done:
+			idle++
+		}
+	}
+}
+
+func (f *FedProto) attemptDelivery(ctx context.Context, d domain.Delivery) {
+	logger := log.With().
+		Int64("id", d.ID).
+		Str("actor", d.Actor.String()).
+		Str("inbox", d.Inbox.String()).
+		Logger()
+
+	// The server may have been suspended after the delivery was scheduled.
+	if err := f.checkHost(ctx, d.Inbox.Host); errors.Is(err, ErrSuspended) {
+		logger.Info().Msg("dropping delivery to suspended instance")
+		if err = f.DB.KillDelivery(ctx, d.ID, d.Attempts, err.Error()); err != nil {
+			logger.Error().Err(err).Msg("failed to drop delivery")
+		}
+		return
+	}
+
+	if !f.acquireHost(d.Inbox.Host) {
+		err := f.DB.RescheduleDelivery(ctx, d.ID, d.Attempts, time.Now().Add(busyHostDelay), "")
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to postpone delivery")
+		}
+		return
+	}
+	defer f.releaseHost(d.Inbox.Host)
+
+	permanent, err := f.post(ctx, d)
+	if err == nil {
+		if err = f.DB.DeleteDelivery(ctx, d.ID); err != nil {
+			logger.Error().Err(err).Msg("failed to remove completed delivery")
+		}
+		return
+	}
+
+	attempts := d.Attempts + 1
+	if permanent || attempts >= MaxDeliveryAttempts {
+		logger.Warn().Err(err).Int("attempts", attempts).Msg("giving up on delivery")
+		err = f.DB.KillDelivery(ctx, d.ID, attempts, err.Error())
+	} else {
+		next := time.Now().Add(Backoff(attempts))
+		logger.Debug().Err(err).Int("attempts", attempts).Time("next", next).Msg("delivery failed")
+		err = f.DB.RescheduleDelivery(ctx, d.ID, attempts, next, err.Error())
+	}
+
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to record delivery failure")
+	}
+}
+
+// post signs and sends a delivery. permanent is true if retrying is pointless: the inbox is not a valid URL, or
+// the remote server refused the delivery with a client error other than 429 Too Many Requests. Failing to sign
+// the request, such as when the key of the actor cannot be loaded, is not permanent.
+func (f *FedProto) post(ctx context.Context, d domain.Delivery) (permanent bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Inbox.String(), bytes.NewReader(d.Activity))
+	if err != nil {
+		return true, err
+	}
+	req.Header.Set("Content-Type", ActivityJSON)
+
+	if err = f.signRequest(ctx, req, d.Actor, d.Activity); err != nil {
+		return false, err
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, MaxBodySize))
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code < 300:
+		return false, nil
+	case code >= 400 && code < 500 && code != http.StatusTooManyRequests:
+		return true, fmt.Errorf("inbox returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("inbox returned %s", resp.Status)
+	}
+}
+
+// Backoff returns how long to wait before retrying a delivery that has failed the given number of times.
+func Backoff(attempts int) time.Duration {
+	d := DeliveryBackoff
+	for i := 1; i < attempts && d < MaxDeliveryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, MaxDeliveryBackoff)
+}
+
+func (f *FedProto) acquireHost(host string) bool {
+	f.queue.mu.Lock()
+	defer f.queue.mu.Unlock()
+
+	if f.queue.hosts[host] >= MaxDeliveriesPerHost {
+		return false
+	}
+	f.queue.hosts[host]++
+	return true
+}
+
+func (f *FedProto) releaseHost(host string) {
+	f.queue.mu.Lock()
+	defer f.queue.mu.Unlock()
+
+	if f.queue.hosts[host]--; f.queue.hosts[host] <= 0 {
+		delete(f.queue.hosts, host)
+	}
+}
@@ -0,0 +1,70 @@
+package fedb
+
+import (
+	"context"
+	"net/url"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+)
+
+// GetOutbox returns the first page of the outbox of a local user, which lists the revisions they published.
+func (fd *FedDB) GetOutbox(ctx context.Context, outboxIRI *url.URL) (outbox vocab.ActivityStreamsOrderedCollectionPage, err error) {
+	actor, err := fd.DB.ActorIdByOutbox(ctx, outboxIRI)
+	if err != nil {
+		return
+	}
+
+	total, err := fd.DB.CountUserRevisions(ctx, actor)
+	if err != nil {
+		return
+	}
+
+	revisions, err := fd.DB.ListUserRevisions(ctx, actor, conversions.CollectionPageSize, 0)
+	if err != nil {
+		return
+	}
+
+	outbox = conversions.OrderedCollectionPage(outboxIRI, 1, total, conversions.RevisionItems(revisions))
+	return
+}
+
+// SetOutbox does nothing: the outbox is derived from the revisions, which are stored when the articles are
+// edited.
+func (fd *FedDB) SetOutbox(ctx context.Context, outbox vocab.ActivityStreamsOrderedCollectionPage) error {
+	return nil
+}
+
+// Followers returns the accepted followers of an actor.
+func (fd *FedDB) Followers(ctx context.Context, actorIRI *url.URL) (followers vocab.ActivityStreamsCollection, err error) {
+	ids, err := fd.DB.ListFollowers(ctx, actorIRI, -1, 0)
+	if err != nil {
+		return
+	}
+	return collection(ids), nil
+}
+
+// Following returns the actors followed by a user.
+func (fd *FedDB) Following(ctx context.Context, actorIRI *url.URL) (following vocab.ActivityStreamsCollection, err error) {
+	ids, err := fd.DB.ListFollowing(ctx, actorIRI, -1, 0)
+	if err != nil {
+		return
+	}
+	return collection(ids), nil
+}
+
+func collection(ids []*url.URL) vocab.ActivityStreamsCollection {
+	c := streams.NewActivityStreamsCollection()
+
+	totalItems := streams.NewActivityStreamsTotalItemsProperty()
+	totalItems.Set(len(ids))
+	c.SetActivityStreamsTotalItems(totalItems)
+
+	items := streams.NewActivityStreamsItemsProperty()
+	for _, id := range ids {
+		items.AppendIRI(id)
+	}
+	c.SetActivityStreamsItems(items)
+	return c
+}
@@ -2,24 +2,140 @@ package fedb
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/url"
 
 	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
 )
 
+var (
+	// ErrLocalObject is returned when trying to create, update or delete a local object, which may only be
+	// modified through the wiki itself.
+	ErrLocalObject     = errors.New("local objects cannot be modified through federation")
+	ErrUnsupportedType = errors.New("unsupported type")
+)
+
+// Get returns the user, article, revision, comment or file with the given ID, whether local or a copy of a foreign one.
 func (fd *FedDB) Get(ctx context.Context, id *url.URL) (value vocab.Type, err error) {
+	user, err := fd.DB.GetUserFed(ctx, id)
+	if errors.Is(err, db.ErrNotFound) {
+		user, err = fd.DB.GetForeignUserFed(ctx, id)
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		if err != nil {
+			return nil, err
+		}
+		return conversions.UserToActor(user), nil
+	}
+
+	if article, err := fd.DB.GetArticleFed(ctx, id); !errors.Is(err, db.ErrNotFound) {
+		if err != nil {
+			return nil, err
+		}
+		if article.Local {
+			article.License = fd.Config.License
+		}
+		return conversions.ArticleToObject(article), nil
+	}
+
+	if revision, err := fd.DB.GetRevisionFed(ctx, id); !errors.Is(err, db.ErrNotFound) {
+		if err != nil {
+			return nil, err
+		}
+		return conversions.RevisionToActivity(revision), nil
+	}
 
-	return
+	if comment, err := fd.DB.GetCommentFed(ctx, id); !errors.Is(err, db.ErrNotFound) {
+		if err != nil {
+			return nil, err
+		}
+		return conversions.CommentToNote(comment), nil
+	}
+
+	file, err := fd.DB.GetFileFed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return conversions.FileToDocument(file), nil
 }
 
+// Create stores a copy of a foreign object. Actors, Articles, Documents and Images are supported, as well as
+// Creates and Updates carrying the patch of a revision of a foreign article.
 func (fd *FedDB) Create(ctx context.Context, asType vocab.Type) error {
-	return nil
+	return fd.store(ctx, asType)
 }
 
+// Update replaces the stored copy of a foreign object, creating it if it does not exist.
 func (fd *FedDB) Update(ctx context.Context, asType vocab.Type) error {
-	return nil
+	return fd.store(ctx, asType)
 }
 
+// Delete removes the stored copy of a foreign object. Actors are deleted the way the deletion of an actor by their
+// server is handled, leaving a tombstone.
 func (fd *FedDB) Delete(ctx context.Context, id *url.URL) error {
-	return nil
+	if fd.isLocal(id) {
+		return ErrLocalObject
+	}
+
+	for _, del := range []func(context.Context, *url.URL) error{
+		fd.DB.DeleteForeignActor,
+		fd.DB.DeleteForeignArticle,
+		fd.DB.DeleteForeignRevision,
+		fd.DB.DeleteForeignFile,
+	} {
+		if err := del(ctx, id); !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+	}
+	return db.ErrNotFound
+}
+
+func (fd *FedDB) store(ctx context.Context, asType vocab.Type) error {
+	id := conversions.GetId(asType)
+	if id == nil {
+		return fmt.Errorf("%w: id", conversions.ErrMissingProperty)
+	}
+	if fd.isLocal(id) {
+		return ErrLocalObject
+	}
+
+	switch asType.GetTypeName() {
+	case "Article":
+		article, err := conversions.ObjectToArticle(asType)
+		if err != nil {
+			return err
+		}
+		_, err = fd.DB.UpsertForeignArticle(ctx, article)
+		return err
+	case "Document", "Image":
+		file, err := conversions.DocumentToFile(asType)
+		if err != nil {
+			return err
+		}
+		return fd.DB.UpsertForeignFile(ctx, file)
+	case "Create", "Update":
+		revision, err := conversions.ActivityToRevision(asType)
+		if err != nil {
+			return err
+		}
+		return fd.DB.InsertForeignRevision(ctx, revision)
+	}
+
+	if _, ok := asType.(conversions.Actor); ok {
+		user, err := conversions.ActorToUser(asType)
+		if err != nil {
+			return err
+		}
+		_, err = fd.DB.UpsertForeignUser(ctx, user)
+		return err
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnsupportedType, asType.GetTypeName())
+}
+
+func (fd *FedDB) isLocal(id *url.URL) bool {
+	return id.Host == fd.Config.Domain
 }
@@ -1,15 +1,33 @@
 package fedb
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
+	"strconv"
 	"testing"
+	"time"
 
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
 	"github.com/golang-migrate/migrate"
 	"github.com/golang-migrate/migrate/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/source/file"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/sidereusnuntius/gowiki/internal/config"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	dbimpl "github.com/sidereusnuntius/gowiki/internal/db/impl"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+var (
+	fd  *FedDB
+	ctx = context.Background()
 )
 
 func TestMain(m *testing.M) {
@@ -41,6 +59,10 @@ func TestMain(m *testing.M) {
 		return
 	}
 
+	u, _ := url.Parse("https://test.wiki")
+	conf := config.Configuration{Domain: "test.wiki", Url: u}
+	fd = &FedDB{DB: dbimpl.New(conf, d), Config: conf}
+
 	m.Run()
 
 	// r := d.QueryRow("SELECT COUNT(id) FROM users")
@@ -58,3 +80,353 @@ func TestMain(m *testing.M) {
 		return
 	}
 }
+
+func mustParse(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// roundTrip creates value, reads it back and checks that it converts into the same domain object.
+func roundTrip[T any](t *testing.T, value vocab.Type, convert func(vocab.Type) (T, error), equal func(a, b T) bool) {
+	t.Helper()
+	want, err := convert(value)
+	if err != nil {
+		t.Fatalf("invalid test value: %s", err)
+	}
+
+	if err = fd.Create(ctx, value); err != nil {
+		t.Fatalf("failed to create %s: %s", value.GetTypeName(), err)
+	}
+
+	id := conversions.GetId(value)
+	if exists, err := fd.Exists(ctx, id); err != nil || !exists {
+		t.Fatalf("expected %s to exist, got %v (%v)", id, exists, err)
+	}
+
+	stored, err := fd.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to get %s: %s", id, err)
+	}
+
+	if stored.GetTypeName() != value.GetTypeName() {
+		t.Fatalf("expected type %s, got %s", value.GetTypeName(), stored.GetTypeName())
+	}
+
+	got, err := convert(stored)
+	if err != nil {
+		t.Fatalf("stored value is invalid: %s", err)
+	}
+
+	if !equal(want, got) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func foreignActor(id *url.URL, name string, bot bool) vocab.Type {
+	return conversions.UserToActor(domain.UserFed{
+		UserCore:  domain.UserCore{Username: path.Base(id.Path), Name: name},
+		ApId:      id,
+		Inbox:     id.JoinPath("inbox"),
+		Outbox:    id.JoinPath("outbox"),
+		Followers: id.JoinPath("followers"),
+		PublicKey: "-----BEGIN PUBLIC KEY-----",
+		Bot:       bot,
+	})
+}
+
+func equalUsers(a, b domain.UserFed) bool {
+	return a.ApId.String() == b.ApId.String() &&
+		a.Username == b.Username &&
+		a.Name == b.Name &&
+		a.Domain == b.Domain &&
+		a.Inbox.String() == b.Inbox.String() &&
+		a.Outbox.String() == b.Outbox.String() &&
+		a.Followers.String() == b.Followers.String() &&
+		a.PublicKey == b.PublicKey &&
+		a.Bot == b.Bot
+}
+
+func TestUsers(t *testing.T) {
+	cases := []struct {
+		Casename string
+		Actor    vocab.Type
+	}{
+		{"person", foreignActor(mustParse("https://other.wiki/u/bob"), "Bob", false)},
+		{"service", foreignActor(mustParse("https://other.wiki/u/bot"), "Bot", true)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Casename, func(t *testing.T) {
+			roundTrip(t, c.Actor, conversions.ActorToUser, equalUsers)
+		})
+	}
+
+	t.Run("update", func(t *testing.T) {
+		id := mustParse("https://other.wiki/u/bob")
+		if err := fd.Update(ctx, foreignActor(id, "Robert", false)); err != nil {
+			t.Fatalf("failed to update actor: %s", err)
+		}
+
+		stored, err := fd.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("failed to get actor: %s", err)
+		}
+		if user, _ := conversions.ActorToUser(stored); user.Name != "Robert" {
+			t.Errorf("expected name to be updated, got %q", user.Name)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		id := mustParse("https://other.wiki/u/bot")
+		if err := fd.Delete(ctx, id); err != nil {
+			t.Fatalf("failed to delete actor: %s", err)
+		}
+		if _, err := fd.Get(ctx, id); !errors.Is(err, db.ErrNotFound) {
+			t.Errorf("expected %s, got %v", db.ErrNotFound, err)
+		}
+		if tombstone, err := fd.DB.GetTombstone(ctx, id); err != nil || tombstone.FormerType != "Person" {
+			t.Errorf("expected a Person tombstone for the deleted actor, got %+v (%v)", tombstone, err)
+		}
+	})
+
+	t.Run("local", func(t *testing.T) {
+		id := fd.Config.Url.JoinPath("u", "alice")
+		err := fd.DB.InsertUser(ctx, domain.UserFedInternal{
+			UserFed: domain.UserFed{
+				UserCore:  domain.UserCore{Username: "alice", Name: "Alice"},
+				ApId:      id,
+				Inbox:     id.JoinPath("inbox"),
+				Outbox:    id.JoinPath("outbox"),
+				Followers: id.JoinPath("followers"),
+			},
+		}, domain.Account{Email: "alice@test.wiki"}, "", "")
+		if err != nil {
+			t.Fatalf("failed to create local user: %s", err)
+		}
+
+		stored, err := fd.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("failed to get local user: %s", err)
+		}
+		if stored.GetTypeName() != "Person" {
+			t.Errorf("expected Person, got %s", stored.GetTypeName())
+		}
+
+		if err = fd.Update(ctx, stored); !errors.Is(err, ErrLocalObject) {
+			t.Errorf("expected %s, got %v", ErrLocalObject, err)
+		}
+		if err = fd.Delete(ctx, id); !errors.Is(err, ErrLocalObject) {
+			t.Errorf("expected %s, got %v", ErrLocalObject, err)
+		}
+	})
+}
+
+func foreignArticle(id *url.URL, content string) domain.ArticleFed {
+	return domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{
+			Title:     "Galileo",
+			Summary:   "An astronomer",
+			Content:   content,
+			MediaType: "text/markdown",
+			Language:  "it",
+		},
+		ApID:        id,
+		Url:         id,
+		Created:     time.Unix(1610000000, 0),
+		LastUpdated: time.Unix(1620000000, 0),
+	}
+}
+
+func equalArticles(a, b domain.ArticleFed) bool {
+	return a.ApID.String() == b.ApID.String() &&
+		a.Url.String() == b.Url.String() &&
+		a.Host == b.Host &&
+		a.ArticleCore == b.ArticleCore
+}
+
+func TestArticles(t *testing.T) {
+	id := mustParse("https://other.wiki/a/Galileo")
+	roundTrip(t, conversions.ArticleToObject(foreignArticle(id, "Sidereus nuncius")), conversions.ObjectToArticle, equalArticles)
+
+	if err := fd.Update(ctx, conversions.ArticleToObject(foreignArticle(id, "Il Saggiatore"))); err != nil {
+		t.Fatalf("failed to update article: %s", err)
+	}
+
+	stored, err := fd.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to get article: %s", err)
+	}
+	if article, _ := conversions.ObjectToArticle(stored); article.Content != "Il Saggiatore" {
+		t.Errorf("expected content to be updated, got %q", article.Content)
+	}
+
+	// The followers of the article are forgotten along with it.
+	follower := mustParse("https://other.wiki/u/vincenzo")
+	if err = fd.Create(ctx, foreignActor(follower, "Vincenzo", false)); err != nil {
+		t.Fatalf("failed to create follower: %s", err)
+	}
+	if err = fd.DB.AddFollow(ctx, domain.Follow{Follower: follower, Followee: id, Accepted: true}); err != nil {
+		t.Fatalf("failed to add follow: %s", err)
+	}
+
+	if err = fd.Delete(ctx, id); err != nil {
+		t.Fatalf("failed to delete article: %s", err)
+	}
+	if exists, _ := fd.Exists(ctx, id); exists {
+		t.Errorf("expected article to be deleted")
+	}
+	if following, err := fd.Following(ctx, follower); err != nil || following.GetActivityStreamsItems().Len() != 0 {
+		t.Errorf("expected the follow of the deleted article to be removed (%v)", err)
+	}
+
+	local := conversions.ArticleToObject(foreignArticle(fd.Config.Url.JoinPath("a", "Galileo"), ""))
+	if err = fd.Create(ctx, local); !errors.Is(err, ErrLocalObject) {
+		t.Errorf("expected %s, got %v", ErrLocalObject, err)
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	article := mustParse("https://third.wiki/a/Kepler")
+	author := mustParse("https://third.wiki/u/johannes")
+
+	if err := fd.Create(ctx, conversions.ArticleToObject(foreignArticle(article, "Astronomia nova"))); err != nil {
+		t.Fatalf("failed to create article: %s", err)
+	}
+	if err := fd.Create(ctx, foreignActor(author, "Johannes", false)); err != nil {
+		t.Fatalf("failed to create author: %s", err)
+	}
+
+	equal := func(a, b domain.RevisionFed) bool {
+		return a.ApID.String() == b.ApID.String() &&
+			a.Article.String() == b.Article.String() &&
+			a.Author.String() == b.Author.String() &&
+			a.Summary == b.Summary &&
+			a.Diff == b.Diff &&
+			a.Initial == b.Initial &&
+			a.Created.Equal(b.Created)
+	}
+
+	for i, initial := range []bool{true, false} {
+		revision := conversions.RevisionToActivity(domain.RevisionFed{
+			ApID:    article.JoinPath("history", strconv.Itoa(i+1)),
+			Article: article,
+			Author:  author,
+			Summary: "Add the third law",
+			Diff:    "@@ -1,3 +1,3 @@\n-a\n+b\n",
+			Initial: initial,
+			Created: time.Unix(1630000000, 0),
+		})
+		roundTrip(t, revision, conversions.ActivityToRevision, equal)
+	}
+
+	object, err := fd.Get(ctx, article)
+	if err != nil {
+		t.Fatalf("failed to get article: %s", err)
+	}
+	stored, err := conversions.ObjectToArticle(object)
+	if err != nil {
+		t.Fatalf("failed to convert article: %s", err)
+	}
+	if len(stored.AttributedTo) != 1 || stored.AttributedTo[0].String() != author.String() {
+		t.Errorf("expected the article to be attributed to %s, got %v", author, stored.AttributedTo)
+	}
+
+	if err := fd.Delete(ctx, article.JoinPath("history", "2")); err != nil {
+		t.Fatalf("failed to delete revision: %s", err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	uploader := mustParse("https://other.wiki/u/bob")
+	cases := []struct {
+		Casename string
+		File     domain.File
+	}{
+		{"document", domain.File{
+			FileMetadata: domain.FileMetadata{
+				Name:     "Starry Messenger",
+				Type:     domain.DocumentType,
+				MimeType: "application/pdf",
+			},
+			ApId:     mustParse("https://other.wiki/f/1"),
+			Url:      mustParse("https://other.wiki/files/sidereus.pdf"),
+			Uploader: uploader,
+		}},
+		{"image", domain.File{
+			FileMetadata: domain.FileMetadata{
+				Name:     "Moon",
+				Type:     domain.ImageType,
+				MimeType: "image/png",
+			},
+			ApId:     mustParse("https://other.wiki/f/2"),
+			Url:      mustParse("https://other.wiki/files/moon.png"),
+			Uploader: uploader,
+		}},
+	}
+
+	equal := func(a, b domain.File) bool {
+		return a.ApId.String() == b.ApId.String() &&
+			a.Url.String() == b.Url.String() &&
+			a.Name == b.Name &&
+			a.Type == b.Type &&
+			a.MimeType == b.MimeType
+	}
+
+	for _, c := range cases {
+		t.Run(c.Casename, func(t *testing.T) {
+			roundTrip(t, conversions.FileToDocument(c.File), conversions.DocumentToFile, equal)
+
+			if err := fd.Delete(ctx, c.File.ApId); err != nil {
+				t.Fatalf("failed to delete file: %s", err)
+			}
+			if _, err := fd.Get(ctx, c.File.ApId); !errors.Is(err, db.ErrNotFound) {
+				t.Errorf("expected %s, got %v", db.ErrNotFound, err)
+			}
+		})
+	}
+}
+
+func TestUnsupportedType(t *testing.T) {
+	note := streams.NewActivityStreamsNote()
+	id := streams.NewJSONLDIdProperty()
+	id.SetIRI(mustParse("https://other.wiki/notes/1"))
+	note.SetJSONLDId(id)
+
+	if err := fd.Create(ctx, note); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected %s, got %v", ErrUnsupportedType, err)
+	}
+}
+
+func TestFollowers(t *testing.T) {
+	followee := mustParse("https://fourth.wiki/u/tycho")
+	follower := mustParse("https://fourth.wiki/u/sophie")
+	for _, id := range []*url.URL{followee, follower} {
+		if err := fd.Create(ctx, foreignActor(id, path.Base(id.Path), false)); err != nil {
+			t.Fatalf("failed to create actor: %s", err)
+		}
+	}
+
+	err := fd.DB.AddFollow(ctx, domain.Follow{Follower: follower, Followee: followee, Accepted: true})
+	if err != nil {
+		t.Fatalf("failed to add follow: %s", err)
+	}
+
+	followers, err := fd.Followers(ctx, followee)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if items := followers.GetActivityStreamsItems(); items.Len() != 1 || items.At(0).GetIRI().String() != follower.String() {
+		t.Errorf("expected %s to be the only follower", follower)
+	}
+
+	following, err := fd.Following(ctx, follower)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if items := following.GetActivityStreamsItems(); items.Len() != 1 || items.At(0).GetIRI().String() != followee.String() {
+		t.Errorf("expected %s to be the only followee", followee)
+	}
+}
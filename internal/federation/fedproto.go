@@ -2,12 +2,117 @@ package federation
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"net/url"
+
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/config"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/state"
 )
 
+// Activity is the set of properties shared by the activities we handle.
+type Activity interface {
+	vocab.Type
+	GetActivityStreamsActor() vocab.ActivityStreamsActorProperty
+	GetActivityStreamsObject() vocab.ActivityStreamsObjectProperty
+}
+
+// ActivityHandler processes an activity received, and already authenticated, by the local actor whose inbox
+// is given, or by the shared inbox.
+type ActivityHandler func(ctx context.Context, inbox *url.URL, activity Activity) error
+
+// FedProto implements the federating side of the ActivityPub protocol: it authenticates the requests made to our
+// actors' inboxes and dispatches the activities they carry to their handlers, and delivers the activities of our
+// actors to other servers.
 type FedProto struct {
+	DB     db.DB
+	Config config.Configuration
+	Client *http.Client
+	// handlers maps the name of an activity type to the function that processes it.
+	handlers map[string]ActivityHandler
+	// undoHandlers maps the name of an activity type to the function that reverts it.
+	undoHandlers map[string]ActivityHandler
+	queue        deliveryQueue
+}
+
+func New(state *state.State) *FedProto {
+	f := &FedProto{
+		DB:           state.DB,
+		Config:       state.Config,
+		Client:       newClient(state.Config),
+		undoHandlers: map[string]ActivityHandler{},
+		queue: deliveryQueue{
+			wake:  make(chan struct{}, 1),
+			hosts: map[string]int{},
+		},
+	}
+
+	f.handlers = map[string]ActivityHandler{
+		"Create":   f.create,
+		"Update":   f.update,
+		"Delete":   f.delete,
+		"Follow":   f.follow,
+		"Undo":     f.undo,
+		"Like":     f.like,
+		"Announce": f.announce,
+		"Accept":   f.accepted,
+		"Reject":   f.rejected,
+	}
+	f.HandleUndo("Follow", f.unfollow)
+	f.HandleUndo("Like", f.unlike)
+	f.HandleUndo("Announce", f.unannounce)
+	return f
+}
+
+// Handle registers h as the handler for the activities of the given type, replacing the previous one.
+func (f *FedProto) Handle(typeName string, h ActivityHandler) {
+	f.handlers[typeName] = h
+}
+
+// HandleUndo registers h as the handler for the Undo of activities of the given type.
+func (f *FedProto) HandleUndo(typeName string, h ActivityHandler) {
+	f.undoHandlers[typeName] = h
 }
 
+// AuthenticateGet verifies the HTTP signature of a GET request for one of our ActivityPub documents, if
+// Config.AuthorizedFetch requires it; otherwise every request is authenticated. Like AuthenticatePostInbox, the
+// returned context carries the ID of the signer of a signed request.
+func (f *FedProto) AuthenticateGet(c context.Context, w http.ResponseWriter, r *http.Request) (out context.Context, authenticated bool, err error) {
+	if !f.Config.AuthorizedFetch {
+		return c, true, nil
+	}
+
+	owner, err := f.verifyRequest(c, r, nil)
+	if err != nil {
+		log.Debug().Err(err).Str("path", r.URL.Path).Msg("rejected unsigned fetch")
+		return c, false, nil
+	}
+	return context.WithValue(c, actorKey{}, owner), true, nil
+}
+
+// AuthenticateGetInbox authenticates a GET request to the inbox of one of our actors, like AuthenticateGet.
 func (f *FedProto) AuthenticateGetInbox(c context.Context, w http.ResponseWriter, r *http.Request) (out context.Context, authenticated bool, err error) {
-	return
+	return f.AuthenticateGet(c, w, r)
+}
+
+// AuthenticateGetOutbox authenticates a GET request to the outbox of one of our actors, like AuthenticateGet.
+func (f *FedProto) AuthenticateGetOutbox(c context.Context, w http.ResponseWriter, r *http.Request) (out context.Context, authenticated bool, err error) {
+	return f.AuthenticateGet(c, w, r)
+}
+
+// InstanceActorId returns the ID of the Service actor that represents the wiki at base as a whole. Following it
+// subscribes to the edits of every local article.
+func InstanceActorId(base *url.URL) *url.URL {
+	return base.JoinPath("actor")
+}
+
+// newActivityId returns a new, random ID for an activity of a local actor.
+func newActivityId(actor *url.URL) *url.URL {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return actor.JoinPath("activities", hex.EncodeToString(b))
 }
@@ -0,0 +1,1788 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/go-fed/httpsig"
+	"github.com/golang-migrate/migrate"
+	"github.com/golang-migrate/migrate/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/source/file"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sidereusnuntius/gowiki/internal/config"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	dbimpl "github.com/sidereusnuntius/gowiki/internal/db/impl"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/state"
+)
+
+var (
+	fed      *FedProto
+	remote   *fakeServer
+	database *sql.DB
+	aliceKey *rsa.PrivateKey
+	// remoteKey is the key bob, the actor of the remote servers, signs his requests with.
+	remoteKey *rsa.PrivateKey
+	ctx       = context.Background()
+)
+
+// fakeServer is a remote ActivityPub server with a single actor, bob, who signs his requests with key, and a
+// single article, Dialogue, which bob created. Its inbox checks that requests are signed by alice, or with one of
+// the trusted keys, and answers them with inboxStatus.
+type fakeServer struct {
+	*httptest.Server
+	key         *rsa.PrivateKey
+	fetches     atomic.Int32
+	inboxStatus atomic.Int32
+	received    atomic.Int32
+	// signer is the ID of the key that signed the last GET request, if it was signed.
+	signer atomic.Value
+	// inboxSigner is the ID of the key that signed the last request accepted by the inbox.
+	inboxSigner atomic.Value
+	// trusted maps the IDs of the keys, other than the key of alice, accepted by the inbox to the keys.
+	trusted sync.Map
+	// content is the content of Dialogue.
+	content atomic.Value
+}
+
+func (s *fakeServer) actorId() *url.URL {
+	u, _ := url.Parse(s.URL + "/users/bob")
+	return u
+}
+
+func (s *fakeServer) articleId() *url.URL {
+	u, _ := url.Parse(s.URL + "/a/Dialogue")
+	return u
+}
+
+func newFakeServer(key *rsa.PrivateKey) (*fakeServer, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	pub := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	s := &fakeServer{key: key}
+	s.inboxStatus.Store(http.StatusAccepted)
+	s.content.Store("<p>Eppur si muove</p>")
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			var keyId string
+			if verifier, err := httpsig.NewVerifier(r); err == nil {
+				keyId = verifier.KeyId()
+			}
+			s.signer.Store(keyId)
+		}
+
+		if r.URL.Path == "/users/bob/inbox" {
+			verifier, err := httpsig.NewVerifier(r)
+			key := &aliceKey.PublicKey
+			if err == nil {
+				if trusted, ok := s.trusted.Load(verifier.KeyId()); ok {
+					key = trusted.(*rsa.PublicKey)
+				}
+			}
+			if err != nil || verifier.Verify(key, httpsig.RSA_SHA256) != nil {
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+			s.received.Add(1)
+			s.inboxSigner.Store(verifier.KeyId())
+			w.WriteHeader(int(s.inboxStatus.Load()))
+			return
+		}
+
+		if r.URL.Path == WebfingerPath {
+			if r.URL.Query().Get("resource") != "acct:Dialogue@"+r.Host {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", JRDContentType)
+			json.NewEncoder(w).Encode(JRD{
+				Subject: "acct:Dialogue@" + r.Host,
+				Links:   []Link{{Rel: RelSelf, Type: ActivityJSON, Href: s.articleId().String()}},
+			})
+			return
+		}
+
+		if r.URL.Path == "/users/bob/outbox" {
+			outbox := s.actorId().JoinPath("outbox")
+			var t vocab.Type = conversions.OrderedCollection(outbox, 1)
+			if r.URL.Query().Get("page") == "1" {
+				t = conversions.OrderedCollectionPage(outbox, 1, 1, conversions.RevisionItems([]domain.RevisionFed{{
+					ApID:    s.articleId().JoinPath("history", "1"),
+					Article: s.articleId(),
+					Author:  s.actorId(),
+					Summary: "First draft",
+					Diff:    "@@ -0,0 +1,20 @@\n+<p>Eppur si muove</p>\n",
+					Initial: true,
+				}}))
+			}
+			m, _ := streams.Serialize(t)
+			w.Header().Set("Content-Type", ActivityJSON)
+			json.NewEncoder(w).Encode(m)
+			return
+		}
+
+		if r.URL.Path == "/a/Dialogue" {
+			m, _ := streams.Serialize(conversions.ArticleToObject(domain.ArticleFed{
+				ArticleCore: domain.ArticleCore{
+					Title:     "Dialogue",
+					Content:   s.content.Load().(string),
+					MediaType: "text/html",
+					Language:  "it",
+				},
+				ApID:         s.articleId(),
+				AttributedTo: []*url.URL{s.actorId()},
+			}))
+			w.Header().Set("Content-Type", ActivityJSON)
+			json.NewEncoder(w).Encode(m)
+			return
+		}
+
+		if r.URL.Path != "/users/bob" {
+			http.NotFound(w, r)
+			return
+		}
+		s.fetches.Add(1)
+
+		id := s.actorId()
+		m, _ := streams.Serialize(conversions.UserToActor(domain.UserFed{
+			UserCore:  domain.UserCore{Username: "bob", Name: "Bob"},
+			ApId:      id,
+			Inbox:     id.JoinPath("inbox"),
+			Outbox:    id.JoinPath("outbox"),
+			Followers: id.JoinPath("followers"),
+			PublicKey: pub,
+		}))
+		w.Header().Set("Content-Type", ActivityJSON)
+		json.NewEncoder(w).Encode(m)
+	}))
+	return s, nil
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	if aliceKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate key: %s", err)
+		return
+	}
+	if remoteKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate key: %s", err)
+		return
+	}
+	m.Run()
+}
+
+// setup gives the test its own database, with the local user alice, and its own remote server, storing them in the
+// package variables the tests use. Tests thus never see what other tests stored, but cannot run in parallel.
+func setup(t *testing.T) {
+	t.Helper()
+	d, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()))
+	if err != nil {
+		t.Fatalf("failed to open connection: %s", err)
+	}
+
+	driver, err := sqlite3.WithInstance(d, &sqlite3.Config{})
+	if err != nil {
+		t.Fatalf("failed to create driver: %s", err)
+	}
+
+	mig, err := migrate.NewWithDatabaseInstance("file://../../migrations", t.Name(), driver)
+	if err != nil {
+		t.Fatalf("failed to create database object: %s", err)
+	}
+
+	if err = mig.Up(); err != nil {
+		t.Fatalf("failed to run migrations: %s", err)
+	}
+	database = d
+
+	u, _ := url.Parse("http://test.wiki")
+	// The fake servers listen on the loopback interface.
+	conf := config.Configuration{Domain: "test.wiki", Url: u, AllowPrivateAddresses: true}
+	fed = New(&state.State{DB: dbimpl.New(conf, d), Config: conf})
+
+	alice := u.JoinPath("u", "alice")
+	err = fed.DB.InsertUser(ctx, domain.UserFedInternal{
+		UserFed: domain.UserFed{
+			UserCore:  domain.UserCore{Username: "alice"},
+			ApId:      alice,
+			Inbox:     alice.JoinPath("inbox"),
+			Outbox:    alice.JoinPath("outbox"),
+			Followers: alice.JoinPath("followers"),
+		},
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(aliceKey),
+		})),
+	}, domain.Account{Email: "alice@test.wiki"}, "", "")
+	if err != nil {
+		t.Fatalf("failed to create local user: %s", err)
+	}
+
+	if remote, err = newFakeServer(remoteKey); err != nil {
+		t.Fatalf("failed to start remote server: %s", err)
+	}
+	t.Cleanup(func() {
+		remote.Close()
+		d.Close()
+	})
+}
+
+// createArticle creates a local article written by alice, for the tests that only need one to exist.
+func createArticle(t *testing.T, title, content string) *url.URL {
+	t.Helper()
+	var aliceId int64
+	if err := database.QueryRow("SELECT id FROM users WHERE ap_id = ?", fed.Config.Url.JoinPath("u", "alice").String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+	article := fed.Config.Url.JoinPath("a", title)
+	_, err := fed.DB.CreateLocalArticle(ctx, aliceId, domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{Title: title, Content: content, Language: "it", MediaType: "text/plain"},
+		ApID:        article,
+	}, domain.Revision{Diff: fmt.Sprintf("@@ -0,0 +1,%d @@\n+%s\n", len([]rune(content)), content)})
+	if err != nil {
+		t.Fatalf("failed to create article: %s", err)
+	}
+	return article
+}
+
+// createInstanceActor creates the instance actor, which signs the requests not made on behalf of a user, and makes
+// the remote server trust its key.
+func createInstanceActor(t *testing.T) *url.URL {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	actor := InstanceActorId(fed.Config.Url)
+	err = fed.DB.CreateInstanceActor(ctx, domain.UserFedInternal{
+		UserFed: domain.UserFed{
+			UserCore:  domain.UserCore{Username: fed.Config.Domain, Name: fed.Config.Domain},
+			ApId:      actor,
+			Inbox:     actor.JoinPath("inbox"),
+			Outbox:    actor.JoinPath("outbox"),
+			Followers: actor.JoinPath("followers"),
+			Bot:       true,
+		},
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})),
+	})
+	if err != nil {
+		t.Fatalf("failed to create instance actor: %s", err)
+	}
+	remote.trusted.Store(conversions.KeyID(actor).String(), &key.PublicKey)
+	return actor
+}
+
+// processDeliveries claims a batch of due deliveries and attempts them concurrently, returning once all of them
+// are done; the queue can thus be drained without running the workers of RunDelivery.
+func (f *FedProto) processDeliveries(ctx context.Context) (int, error) {
+	deliveries, err := f.DB.ClaimDeliveries(ctx, DeliveryWorkers, deliveryLease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.attemptDelivery(ctx, d)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+func activityBody(actor *url.URL) []byte {
+	return []byte(fmt.Sprintf(`{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "%[1]s/activities/1",
+		"type": "Create",
+		"actor": "%[1]s",
+		"object": {
+			"id": "%[1]s/notes/1",
+			"type": "Note",
+			"content": "Hello, wiki!"
+		}
+	}`, actor))
+}
+
+func signedRequest(t *testing.T, key *rsa.PrivateKey, keyId string, body []byte, date time.Time) *http.Request {
+	return signedRequestTo(t, "http://test.wiki/u/alice/inbox", key, keyId, body, date)
+}
+
+func signedRequestTo(t *testing.T, inbox string, key *rsa.PrivateKey, keyId string, body []byte, date time.Time) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, inbox, bytes.NewReader(body))
+	r.Header.Set("Content-Type", ActivityJSON)
+	return sign(t, r, key, keyId, body, date)
+}
+
+// sign signs r with key, covering the digest of body unless it is nil.
+func sign(t *testing.T, r *http.Request, key *rsa.PrivateKey, keyId string, body []byte, date time.Time) *http.Request {
+	r.Header.Set("Host", "test.wiki")
+	r.Header.Set("Date", date.UTC().Format(http.TimeFormat))
+
+	headers := []string{httpsig.RequestTarget, "host", "date"}
+	if body != nil {
+		headers = append(headers, "digest")
+	}
+
+	signer, _, err := httpsig.NewSigner(
+		[]httpsig.Algorithm{httpsig.RSA_SHA256},
+		httpsig.DigestSha256,
+		headers,
+		httpsig.Signature,
+		0,
+	)
+	if err != nil {
+		t.Fatalf("failed to create signer: %s", err)
+	}
+
+	if err = signer.SignRequest(key, keyId, r, body); err != nil {
+		t.Fatalf("failed to sign request: %s", err)
+	}
+	return r
+}
+
+func TestPostInbox(t *testing.T) {
+	setup(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received int
+	fed.Handle("Create", func(ctx context.Context, inbox *url.URL, activity Activity) error {
+		received++
+		return nil
+	})
+
+	bob := remote.actorId()
+	keyId := conversions.KeyID(bob).String()
+	body := activityBody(bob)
+
+	cases := []struct {
+		Casename string
+		Request  func() *http.Request
+		Code     int
+	}{
+		{"valid signature", func() *http.Request {
+			return signedRequest(t, remote.key, keyId, body, time.Now())
+		}, http.StatusAccepted},
+		{"tampered body", func() *http.Request {
+			r := signedRequest(t, remote.key, keyId, body, time.Now())
+			r.Body = http.NoBody
+			return r
+		}, http.StatusUnauthorized},
+		{"stale date", func() *http.Request {
+			return signedRequest(t, remote.key, keyId, body, time.Now().Add(-2*MaxClockSkew))
+		}, http.StatusUnauthorized},
+		{"wrong key", func() *http.Request {
+			return signedRequest(t, other, keyId, body, time.Now())
+		}, http.StatusUnauthorized},
+		{"unsigned", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "http://test.wiki/u/alice/inbox", bytes.NewReader(body))
+			r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+			return r
+		}, http.StatusUnauthorized},
+		{"actor is not the signer", func() *http.Request {
+			impostor, _ := url.Parse(remote.URL + "/users/carol")
+			return signedRequest(t, remote.key, keyId, activityBody(impostor), time.Now())
+		}, http.StatusForbidden},
+		{"unknown inbox", func() *http.Request {
+			r := signedRequest(t, remote.key, keyId, body, time.Now())
+			r.URL.Path = "/u/nobody/inbox"
+			return r
+		}, http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Casename, func(t *testing.T) {
+			before := received
+			w := httptest.NewRecorder()
+			fed.PostInbox(w, c.Request())
+
+			if w.Code != c.Code {
+				t.Fatalf("expected status %d, got %d: %s", c.Code, w.Code, w.Body.String())
+			}
+
+			if accepted := c.Code == http.StatusAccepted; accepted != (received == before+1) {
+				t.Errorf("expected handler to be called: %v, calls before: %d, after: %d", accepted, before, received)
+			}
+		})
+	}
+}
+
+func TestPublicKeyIsCached(t *testing.T) {
+	setup(t)
+	bob := remote.actorId()
+	keyId := conversions.KeyID(bob).String()
+
+	for range 2 {
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequest(t, remote.key, keyId, activityBody(bob), time.Now()))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+	}
+
+	before := remote.fetches.Load()
+	w := httptest.NewRecorder()
+	fed.PostInbox(w, signedRequest(t, remote.key, keyId, activityBody(bob), time.Now()))
+	if after := remote.fetches.Load(); after != before {
+		t.Errorf("expected cached key to be used, but actor was fetched %d more times", after-before)
+	}
+
+	key, err := fed.DB.GetPublicKey(ctx, bob)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err = ParsePublicKey(key); err != nil {
+		t.Errorf("stored key is invalid: %s", err)
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	inbox := remote.actorId().JoinPath("inbox")
+
+	cases := []struct {
+		Casename string
+		Status   int
+		Attempts int
+		// Pending is true if the delivery should remain in the queue, and Dead if it should be given up on.
+		Pending bool
+		Dead    bool
+	}{
+		{"accepted", http.StatusAccepted, 0, false, false},
+		{"server error", http.StatusInternalServerError, 0, true, false},
+		{"gone", http.StatusGone, 0, true, true},
+		{"too many attempts", http.StatusServiceUnavailable, MaxDeliveryAttempts - 1, true, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Casename, func(t *testing.T) {
+			remote.inboxStatus.Store(int32(c.Status))
+			before := remote.received.Load()
+
+			note := streams.NewActivityStreamsNote()
+			if err := fed.Deliver(ctx, alice, note, []*url.URL{inbox}); err != nil {
+				t.Fatalf("failed to enqueue delivery: %s", err)
+			}
+
+			var id int64
+			err := database.QueryRow("SELECT id FROM deliveries ORDER BY id DESC LIMIT 1").Scan(&id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = database.Exec("UPDATE deliveries SET attempts = ? WHERE id = ?", c.Attempts, id); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = fed.processDeliveries(ctx); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if after := remote.received.Load(); after != before+1 {
+				t.Fatalf("expected a signed delivery to reach the inbox, got %d", after-before)
+			}
+
+			var attempts int
+			var dead bool
+			err = database.QueryRow("SELECT attempts, dead FROM deliveries WHERE id = ?", id).Scan(&attempts, &dead)
+			if pending := err == nil; pending != c.Pending {
+				t.Fatalf("expected delivery to be pending: %v, got: %v (%v)", c.Pending, pending, err)
+			}
+			if !c.Pending {
+				return
+			}
+
+			if attempts != c.Attempts+1 {
+				t.Errorf("expected %d attempts, got %d", c.Attempts+1, attempts)
+			}
+			if dead != c.Dead {
+				t.Errorf("expected delivery to be dead: %v, got: %v", c.Dead, dead)
+			}
+
+			// Deliveries that failed are not due until later.
+			if n, _ := fed.processDeliveries(ctx); n != 0 {
+				t.Errorf("expected no due deliveries, got %d", n)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if b := Backoff(1); b != DeliveryBackoff {
+		t.Errorf("expected first retry after %s, got %s", DeliveryBackoff, b)
+	}
+	if b := Backoff(3); b != 4*DeliveryBackoff {
+		t.Errorf("expected third retry after %s, got %s", 4*DeliveryBackoff, b)
+	}
+	if b := Backoff(100); b != MaxDeliveryBackoff {
+		t.Errorf("expected backoff to be capped at %s, got %s", MaxDeliveryBackoff, b)
+	}
+}
+
+func TestSigningFailureIsRetried(t *testing.T) {
+	setup(t)
+	// An actor without a key cannot sign, which may be a passing failure of the database.
+	ghost := fed.Config.Url.JoinPath("u", "ghost")
+	if err := fed.Deliver(ctx, ghost, streams.NewActivityStreamsNote(), []*url.URL{remote.actorId().JoinPath("inbox")}); err != nil {
+		t.Fatalf("failed to deliver: %s", err)
+	}
+	var id int64
+	if err := database.QueryRow("SELECT id FROM deliveries WHERE actor = ?", ghost.String()).Scan(&id); err != nil {
+		t.Fatalf("expected a delivery: %s", err)
+	}
+
+	if _, err := fed.processDeliveries(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var attempts int
+	var dead bool
+	if err := database.QueryRow("SELECT attempts, dead FROM deliveries WHERE id = ?", id).Scan(&attempts, &dead); err != nil {
+		t.Fatalf("failed to get delivery: %s", err)
+	}
+	if dead || attempts != 1 {
+		t.Errorf("expected the delivery to be retried, got %d attempts (dead: %v)", attempts, dead)
+	}
+}
+
+func TestDeliveryWorkers(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+
+	// A delivery to an inbox that cannot be parsed is given up on rather than claimed again.
+	res, err := database.Exec("INSERT INTO deliveries (actor, inbox, activity) VALUES (?, ?, '{}')", alice.String(), "http://[::1")
+	if err != nil {
+		t.Fatalf("failed to insert delivery: %s", err)
+	}
+	id, _ := res.LastInsertId()
+	if n, err := fed.processDeliveries(ctx); err != nil || n != 0 {
+		t.Errorf("expected the delivery not to be attempted, got %d (%v)", n, err)
+	}
+	var dead bool
+	if err = database.QueryRow("SELECT dead FROM deliveries WHERE id = ?", id).Scan(&dead); err != nil || !dead {
+		t.Errorf("expected the delivery to be given up on, got %v (%v)", dead, err)
+	}
+
+	// A server that is slow to answer does not hold back the deliveries to other servers.
+	arrived, release := make(chan struct{}), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	c, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		fed.RunDelivery(c)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	slowInbox, _ := url.Parse(slow.URL + "/inbox")
+	if err = fed.Deliver(ctx, alice, streams.NewActivityStreamsNote(), []*url.URL{slowInbox}); err != nil {
+		t.Fatalf("failed to enqueue delivery: %s", err)
+	}
+	select {
+	case <-arrived:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the delivery to the slow server to be attempted")
+	}
+
+	before := remote.received.Load()
+	if err = fed.Deliver(ctx, alice, streams.NewActivityStreamsNote(), []*url.URL{remote.actorId().JoinPath("inbox")}); err != nil {
+		t.Fatalf("failed to enqueue delivery: %s", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); remote.received.Load() == before; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the delivery to be attempted while the slow server is busy")
+		}
+	}
+}
+
+func TestFollow(t *testing.T) {
+	setup(t)
+	bob := remote.actorId()
+	keyId := conversions.KeyID(bob).String()
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	follow := fmt.Sprintf(`{
+		"id": "%[1]s/follows/1",
+		"type": "Follow",
+		"actor": "%[1]s",
+		"object": "%[2]s"
+	}`, bob, alice)
+
+	post := func(body string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequest(t, remote.key, keyId, []byte(body), time.Now()))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+	}
+
+	post(`{"@context": "https://www.w3.org/ns/activitystreams", ` + follow[2:])
+	if n, err := fed.DB.CountFollowers(ctx, alice); err != nil || n != 1 {
+		t.Fatalf("expected 1 follower, got %d (%v)", n, err)
+	}
+
+	before := remote.received.Load()
+	if _, err := fed.processDeliveries(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if after := remote.received.Load(); after != before+1 {
+		t.Errorf("expected an Accept to be delivered to the follower, got %d deliveries", after-before)
+	}
+
+	post(fmt.Sprintf(`{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "%s/follows/1/undo",
+		"type": "Undo",
+		"actor": "%s",
+		"object": %s
+	}`, bob, bob, follow))
+	if n, err := fed.DB.CountFollowers(ctx, alice); err != nil || n != 0 {
+		t.Errorf("expected follower to be removed, got %d (%v)", n, err)
+	}
+}
+
+func TestPublishRevision(t *testing.T) {
+	setup(t)
+	bob := remote.actorId()
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	if _, err := fed.FetchActor(ctx, bob); err != nil {
+		t.Fatalf("failed to fetch follower: %s", err)
+	}
+	if err := fed.DB.AddFollow(ctx, domain.Follow{Follower: bob, Followee: alice, Accepted: true}); err != nil {
+		t.Fatalf("failed to add follower: %s", err)
+	}
+
+	var aliceId int64
+	if err := database.QueryRow("SELECT id FROM users WHERE ap_id = ?", alice.String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+
+	article := fed.Config.Url.JoinPath("a", "Sidereus Nuncius")
+	_, err := fed.DB.CreateLocalArticle(ctx, aliceId, domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{Title: "Sidereus Nuncius", Content: "Moons", Language: "la", MediaType: "text/plain"},
+		ApID:        article,
+	}, domain.Revision{Diff: "@@ -0,0 +1,5 @@\n+Moons\n"})
+	if err != nil {
+		t.Fatalf("failed to create article: %s", err)
+	}
+
+	var articleId, prev int64
+	err = database.QueryRow("SELECT article_id, id FROM revisions WHERE ap_id = ?", article.JoinPath("history", "1").String()).
+		Scan(&articleId, &prev)
+	if err != nil {
+		t.Fatalf("expected the initial revision to have an ID: %s", err)
+	}
+
+	revision, err := fed.DB.UpdateArticle(ctx, prev, articleId, aliceId, "Jupiter", "Moons of Jupiter")
+	if err != nil {
+		t.Fatalf("failed to update article: %s", err)
+	}
+	if expected := article.JoinPath("history", strconv.FormatInt(prev+1, 10)); revision.String() != expected.String() {
+		t.Errorf("expected revision ID %s, got %s", expected, revision)
+	}
+
+	if err := fed.PublishRevision(ctx, revision); err != nil {
+		t.Fatalf("failed to publish revision: %s", err)
+	}
+
+	var activity string
+	if err := database.QueryRow("SELECT activity FROM deliveries ORDER BY id DESC LIMIT 1").Scan(&activity); err != nil {
+		t.Fatalf("expected a delivery: %s", err)
+	}
+	for _, s := range []string{`"type":"Update"`, `"patch":`, `"summary":"Jupiter"`, alice.JoinPath("followers").String()} {
+		if !strings.Contains(activity, s) {
+			t.Errorf("expected the delivered activity to contain %s: %s", s, activity)
+		}
+	}
+
+	before := remote.received.Load()
+	if _, err := fed.processDeliveries(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if after := remote.received.Load(); after != before+1 {
+		t.Errorf("expected the revision to be delivered to the follower, got %d deliveries", after-before)
+	}
+}
+
+func TestFollowArticle(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	var aliceId int64
+	if err := database.QueryRow("SELECT id FROM users WHERE ap_id = ?", alice.String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+
+	article := fed.Config.Url.JoinPath("a", "Lettere")
+	_, err := fed.DB.CreateLocalArticle(ctx, aliceId, domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{Title: "Lettere", Content: "Motion", Language: "it", MediaType: "text/plain"},
+		ApID:        article,
+	}, domain.Revision{Diff: "@@ -0,0 +1,6 @@\n+Motion\n"})
+	if err != nil {
+		t.Fatalf("failed to create article: %s", err)
+	}
+
+	bob := remote.actorId()
+	body := fmt.Sprintf(`{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "%[1]s/follows/lettere",
+		"type": "Follow",
+		"actor": "%[1]s",
+		"object": "%[2]s"
+	}`, bob, article)
+	follow := func() int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequestTo(t, article.JoinPath("inbox").String(), remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+		return w.Code
+	}
+
+	// Articles without a key cannot sign the Accept, so they cannot be followed.
+	if code := follow(); code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an article without keys, got %d", http.StatusBadRequest, code)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&aliceKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to encode key: %s", err)
+	}
+	err = fed.DB.SetArticleKeys(ctx, article,
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(aliceKey)})),
+	)
+	if err != nil {
+		t.Fatalf("failed to set article keys: %s", err)
+	}
+
+	// With a key, the article is still served as an Article, with the properties of an actor as extensions.
+	stored, err := fed.DB.GetArticleFed(ctx, article)
+	if err != nil {
+		t.Fatalf("failed to get article: %s", err)
+	}
+	object := conversions.ArticleToObject(stored)
+	m, err := streams.Serialize(object)
+	if err != nil {
+		t.Fatalf("failed to serialize article: %s", err)
+	}
+	if m["type"] != "Article" || m["inbox"] != article.JoinPath("inbox").String() || m["publicKey"] == nil {
+		t.Errorf("expected the article to be served as an Article that can be followed, got %v", m)
+	}
+	if converted, err := conversions.ObjectToArticle(object); err != nil || converted.Content != "Motion" {
+		t.Errorf("expected the object to be read as an article, got %+v (%v)", converted, err)
+	}
+
+	if code := follow(); code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, code)
+	}
+	if n, err := fed.DB.CountFollowers(ctx, article); err != nil || n != 1 {
+		t.Fatalf("expected the article to have 1 follower, got %d (%v)", n, err)
+	}
+
+	var actor, activity string
+	if err := database.QueryRow("SELECT actor, activity FROM deliveries ORDER BY id DESC LIMIT 1").Scan(&actor, &activity); err != nil {
+		t.Fatalf("expected a delivery: %s", err)
+	}
+	if actor != article.String() || !strings.Contains(activity, `"type":"Accept"`) {
+		t.Errorf("expected the article to send an Accept, got %s from %s", activity, actor)
+	}
+
+	before := remote.received.Load()
+	if _, err := fed.processDeliveries(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if after := remote.received.Load(); after != before+1 {
+		t.Errorf("expected the Accept to be delivered, got %d deliveries", after-before)
+	}
+
+	// Revisions of the article now reach its followers.
+	articleId, _, prev, err := fed.DB.GetLastRevisionID(ctx, "Lettere")
+	if err != nil {
+		t.Fatalf("failed to get last revision: %s", err)
+	}
+	revision, err := fed.DB.UpdateArticle(ctx, prev, articleId, aliceId, "Bodies", "Motion of bodies")
+	if err != nil {
+		t.Fatalf("failed to update article: %s", err)
+	}
+	if err := fed.PublishRevision(ctx, revision); err != nil {
+		t.Fatalf("failed to publish revision: %s", err)
+	}
+	err = database.QueryRow("SELECT activity FROM deliveries WHERE inbox = ? ORDER BY id DESC LIMIT 1", bob.JoinPath("inbox").String()).
+		Scan(&activity)
+	if err != nil || !strings.Contains(activity, article.JoinPath("followers").String()) {
+		t.Errorf("expected the revision to be delivered to the followers of the article: %s (%v)", activity, err)
+	}
+	if _, err := fed.processDeliveries(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestFetchArticle(t *testing.T) {
+	setup(t)
+	host := remote.articleId().Host
+	if _, err := fed.Finger(ctx, "Nonexistent", host); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected %v for an unknown handle, got %v", db.ErrNotFound, err)
+	}
+
+	id, err := fed.Finger(ctx, "Dialogue", host)
+	if err != nil {
+		t.Fatalf("failed to resolve article: %s", err)
+	}
+	if id.String() != remote.articleId().String() {
+		t.Fatalf("expected %s, got %s", remote.articleId(), id)
+	}
+
+	if _, err := fed.FetchArticle(ctx, id); err != nil {
+		t.Fatalf("failed to fetch article: %s", err)
+	}
+
+	article, err := fed.DB.GetForeignArticle(ctx, "dialogue", host)
+	if err != nil {
+		t.Fatalf("failed to get stored article: %s", err)
+	}
+	if article.Local || article.Host != host || article.Language != "it" || article.LastFetched.IsZero() {
+		t.Errorf("unexpected stored article: %+v", article)
+	}
+
+	if _, err := fed.FetchArticle(ctx, remote.actorId()); !errors.Is(err, ErrFetch) {
+		t.Errorf("expected %v when fetching an actor as an article, got %v", ErrFetch, err)
+	}
+}
+
+func TestArticleUpdate(t *testing.T) {
+	setup(t)
+	bob := remote.actorId()
+	article := remote.articleId()
+	if _, err := fed.FetchArticle(ctx, article); err != nil {
+		t.Fatalf("failed to fetch article: %s", err)
+	}
+
+	post := func(body string) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequest(t, remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+		return w.Code
+	}
+	content := func() string {
+		t.Helper()
+		a, err := fed.DB.GetArticleFed(ctx, article)
+		if err != nil {
+			t.Fatalf("failed to get article: %s", err)
+		}
+		return a.Content
+	}
+
+	// The server of the article announces a revision with its patch, and our copy is fetched again.
+	remote.content.Store("<p>E pur si muove</p>")
+	revision := article.JoinPath("history", "2")
+	code := post(fmt.Sprintf(`{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "%[3]s",
+		"type": "Update",
+		"actor": "%[1]s",
+		"object": "%[2]s",
+		"summary": "Spelling",
+		"patch": "@@ -1,6 +1,7 @@\n %%3Cp%%3EE\n-pp\n+ p\n ur s\n"
+	}`, bob, article, revision))
+	if code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, code)
+	}
+	if r, err := fed.DB.GetRevisionFed(ctx, revision); err != nil || r.Summary != "Spelling" {
+		t.Errorf("expected the revision to be stored, got %+v (%v)", r, err)
+	}
+	if c := content(); c != "<p>E pur si muove</p>" {
+		t.Errorf("expected the Update to change the content of our copy, got %q", c)
+	}
+
+	// The Article carried by an Update replaces our copy.
+	object, err := streams.Serialize(conversions.ArticleToObject(domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{Title: "Dialogue", Content: "<p>Eppure si muove</p>", MediaType: "text/html", Language: "it"},
+		ApID:        article,
+	}))
+	if err != nil {
+		t.Fatalf("failed to serialize article: %s", err)
+	}
+	delete(object, "@context")
+	encoded, _ := json.Marshal(object)
+	code = post(fmt.Sprintf(`{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "%[1]s/updates/dialogue",
+		"type": "Update",
+		"actor": "%[1]s",
+		"object": %[2]s
+	}`, bob, encoded))
+	if code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, code)
+	}
+	if c := content(); c != "<p>Eppure si muove</p>" {
+		t.Errorf("expected the Article carried by the Update to replace our copy, got %q", c)
+	}
+}
+
+func TestProposal(t *testing.T) {
+	setup(t)
+	article := remote.articleId()
+	if _, err := fed.FetchArticle(ctx, article); err != nil {
+		t.Fatalf("failed to fetch article: %s", err)
+	}
+
+	var aliceId int64
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	if err := database.QueryRow("SELECT id FROM users WHERE ap_id = ?", alice.String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+
+	inbox, err := fed.ProposalInbox(ctx, article)
+	if err != nil {
+		t.Fatalf("failed to find inbox: %s", err)
+	}
+	if expected := remote.actorId().JoinPath("inbox"); inbox.String() != expected.String() {
+		t.Errorf("expected inbox %s, got %s", expected, inbox)
+	}
+
+	id, err := fed.DB.InsertProposal(ctx, article, aliceId, "Galileo", "@@ -1,3 +1,3 @@\n-a\n+b\n")
+	if err != nil {
+		t.Fatalf("failed to insert proposal: %s", err)
+	}
+	if err := fed.SendProposal(ctx, id, inbox); err != nil {
+		t.Fatalf("failed to send proposal: %s", err)
+	}
+
+	var activity string
+	if err := database.QueryRow("SELECT activity FROM deliveries ORDER BY id DESC LIMIT 1").Scan(&activity); err != nil {
+		t.Fatalf("expected a delivery: %s", err)
+	}
+	if !strings.Contains(activity, `"type":"Update"`) || !strings.Contains(activity, id.String()) {
+		t.Errorf("expected an Update with ID %s, got %s", id, activity)
+	}
+
+	before := remote.received.Load()
+	if _, err := fed.processDeliveries(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if after := remote.received.Load(); after != before+1 {
+		t.Errorf("expected the proposal to be delivered, got %d deliveries", after-before)
+	}
+
+	bob := remote.actorId()
+	review := func(typeName string) {
+		t.Helper()
+		body := fmt.Sprintf(`{
+			"@context": "https://www.w3.org/ns/activitystreams",
+			"id": "%[1]s/reviews/%[2]s",
+			"type": "%[2]s",
+			"actor": "%[1]s",
+			"object": "%[3]s"
+		}`, bob, typeName, id)
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequest(t, remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+	}
+
+	review("Accept")
+	// A proposal can only be reviewed once.
+	review("Reject")
+
+	revision, err := fed.DB.GetRevisionFed(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %s", err)
+	}
+	if !revision.Proposal || !revision.Reviewed || !revision.Published || revision.Initial {
+		t.Errorf("expected an accepted proposal, got %+v", revision)
+	}
+}
+
+func TestIncomingProposal(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	var aliceId int64
+	if err := database.QueryRow("SELECT id FROM users WHERE ap_id = ?", alice.String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+
+	article := fed.Config.Url.JoinPath("a", "Dialogo")
+	_, err := fed.DB.CreateLocalArticle(ctx, aliceId, domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{Title: "Dialogo", Content: "Two systems", Language: "it", MediaType: "text/plain"},
+		ApID:        article,
+	}, domain.Revision{Diff: "@@ -0,0 +1,11 @@\n+Two systems\n"})
+	if err != nil {
+		t.Fatalf("failed to create article: %s", err)
+	}
+
+	bob := remote.actorId()
+	proposal := bob.JoinPath("proposals", "1")
+	body := fmt.Sprintf(`{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "%s",
+		"type": "Update",
+		"actor": "%s",
+		"object": "%s",
+		"summary": "Chief systems",
+		"patch": "@@ -1,3 +1,9 @@\n-Two\n+The chief\n  sy\n"
+	}`, proposal, bob, article)
+	for range 2 {
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequest(t, remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+	}
+
+	pending, err := fed.DB.ListPendingProposals(ctx)
+	if err != nil {
+		t.Fatalf("failed to list proposals: %s", err)
+	}
+	if len(pending) != 1 || pending[0].Title != "Dialogo" || pending[0].Summary != "Chief systems" {
+		t.Fatalf("expected a single pending proposal, got %+v", pending)
+	}
+
+	received, err := fed.DB.GetRevisionFed(ctx, proposal)
+	if err != nil {
+		t.Fatalf("failed to get proposal: %s", err)
+	}
+
+	if _, err := fed.DB.AcceptProposal(ctx, pending[0].ID, aliceId, received.Diff, "The chief systems"); err != nil {
+		t.Fatalf("failed to accept proposal: %s", err)
+	}
+	if err := fed.SendReview(ctx, alice, received, true); err != nil {
+		t.Fatalf("failed to send review: %s", err)
+	}
+
+	var activity string
+	if err := database.QueryRow("SELECT activity FROM deliveries ORDER BY id DESC LIMIT 1").Scan(&activity); err != nil {
+		t.Fatalf("expected a delivery: %s", err)
+	}
+	if !strings.Contains(activity, `"type":"Accept"`) || !strings.Contains(activity, proposal.String()) {
+		t.Errorf("expected an Accept of %s, got %s", proposal, activity)
+	}
+
+	stored, err := fed.DB.GetArticleFed(ctx, article)
+	if err != nil {
+		t.Fatalf("failed to get article: %s", err)
+	}
+	if stored.Content != "The chief systems" {
+		t.Errorf("expected the proposal to be applied, got %q", stored.Content)
+	}
+}
+
+func TestReactions(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	var aliceId int64
+	if err := database.QueryRow("SELECT id FROM users WHERE ap_id = ?", alice.String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+
+	article := fed.Config.Url.JoinPath("a", "Il Saggiatore")
+	_, err := fed.DB.CreateLocalArticle(ctx, aliceId, domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{Title: "Il Saggiatore", Content: "Comets", Language: "it", MediaType: "text/plain"},
+		ApID:        article,
+	}, domain.Revision{Diff: "@@ -0,0 +1,6 @@\n+Comets\n"})
+	if err != nil {
+		t.Fatalf("failed to create article: %s", err)
+	}
+
+	bob := remote.actorId()
+	post := func(fields string) {
+		t.Helper()
+		body := `{"@context": "https://www.w3.org/ns/activitystreams", ` + fields + `}`
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequest(t, remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+	}
+	reaction := func(typeName string) string {
+		return fmt.Sprintf(`"id": "%[1]s/%[2]s/1", "type": "%[2]s", "actor": "%[1]s", "object": "%[3]s"`, bob, typeName, article)
+	}
+	counts := func() (likes, shares int64) {
+		t.Helper()
+		a, err := fed.DB.GetArticleFed(ctx, article)
+		if err != nil {
+			t.Fatalf("failed to get article: %s", err)
+		}
+		return a.Likes, a.Shares
+	}
+
+	for _, typeName := range []string{"Like", "Announce"} {
+		post(reaction(typeName))
+		// Repeated activities are only counted once.
+		post(reaction(typeName))
+	}
+	if likes, shares := counts(); likes != 1 || shares != 1 {
+		t.Errorf("expected a like and a share, got %d and %d", likes, shares)
+	}
+
+	post(fmt.Sprintf(`"id": "%s/undo/1", "type": "Undo", "actor": "%s", "object": {%s}`, bob, bob, reaction("Like")))
+	if likes, shares := counts(); likes != 0 || shares != 1 {
+		t.Errorf("expected only a share after the undo, got %d likes and %d shares", likes, shares)
+	}
+}
+
+func TestComments(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	var aliceId int64
+	if err := database.QueryRow("SELECT id FROM users WHERE ap_id = ?", alice.String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+
+	article := fed.Config.Url.JoinPath("a", "Discorsi")
+	_, err := fed.DB.CreateLocalArticle(ctx, aliceId, domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{Title: "Discorsi", Content: "Two new sciences", Language: "it", MediaType: "text/plain"},
+		ApID:        article,
+	}, domain.Revision{Diff: "@@ -0,0 +1,16 @@\n+Two new sciences\n"})
+	if err != nil {
+		t.Fatalf("failed to create article: %s", err)
+	}
+
+	local, err := fed.DB.InsertLocalComment(ctx, article, aliceId, 0, "<p>Comments welcome</p>")
+	if err != nil {
+		t.Fatalf("failed to insert comment: %s", err)
+	}
+
+	bob := remote.actorId()
+	note := func(id int, inReplyTo *url.URL) {
+		t.Helper()
+		body := fmt.Sprintf(`{
+			"@context": "https://www.w3.org/ns/activitystreams",
+			"id": "%[1]s/notes/%[2]d/activity",
+			"type": "Create",
+			"actor": "%[1]s",
+			"object": {
+				"id": "%[1]s/notes/%[2]d",
+				"type": "Note",
+				"attributedTo": "%[1]s",
+				"inReplyTo": "%[3]s",
+				"content": "<p>Note %[2]d</p>"
+			}
+		}`, bob, id, inReplyTo)
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequest(t, remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+	}
+
+	note(1, local)
+	note(2, article)
+	note(3, bob.JoinPath("notes", "2"))
+	// Notes outside of a discussion are ignored.
+	note(4, bob.JoinPath("elsewhere"))
+
+	threads, err := fed.DB.ListComments(ctx, article)
+	if err != nil {
+		t.Fatalf("failed to list comments: %s", err)
+	}
+	if len(threads) != 2 {
+		t.Fatalf("expected 2 threads, got %+v", threads)
+	}
+	for i, expected := range []string{"<p>Note 1</p>", "<p>Note 3</p>"} {
+		if len(threads[i].Replies) != 1 || threads[i].Replies[0].Content != expected {
+			t.Errorf("expected thread %d to have the reply %s, got %+v", i, expected, threads[i].Replies)
+		}
+	}
+
+	reply, err := fed.DB.InsertLocalComment(ctx, article, aliceId, threads[1].ID, "<p>Indeed</p>")
+	if err != nil {
+		t.Fatalf("failed to insert reply: %s", err)
+	}
+	if err := fed.PublishComment(ctx, reply); err != nil {
+		t.Fatalf("failed to publish comment: %s", err)
+	}
+
+	var activity string
+	if err := database.QueryRow("SELECT activity FROM deliveries WHERE inbox = ? ORDER BY id DESC LIMIT 1", bob.JoinPath("inbox").String()).Scan(&activity); err != nil {
+		t.Fatalf("expected the reply to be delivered to the author of the note: %s", err)
+	}
+	if !strings.Contains(activity, `"type":"Note"`) || !strings.Contains(activity, bob.JoinPath("notes", "2").String()) {
+		t.Errorf("expected a Note in reply to %s, got %s", bob.JoinPath("notes", "2"), activity)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	setup(t)
+	bob := remote.actorId()
+	host := bob.Host
+	article := createArticle(t, "Discorsi", "Two new sciences")
+
+	if err := fed.DB.SetInstancePolicy(ctx, host, domain.PolicySuspend); err != nil {
+		t.Fatalf("failed to suspend instance: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	fed.PostInbox(w, signedRequest(t, remote.key, conversions.KeyID(bob).String(), activityBody(bob), time.Now()))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, w.Code, w.Body.String())
+	}
+
+	before := remote.fetches.Load()
+	if _, err := fed.FetchActor(ctx, bob); !errors.Is(err, ErrSuspended) {
+		t.Errorf("expected %v, got %v", ErrSuspended, err)
+	}
+	if after := remote.fetches.Load(); after != before {
+		t.Errorf("expected no request to a suspended instance, got %d", after-before)
+	}
+
+	count := func() int {
+		t.Helper()
+		var n int
+		if err := database.QueryRow("SELECT COUNT(*) FROM deliveries").Scan(&n); err != nil {
+			t.Fatalf("failed to count deliveries: %s", err)
+		}
+		return n
+	}
+	pending := count()
+	if err := fed.Deliver(ctx, fed.Config.Url.JoinPath("u", "alice"), streams.NewActivityStreamsNote(), []*url.URL{bob.JoinPath("inbox")}); err != nil {
+		t.Fatalf("failed to deliver: %s", err)
+	}
+	if n := count(); n != pending {
+		t.Errorf("expected no delivery to a suspended instance, got %d", n-pending)
+	}
+
+	if err := fed.DB.SetInstancePolicy(ctx, host, domain.PolicySilence); err != nil {
+		t.Fatalf("failed to silence instance: %s", err)
+	}
+
+	note := bob.JoinPath("notes", "silenced")
+	body := fmt.Sprintf(`{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "%[2]s/activity",
+		"type": "Create",
+		"actor": "%[1]s",
+		"object": {
+			"id": "%[2]s",
+			"type": "Note",
+			"attributedTo": "%[1]s",
+			"inReplyTo": "%[3]s",
+			"content": "<p>Unwelcome</p>"
+		}
+	}`, bob, note, article)
+	w = httptest.NewRecorder()
+	fed.PostInbox(w, signedRequest(t, remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+
+	var hidden bool
+	if err := database.QueryRow("SELECT hidden FROM comments WHERE ap_id = ?", note.String()).Scan(&hidden); err != nil {
+		t.Fatalf("expected the note to be stored: %s", err)
+	}
+	if !hidden {
+		t.Error("expected the note of a silenced instance to be hidden")
+	}
+}
+
+func TestFetchRevisions(t *testing.T) {
+	setup(t)
+	revisions, err := fed.FetchRevisions(ctx, remote.actorId().JoinPath("outbox"))
+	if err != nil {
+		t.Fatalf("failed to fetch revisions: %s", err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("expected a revision, got %+v", revisions)
+	}
+	if r := revisions[0]; r.Article.String() != remote.articleId().String() || r.Summary != "First draft" || !r.Initial {
+		t.Errorf("unexpected revision %+v", r)
+	}
+
+	if _, err := fed.FetchRevisions(ctx, remote.actorId()); !errors.Is(err, ErrFetch) {
+		t.Errorf("expected %v for an actor, got %v", ErrFetch, err)
+	}
+}
+
+func TestAuthorizedFetch(t *testing.T) {
+	setup(t)
+	fed.Config.AuthorizedFetch = true
+
+	bob := remote.actorId()
+	if _, authenticated, _ := fed.AuthenticateGet(ctx, nil, httptest.NewRequest(http.MethodGet, "http://test.wiki/u/alice", nil)); authenticated {
+		t.Error("expected an unsigned fetch to be refused")
+	}
+
+	r := sign(t, httptest.NewRequest(http.MethodGet, "http://test.wiki/u/alice", nil), remote.key, conversions.KeyID(bob).String(), nil, time.Now())
+	c, authenticated, err := fed.AuthenticateGet(ctx, nil, r)
+	if err != nil || !authenticated {
+		t.Fatalf("expected a signed fetch to be authenticated, got %v", err)
+	}
+	if signer, ok := ActorFromContext(c); !ok || signer.String() != bob.String() {
+		t.Errorf("expected the fetch to be signed by %s, got %s", bob, signer)
+	}
+
+	actor := createInstanceActor(t)
+
+	if _, err = fed.Dereference(ctx, remote.articleId()); err != nil {
+		t.Fatalf("failed to fetch article: %s", err)
+	}
+	if signer := remote.signer.Load(); signer != conversions.KeyID(actor).String() {
+		t.Errorf("expected the fetch to be signed by %s, got %q", conversions.KeyID(actor), signer)
+	}
+}
+
+func TestRotateKey(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	bob := remote.actorId()
+	if _, err := fed.FetchActor(ctx, bob); err != nil {
+		t.Fatalf("failed to fetch actor: %s", err)
+	}
+	err := fed.DB.AddFollow(ctx, domain.Follow{ApID: bob.JoinPath("follows", "alice"), Follower: bob, Followee: alice, Accepted: true})
+	if err != nil {
+		t.Fatalf("failed to add follower: %s", err)
+	}
+
+	// The fake server only accepts signatures by aliceKey, so every new key is that same one.
+	der, err := x509.MarshalPKIXPublicKey(&aliceKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to encode key: %s", err)
+	}
+	private, err := x509.MarshalPKCS8PrivateKey(aliceKey)
+	if err != nil {
+		t.Fatalf("failed to encode key: %s", err)
+	}
+	rotate := func(fragment string, graceUntil time.Time) *url.URL {
+		t.Helper()
+		keyId := *alice
+		keyId.Fragment = fragment
+		err := fed.DB.RotateUserKey(ctx, alice, &keyId,
+			string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private})),
+			graceUntil,
+		)
+		if err != nil {
+			t.Fatalf("failed to rotate key: %s", err)
+		}
+		return &keyId
+	}
+
+	previous := rotate("key-1", time.Now().Add(time.Hour))
+	keyId := rotate("key-2", time.Now().Add(time.Hour))
+
+	user, err := fed.DB.GetUserFed(ctx, alice)
+	if err != nil {
+		t.Fatalf("failed to get user: %s", err)
+	}
+	if user.KeyId == nil || user.KeyId.String() != keyId.String() || user.PreviousKeyId == nil || user.PreviousKeyId.String() != previous.String() {
+		t.Errorf("expected the key %s to replace %s, got %+v", keyId, previous, user)
+	}
+
+	m, err := streams.Serialize(conversions.UserToActor(user))
+	if err != nil {
+		t.Fatalf("failed to serialize actor: %s", err)
+	}
+	keys, ok := m["publicKey"].([]any)
+	if !ok || len(keys) != 2 {
+		t.Fatalf("expected the actor to publish both keys, got %v", m["publicKey"])
+	}
+	if current, _ := keys[0].(map[string]any); current["id"] != keyId.String() {
+		t.Errorf("expected the new key to come first, got %v", keys[0])
+	}
+
+	if err = fed.PublishActorUpdate(ctx, alice); err != nil {
+		t.Fatalf("failed to publish update: %s", err)
+	}
+	var activity string
+	if err := database.QueryRow("SELECT activity FROM deliveries ORDER BY id DESC LIMIT 1").Scan(&activity); err != nil {
+		t.Fatalf("expected a delivery: %s", err)
+	}
+	if !strings.Contains(activity, `"type":"Update"`) || !strings.Contains(activity, keyId.String()) {
+		t.Errorf("expected an Update carrying the new key: %s", activity)
+	}
+
+	if _, err := fed.processDeliveries(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if signer := remote.inboxSigner.Load(); signer != keyId.String() {
+		t.Errorf("expected the update to be signed with %s, got %q", keyId, signer)
+	}
+
+	// Once the grace period is over, only the new key is published.
+	rotate("key-3", time.Now().Add(-time.Second))
+	if user, err = fed.DB.GetUserFed(ctx, alice); err != nil || user.PreviousKey != "" {
+		t.Errorf("expected the expired key not to be published, got %q (%v)", user.PreviousKey, err)
+	}
+}
+
+func TestFork(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	var aliceId int64
+	if err := database.QueryRow("SELECT id FROM users WHERE ap_id = ?", alice.String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+
+	source := remote.articleId()
+	if _, err := fed.FetchArticle(ctx, source); err != nil {
+		t.Fatalf("failed to fetch article: %s", err)
+	}
+	if _, err := fed.FetchActor(ctx, remote.actorId()); err != nil {
+		t.Fatalf("failed to fetch actor: %s", err)
+	}
+	err := fed.DB.InsertForeignRevision(ctx, domain.RevisionFed{
+		ApID:    source.JoinPath("history", "1"),
+		Article: source,
+		Author:  remote.actorId(),
+		Summary: "First draft",
+		Diff:    "@@ -0,0 +1,20 @@\n+<p>Eppur si muove</p>\n",
+		Initial: true,
+		Created: time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to insert revision: %s", err)
+	}
+
+	history, err := fed.DB.ListArticleRevisions(ctx, source)
+	if err != nil || len(history) == 0 {
+		t.Fatalf("expected the revisions of the source, got %v (%v)", history, err)
+	}
+	if !history[0].Initial || history[0].Author.String() != remote.actorId().String() {
+		t.Errorf("expected the history to start with the creation of the article, got %+v", history[0])
+	}
+
+	fork := fed.Config.Url.JoinPath("a", "Dialogo sopra i due massimi sistemi")
+	_, err = fed.DB.ForkArticle(ctx, aliceId, domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{
+			Title:      "Dialogo sopra i due massimi sistemi",
+			Content:    "<p>Eppur si muove</p>",
+			Language:   "it",
+			MediaType:  "text/html",
+			ForkedFrom: source,
+		},
+		ApID: fork,
+		Url:  fork,
+	}, history, domain.Revision{Summary: "Forked from " + source.String()})
+	if err != nil {
+		t.Fatalf("failed to fork article: %s", err)
+	}
+
+	article, err := fed.DB.GetArticleFed(ctx, fork)
+	if err != nil {
+		t.Fatalf("failed to get fork: %s", err)
+	}
+	if !article.Local || article.ForkedFrom == nil || article.ForkedFrom.String() != source.String() {
+		t.Errorf("expected a local article forked from %s, got %+v", source, article)
+	}
+
+	revisions, err := fed.DB.ListArticleRevisions(ctx, fork)
+	if err != nil || len(revisions) != len(history)+1 {
+		t.Fatalf("expected %d revisions, got %d (%v)", len(history)+1, len(revisions), err)
+	}
+	if r := revisions[0]; !r.Initial || r.Author.String() != remote.actorId().String() || r.ApID.Host != fed.Config.Domain {
+		t.Errorf("expected the imported revision to keep its author under a local ID, got %+v", r)
+	}
+	if r := revisions[len(revisions)-1]; r.Author.String() != alice.String() || r.Initial {
+		t.Errorf("expected the fork revision to follow the history, got %+v", r)
+	}
+
+	m, err := streams.Serialize(conversions.ArticleToObject(article))
+	if err != nil {
+		t.Fatalf("failed to serialize article: %s", err)
+	}
+	if m[conversions.ForkedFromProperty] != source.String() {
+		t.Errorf("expected the object to carry its source, got %v", m[conversions.ForkedFromProperty])
+	}
+}
+
+func TestSharedInbox(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	user, err := fed.DB.GetUserFed(ctx, alice)
+	if err != nil {
+		t.Fatalf("failed to get user: %s", err)
+	}
+	m, err := streams.Serialize(conversions.UserToActor(user))
+	if err != nil {
+		t.Fatalf("failed to serialize actor: %s", err)
+	}
+	endpoints, _ := m["endpoints"].(map[string]any)
+	sharedInbox, _ := endpoints["sharedInbox"].(string)
+	if sharedInbox != "http://test.wiki/inbox" {
+		t.Fatalf("expected the actor to advertise the shared inbox, got %v", m["endpoints"])
+	}
+
+	bob := remote.actorId()
+	post := func(fields string) int {
+		t.Helper()
+		body := `{"@context": "https://www.w3.org/ns/activitystreams", ` + fields + `}`
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequestTo(t, sharedInbox, remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+		return w.Code
+	}
+
+	article := createArticle(t, "Il Saggiatore", "Comets")
+	code := post(fmt.Sprintf(`"id": "%s/likes/shared", "type": "Like", "actor": "%s", "object": "%s"`, bob, bob, article))
+	if code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, code)
+	}
+	if a, err := fed.DB.GetArticleFed(ctx, article); err != nil || a.Likes != 1 {
+		t.Errorf("expected the like posted to the shared inbox to be counted, got %d (%v)", a.Likes, err)
+	}
+
+	recipients := func(fields string) []*url.URL {
+		t.Helper()
+		activity, err := readActivity(ctx, strings.NewReader(`{"@context": "https://www.w3.org/ns/activitystreams", `+fields+`}`))
+		if err != nil {
+			t.Fatalf("failed to read activity: %s", err)
+		}
+		inboxes, err := fed.localRecipients(ctx, activity)
+		if err != nil {
+			t.Fatalf("failed to find recipients: %s", err)
+		}
+		return inboxes
+	}
+	note := func(to string) string {
+		return fmt.Sprintf(`"id": "%[1]s/activities/shared", "type": "Create", "actor": "%[1]s", "to": "%[2]s",
+			"object": {"id": "%[1]s/notes/shared", "type": "Note", "content": "Hello"}`, bob, to)
+	}
+
+	inbox := alice.JoinPath("inbox").String()
+	if r := recipients(note(alice.JoinPath("followers").String())); len(r) != 1 || r[0].String() != inbox {
+		t.Errorf("expected a note addressed to the followers of alice to reach her, got %v", r)
+	}
+	if r := recipients(note("https://www.w3.org/ns/activitystreams#Public")); len(r) != 0 {
+		t.Errorf("expected a public note to reach nobody, got %v", r)
+	}
+
+	follow := domain.Follow{ApID: alice.JoinPath("follows", "bob"), Follower: alice, Followee: bob, Accepted: true}
+	if err = fed.DB.AddFollow(ctx, follow); err != nil {
+		t.Fatalf("failed to add follow: %s", err)
+	}
+	if r := recipients(note("https://www.w3.org/ns/activitystreams#Public")); len(r) != 1 || r[0].String() != inbox {
+		t.Errorf("expected a public note of bob to reach his followers, got %v", r)
+	}
+	if err = fed.DB.RemoveFollow(ctx, alice, bob); err != nil {
+		t.Fatalf("failed to remove follow: %s", err)
+	}
+
+	if code := post(note("https://www.w3.org/ns/activitystreams#Public")); code != http.StatusAccepted {
+		t.Errorf("expected an activity addressed to nobody here to be accepted and ignored, got %d", code)
+	}
+
+	// An Update of an actor we store is handled even if it is only addressed to the public.
+	stored, err := fed.DB.GetForeignUserFed(ctx, bob)
+	if err != nil {
+		t.Fatalf("failed to get foreign user: %s", err)
+	}
+	renamed := stored
+	renamed.Name = "Roberto"
+	object, err := streams.Serialize(conversions.UserToActor(renamed))
+	if err != nil {
+		t.Fatalf("failed to serialize actor: %s", err)
+	}
+	delete(object, "@context")
+	encoded, _ := json.Marshal(object)
+	code = post(fmt.Sprintf(`"id": "%[1]s/updates/shared", "type": "Update", "actor": "%[1]s",
+		"to": "https://www.w3.org/ns/activitystreams#Public", "object": %[2]s`, bob, encoded))
+	if code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, code)
+	}
+	if user, err := fed.DB.GetForeignUserFed(ctx, bob); err != nil || user.Name != "Roberto" {
+		t.Errorf("expected the public Update of a stored actor to be handled, got %q (%v)", user.Name, err)
+	}
+
+	// Recipients on the same server are reached through its shared inbox, but a single one is not.
+	var inboxes []*url.URL
+	for _, u := range []string{"http://shared.example/users/carol", "http://shared.example/users/dave", "http://other.example/users/erin"} {
+		id, _ := url.Parse(u)
+		shared := &url.URL{Scheme: id.Scheme, Host: id.Host, Path: "/inbox"}
+		_, err := fed.DB.UpsertForeignUser(ctx, domain.UserFed{
+			UserCore:    domain.UserCore{Username: strings.TrimPrefix(id.Path, "/users/"), Domain: id.Host},
+			ApId:        id,
+			Inbox:       id.JoinPath("inbox"),
+			Outbox:      id.JoinPath("outbox"),
+			SharedInbox: shared,
+			PublicKey:   "key",
+		})
+		if err != nil {
+			t.Fatalf("failed to store foreign user: %s", err)
+		}
+		inboxes = append(inboxes, id.JoinPath("inbox"))
+	}
+	inboxes = append(inboxes, bob.JoinPath("inbox"))
+
+	collapsed, err := fed.collapseInboxes(ctx, inboxes)
+	if err != nil {
+		t.Fatalf("failed to collapse inboxes: %s", err)
+	}
+	var got []string
+	for _, inbox := range collapsed {
+		got = append(got, inbox.String())
+	}
+	want := []string{"http://shared.example/inbox", "http://other.example/users/erin/inbox", bob.JoinPath("inbox").String()}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expected inboxes %v, got %v", want, got)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	setup(t)
+	alice := fed.Config.Url.JoinPath("u", "alice")
+	var aliceId int64
+	if err := database.QueryRow("SELECT id FROM users WHERE ap_id = ?", alice.String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+
+	bob := remote.actorId()
+	article := fed.Config.Url.JoinPath("a", "Il Principe")
+	create := func() {
+		t.Helper()
+		_, err := fed.DB.CreateLocalArticle(ctx, aliceId, domain.ArticleFed{
+			ArticleCore: domain.ArticleCore{Title: "Il Principe", Content: "Virtù", Language: "it", MediaType: "text/plain"},
+			ApID:        article,
+		}, domain.Revision{Diff: "@@ -0,0 +1,5 @@\n+Virtù\n"})
+		if err != nil {
+			t.Fatalf("failed to create article: %s", err)
+		}
+	}
+	create()
+	createInstanceActor(t)
+	if _, err := fed.FetchActor(ctx, bob); err != nil {
+		t.Fatalf("failed to fetch actor: %s", err)
+	}
+
+	err := fed.DB.AddFollow(ctx, domain.Follow{ApID: bob.JoinPath("follows", "principe"), Follower: bob, Followee: article, Accepted: true})
+	if err != nil {
+		t.Fatalf("failed to add follower: %s", err)
+	}
+
+	inboxes, err := fed.DeleteRecipients(ctx, article)
+	if err != nil || len(inboxes) == 0 {
+		t.Fatalf("expected the follower of the article to be a recipient, got %v (%v)", inboxes, err)
+	}
+	if err = fed.DB.DeleteLocalArticle(ctx, article); err != nil {
+		t.Fatalf("failed to delete article: %s", err)
+	}
+	if _, err = fed.DB.GetArticleFed(ctx, article); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected %v for a deleted article, got %v", db.ErrNotFound, err)
+	}
+	if n, err := fed.DB.CountFollowers(ctx, article); err != nil || n != 0 {
+		t.Errorf("expected the follows of a deleted article to be removed, got %d (%v)", n, err)
+	}
+
+	if err = fed.PublishDelete(ctx, article, inboxes); err != nil {
+		t.Fatalf("failed to publish delete: %s", err)
+	}
+	var activity string
+	if err := database.QueryRow("SELECT activity FROM deliveries ORDER BY id DESC LIMIT 1").Scan(&activity); err != nil {
+		t.Fatalf("expected a delivery: %s", err)
+	}
+	for _, s := range []string{`"type":"Delete"`, `"type":"Tombstone"`, `"formerType":"Article"`, InstanceActorId(fed.Config.Url).String()} {
+		if !strings.Contains(activity, s) {
+			t.Errorf("expected the delivered activity to contain %s: %s", s, activity)
+		}
+	}
+
+	before := remote.received.Load()
+	if _, err := fed.processDeliveries(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if after := remote.received.Load(); after != before+1 {
+		t.Errorf("expected the Delete to be delivered, got %d deliveries", after-before)
+	}
+
+	// Creating the article again buries its tombstone.
+	create()
+	if _, err = fed.DB.GetTombstone(ctx, article); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected the tombstone to be removed, got %v", err)
+	}
+
+	del := func(object *url.URL) int {
+		t.Helper()
+		body := fmt.Sprintf(`{
+			"@context": "https://www.w3.org/ns/activitystreams",
+			"id": "%[1]s/deletes/%[3]d",
+			"type": "Delete",
+			"actor": "%[1]s",
+			"object": "%[2]s"
+		}`, bob, object, time.Now().UnixNano())
+		w := httptest.NewRecorder()
+		fed.PostInbox(w, signedRequest(t, remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+		return w.Code
+	}
+
+	// Foreign actors cannot delete our articles.
+	del(article)
+	if _, err = fed.DB.GetArticleFed(ctx, article); err != nil {
+		t.Errorf("expected the local article to survive a foreign Delete, got %v", err)
+	}
+
+	if _, err = fed.FetchArticle(ctx, remote.articleId()); err != nil {
+		t.Fatalf("failed to fetch article: %s", err)
+	}
+	if code := del(remote.articleId()); code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, code)
+	}
+	if _, err = fed.DB.GetArticleFed(ctx, remote.articleId()); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected the copy of the deleted article to be removed, got %v", err)
+	}
+
+	note := bob.JoinPath("notes", "principe")
+	body := fmt.Sprintf(`{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id": "%[2]s/activity",
+		"type": "Create",
+		"actor": "%[1]s",
+		"object": {"id": "%[2]s", "type": "Note", "attributedTo": "%[1]s", "inReplyTo": "%[3]s", "content": "<p>Fortuna</p>"}
+	}`, bob, note, article)
+	w := httptest.NewRecorder()
+	fed.PostInbox(w, signedRequest(t, remote.key, conversions.KeyID(bob).String(), []byte(body), time.Now()))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+
+	if code := del(bob); code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, code)
+	}
+	if tombstone, err := fed.DB.GetTombstone(ctx, bob); err != nil || tombstone.FormerType != "Person" {
+		t.Errorf("expected a Person tombstone for the deleted actor, got %+v (%v)", tombstone, err)
+	}
+	var hidden bool
+	if err := database.QueryRow("SELECT hidden FROM comments WHERE ap_id = ?", note.String()).Scan(&hidden); err != nil {
+		t.Fatalf("expected the note to be stored: %s", err)
+	}
+	if !hidden {
+		t.Error("expected the comments of the deleted actor to be hidden")
+	}
+}
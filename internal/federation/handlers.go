@@ -0,0 +1,293 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// Object returns the first object of activity: either the embedded object, in which case iri is its ID, or
+// just the IRI of the object, in which case t is nil.
+func Object(activity Activity) (t vocab.Type, iri *url.URL) {
+	p := activity.GetActivityStreamsObject()
+	if p == nil || p.Len() == 0 {
+		return
+	}
+
+	it := p.Begin()
+	if it.IsIRI() {
+		return nil, it.GetIRI()
+	}
+
+	t = it.GetType()
+	if t != nil {
+		iri = conversions.GetId(t)
+	}
+	return
+}
+
+func logActivity(activity Activity, inbox *url.URL) {
+	_, object := Object(activity)
+	ev := log.Info().Str("type", activity.GetTypeName()).Str("inbox", inbox.String())
+	if actor := ActorId(activity); actor != nil {
+		ev = ev.Str("actor", actor.String())
+	}
+	if object != nil {
+		ev = ev.Str("object", object.String())
+	}
+	ev.Msg("received activity")
+}
+
+// create stores the Notes made in reply to local articles and to their comments. Other objects, and Notes that
+// reply to nothing, are ignored.
+func (f *FedProto) create(ctx context.Context, inbox *url.URL, activity Activity) error {
+	logActivity(activity, inbox)
+	object, _ := Object(activity)
+	note, ok := object.(vocab.ActivityStreamsNote)
+	if !ok || note.GetActivityStreamsInReplyTo() == nil || note.GetActivityStreamsInReplyTo().Len() == 0 {
+		return nil
+	}
+	return f.commented(ctx, activity, note)
+}
+
+// update refreshes our copy of an actor when it announces a change to itself, such as a new public key, and of a
+// foreign article when its server announces a revision of it, and stores the edits of local articles proposed by
+// foreign users.
+func (f *FedProto) update(ctx context.Context, inbox *url.URL, activity Activity) error {
+	logActivity(activity, inbox)
+	object, iri := Object(activity)
+	if iri != nil && iri.Host == f.Config.Domain {
+		return f.proposed(ctx, activity)
+	}
+	if iri != nil && (object == nil || object.GetTypeName() == "Article") {
+		return f.revised(ctx, activity, object, iri)
+	}
+	if object == nil {
+		return nil
+	}
+
+	if _, ok := object.(conversions.Actor); !ok {
+		return nil
+	}
+
+	if iri == nil || iri.String() != ActorId(activity).String() {
+		return fmt.Errorf("%w: actors can only update themselves", ErrInvalidActivity)
+	}
+
+	user, err := conversions.ActorToUser(object)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidActivity, err)
+	}
+
+	_, err = f.DB.UpsertForeignUser(ctx, user)
+	return err
+}
+
+// revised stores the revision of a foreign article announced by an Update of its server, and refreshes our copy of
+// the article, with the Article carried by the Update or else by fetching it again. Only actors of the server of
+// the article may update it, and Updates of articles we do not keep a copy of are ignored.
+func (f *FedProto) revised(ctx context.Context, activity Activity, object vocab.Type, iri *url.URL) error {
+	if _, err := f.DB.GetArticleFed(ctx, iri); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if actor := ActorId(activity); actor == nil || actor.Host != iri.Host {
+		return fmt.Errorf("%w: %s cannot update %s", ErrInvalidActivity, actor, iri)
+	}
+
+	// An Update carrying the Article itself need not carry a patch.
+	revision, err := conversions.ActivityToRevision(activity)
+	if err != nil && object == nil {
+		return fmt.Errorf("%w: %s", ErrInvalidActivity, err)
+	}
+	if err == nil {
+		if err = f.storeRevision(ctx, revision); err != nil {
+			return err
+		}
+	}
+
+	if object == nil {
+		_, err = f.FetchArticle(ctx, iri)
+		return err
+	}
+
+	article, err := conversions.ObjectToArticle(object)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidActivity, err)
+	}
+	_, err = f.DB.UpsertForeignArticle(ctx, article)
+	return err
+}
+
+// storeRevision stores a revision of a foreign article, and its author if we do not know them yet. Revisions
+// already stored, such as those delivered twice, are skipped.
+func (f *FedProto) storeRevision(ctx context.Context, revision domain.RevisionFed) error {
+	if _, err := f.DB.GetRevisionFed(ctx, revision.ApID); !errors.Is(err, db.ErrNotFound) {
+		return err
+	}
+	if _, err := f.actor(ctx, revision.Author); err != nil {
+		return err
+	}
+	return f.DB.InsertForeignRevision(ctx, revision)
+}
+
+// delete removes our copy of a foreign article, or handles the deletion of a foreign user, when their server
+// deletes them. Objects may only be deleted by an actor of their own server, and the deletion of objects we do
+// not have is ignored.
+func (f *FedProto) delete(ctx context.Context, inbox *url.URL, activity Activity) error {
+	logActivity(activity, inbox)
+	_, iri := Object(activity)
+	if iri == nil {
+		return fmt.Errorf("%w: missing object", ErrInvalidActivity)
+	}
+
+	actor := ActorId(activity)
+	if iri.Host == f.Config.Domain || actor == nil || actor.Host != iri.Host {
+		return fmt.Errorf("%w: %s cannot delete %s", ErrInvalidActivity, actor, iri)
+	}
+
+	var err error
+	if iri.String() == actor.String() {
+		err = f.DB.DeleteForeignActor(ctx, iri)
+	} else {
+		err = f.DB.DeleteForeignArticle(ctx, iri)
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// follow records a follower of a local actor, which may be a user, an article or the instance actor, and sends
+// back an Accept; follow requests are always accepted.
+func (f *FedProto) follow(ctx context.Context, inbox *url.URL, activity Activity) error {
+	logActivity(activity, inbox)
+	_, followee := Object(activity)
+	if followee == nil {
+		return fmt.Errorf("%w: missing object", ErrInvalidActivity)
+	}
+
+	if followee.Host != f.Config.Domain {
+		return fmt.Errorf("%w: %s is not a local actor", ErrInvalidActivity, followee)
+	}
+
+	if err := f.localActor(ctx, followee); err != nil {
+		return err
+	}
+
+	follower := ActorId(activity)
+	user, err := f.actor(ctx, follower)
+	if err != nil {
+		return err
+	}
+
+	err = f.DB.AddFollow(ctx, domain.Follow{
+		ApID:     conversions.GetId(activity),
+		Follower: follower,
+		Followee: followee,
+		Accepted: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return f.Deliver(ctx, followee, f.accept(followee, activity), []*url.URL{user.Inbox})
+}
+
+// localActor returns an ErrInvalidActivity if id is not the ID of a local user, of the instance actor or of a
+// local article that has a key to sign its activities with.
+func (f *FedProto) localActor(ctx context.Context, id *url.URL) error {
+	_, err := f.DB.GetUserFed(ctx, id)
+	if !errors.Is(err, db.ErrNotFound) {
+		return err
+	}
+
+	article, err := f.DB.GetArticleFed(ctx, id)
+	if errors.Is(err, db.ErrNotFound) || (err == nil && (!article.Local || article.PublicKey == "")) {
+		return fmt.Errorf("%w: %s is not a local actor", ErrInvalidActivity, id)
+	}
+	return err
+}
+
+// unfollow removes a follower after they undo their Follow.
+func (f *FedProto) unfollow(ctx context.Context, inbox *url.URL, activity Activity) error {
+	_, followee := Object(activity)
+	if followee == nil {
+		return fmt.Errorf("%w: missing object", ErrInvalidActivity)
+	}
+
+	err := f.DB.RemoveFollow(ctx, ActorId(activity), followee)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// actor returns the stored actor with the given ID, fetching it if it is a foreign actor we do not know yet.
+func (f *FedProto) actor(ctx context.Context, id *url.URL) (domain.UserFed, error) {
+	if id.Host == f.Config.Domain {
+		return f.DB.GetUserFed(ctx, id)
+	}
+
+	user, err := f.DB.GetForeignUserFed(ctx, id)
+	if errors.Is(err, db.ErrNotFound) {
+		return f.FetchActor(ctx, id)
+	}
+	return user, err
+}
+
+// accept returns an Accept of activity by actor.
+func (f *FedProto) accept(actor *url.URL, activity Activity) vocab.ActivityStreamsAccept {
+	accept := streams.NewActivityStreamsAccept()
+
+	id := streams.NewJSONLDIdProperty()
+	id.SetIRI(newActivityId(actor))
+	accept.SetJSONLDId(id)
+
+	actorProp := streams.NewActivityStreamsActorProperty()
+	actorProp.AppendIRI(actor)
+	accept.SetActivityStreamsActor(actorProp)
+
+	object := streams.NewActivityStreamsObjectProperty()
+	object.AppendType(activity)
+	accept.SetActivityStreamsObject(object)
+
+	return accept
+}
+
+// undo passes the undone activity on to the handler registered for its type. The undone activity must be
+// embedded, and both activities must have the same actor.
+func (f *FedProto) undo(ctx context.Context, inbox *url.URL, activity Activity) error {
+	logActivity(activity, inbox)
+	object, _ := Object(activity)
+	if object == nil {
+		return fmt.Errorf("%w: undone activity must be embedded", ErrInvalidActivity)
+	}
+
+	undone, ok := object.(Activity)
+	if !ok {
+		return fmt.Errorf("%w: %s is not an activity", ErrInvalidActivity, object.GetTypeName())
+	}
+
+	if actor := ActorId(undone); actor == nil || actor.String() != ActorId(activity).String() {
+		return fmt.Errorf("%w: actors can only undo their own activities", ErrInvalidActivity)
+	}
+
+	h, ok := f.undoHandlers[undone.GetTypeName()]
+	if !ok {
+		log.Debug().Str("type", undone.GetTypeName()).Msg("ignoring undo of unsupported activity type")
+		return nil
+	}
+	return h(ctx, inbox, undone)
+}
@@ -0,0 +1,311 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+)
+
+const (
+	// MaxBodySize is the size limit of the activities we receive and of the objects we fetch.
+	MaxBodySize = 1 << 20
+	// RequestTimeout limits the time taken by our requests to other servers.
+	RequestTimeout = 30 * time.Second
+)
+
+var ErrInvalidActivity = errors.New("invalid activity")
+
+type actorKey struct{}
+
+// ActorFromContext returns the ID of the actor that signed the request being handled.
+func ActorFromContext(ctx context.Context) (*url.URL, bool) {
+	iri, ok := ctx.Value(actorKey{}).(*url.URL)
+	return iri, ok
+}
+
+// AuthenticatePostInbox verifies the HTTP signature of a request made to an inbox. If the signature is valid,
+// the returned context carries the ID of the signer, which can be retrieved with ActorFromContext. The request
+// body is read, but r.Body is replaced so it can be read again.
+func (f *FedProto) AuthenticatePostInbox(c context.Context, w http.ResponseWriter, r *http.Request) (out context.Context, authenticated bool, err error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize))
+	if err != nil {
+		return c, false, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	owner, err := f.verifyRequest(c, r, body)
+	if err != nil {
+		log.Debug().Err(err).Str("inbox", r.URL.Path).Msg("rejected inbox request")
+		return c, false, nil
+	}
+
+	return context.WithValue(c, actorKey{}, owner), true, nil
+}
+
+// PostInbox handles a POST request to the inbox of one of our actors, or to the shared inbox: it authenticates the
+// request, makes sure the signer is the actor of the activity, and passes the activity on to the handler for its
+// type. Activities posted to the shared inbox are handled once on behalf of all the local actors they are meant
+// for, and also when they concern an object we store, such as a Delete of a mirrored article addressed only to
+// the public; they are ignored otherwise.
+func (f *FedProto) PostInbox(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	inbox := f.Config.Url.JoinPath(r.URL.Path)
+	shared := inbox.String() == conversions.SharedInboxIRI(f.Config.Url).String()
+
+	if !shared {
+		if _, err := f.DB.ActorIdByInbox(ctx, inbox); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				http.Error(w, "not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	ctx, authenticated, err := f.AuthenticatePostInbox(ctx, w, r)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if !authenticated {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	activity, err := readActivity(ctx, r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	signer, _ := ActorFromContext(ctx)
+	actor := ActorId(activity)
+	if actor == nil || actor.String() != signer.String() {
+		http.Error(w, "activity actor does not match signer", http.StatusForbidden)
+		return
+	}
+
+	if shared {
+		recipients, err := f.localRecipients(ctx, activity)
+		if err != nil {
+			log.Error().Err(err).Str("actor", actor.String()).Msg("failed to find the recipients of activity")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		stored := false
+		if len(recipients) == 0 {
+			if stored, err = f.concernsStored(ctx, activity); err != nil {
+				log.Error().Err(err).Str("actor", actor.String()).Msg("failed to find the object of activity")
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if len(recipients) == 0 && !stored {
+			log.Debug().
+				Str("type", activity.GetTypeName()).
+				Str("actor", actor.String()).
+				Msg("ignoring activity that concerns no local actor nor stored object")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		log.Debug().
+			Str("type", activity.GetTypeName()).
+			Str("actor", actor.String()).
+			Int("recipients", len(recipients)).
+			Msg("handling activity received by the shared inbox")
+	}
+
+	if err = f.dispatch(ctx, inbox, activity); err != nil {
+		log.Error().
+			Err(err).
+			Str("type", activity.GetTypeName()).
+			Str("actor", actor.String()).
+			Msg("failed to handle activity")
+
+		if errors.Is(err, ErrInvalidActivity) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// localRecipients returns the inboxes of the local actors activity is meant for: those it is addressed to, either
+// directly or through their followers collections, and the followers of its actor or of the foreign actors and
+// collections it is addressed to. Activities about one of our objects that reach no other actor are meant for the
+// instance actor.
+//
+// The handlers act on the objects an activity names rather than on the inbox it was posted to, so an activity
+// received by the shared inbox is handled only once, for all of its recipients.
+func (f *FedProto) localRecipients(ctx context.Context, activity Activity) ([]*url.URL, error) {
+	var iris []*url.URL
+	if a, ok := activity.(addressing); ok {
+		iris = addressees(a)
+	}
+	iris = append(iris, ActorId(activity))
+
+	object, iri := Object(activity)
+	if iri != nil {
+		iris = append(iris, iri)
+	}
+	switch o := object.(type) {
+	case Activity:
+		// Undone activities are addressed like the activity that undoes them, but name our object themselves.
+		if _, iri := Object(o); iri != nil {
+			iris = append(iris, iri)
+		}
+	case interface {
+		GetActivityStreamsInReplyTo() vocab.ActivityStreamsInReplyToProperty
+	}:
+		if p := o.GetActivityStreamsInReplyTo(); p != nil {
+			iris = appendIRIs(iris, p.Begin(), p.End())
+		}
+	}
+
+	recipients, err := f.DB.LocalRecipients(ctx, iris)
+	if err != nil || len(recipients) > 0 {
+		return recipients, err
+	}
+
+	for _, iri := range iris {
+		if iri.Host == f.Config.Url.Host {
+			return []*url.URL{conversions.InboxIRI(InstanceActorId(f.Config.Url))}, nil
+		}
+	}
+	return nil, nil
+}
+
+// concernsStored reports whether the object of activity, or the object of the activity it undoes, is a user,
+// article, revision or file we store, as is the case of the Updates and Deletes of the foreign objects we mirror.
+func (f *FedProto) concernsStored(ctx context.Context, activity Activity) (bool, error) {
+	object, iri := Object(activity)
+	iris := []*url.URL{iri}
+	if o, ok := object.(Activity); ok {
+		_, inner := Object(o)
+		iris = append(iris, inner)
+	}
+
+	for _, iri := range iris {
+		if iri == nil {
+			continue
+		}
+		if exists, err := f.DB.ObjectExists(ctx, iri); err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+// addressing is implemented by the activities and objects that have audience targeting properties.
+type addressing interface {
+	GetActivityStreamsTo() vocab.ActivityStreamsToProperty
+	GetActivityStreamsCc() vocab.ActivityStreamsCcProperty
+	GetActivityStreamsBto() vocab.ActivityStreamsBtoProperty
+	GetActivityStreamsBcc() vocab.ActivityStreamsBccProperty
+	GetActivityStreamsAudience() vocab.ActivityStreamsAudienceProperty
+}
+
+// addressees returns the IRIs t is addressed to.
+func addressees(t addressing) (iris []*url.URL) {
+	if p := t.GetActivityStreamsTo(); p != nil {
+		iris = appendIRIs(iris, p.Begin(), p.End())
+	}
+	if p := t.GetActivityStreamsCc(); p != nil {
+		iris = appendIRIs(iris, p.Begin(), p.End())
+	}
+	if p := t.GetActivityStreamsBto(); p != nil {
+		iris = appendIRIs(iris, p.Begin(), p.End())
+	}
+	if p := t.GetActivityStreamsBcc(); p != nil {
+		iris = appendIRIs(iris, p.Begin(), p.End())
+	}
+	if p := t.GetActivityStreamsAudience(); p != nil {
+		iris = appendIRIs(iris, p.Begin(), p.End())
+	}
+	return
+}
+
+// iriIterator is the set of methods, shared by the iterators of the properties that refer to objects, that
+// appendIRIs uses.
+type iriIterator[I any] interface {
+	comparable
+	IsIRI() bool
+	GetIRI() *url.URL
+	GetType() vocab.Type
+	Next() I
+}
+
+// appendIRIs appends to iris the IRIs of the values of a property, from begin up to end, and the IDs of the
+// values that are embedded objects.
+func appendIRIs[I iriIterator[I]](iris []*url.URL, begin, end I) []*url.URL {
+	for it := begin; it != end; it = it.Next() {
+		if it.IsIRI() {
+			iris = append(iris, it.GetIRI())
+		} else if t := it.GetType(); t != nil {
+			if id := conversions.GetId(t); id != nil {
+				iris = append(iris, id)
+			}
+		}
+	}
+	return iris
+}
+
+func (f *FedProto) dispatch(ctx context.Context, inbox *url.URL, activity Activity) error {
+	h, ok := f.handlers[activity.GetTypeName()]
+	if !ok {
+		log.Debug().Str("type", activity.GetTypeName()).Msg("ignoring activity of unsupported type")
+		return nil
+	}
+	return h(ctx, inbox, activity)
+}
+
+func readActivity(ctx context.Context, body io.Reader) (Activity, error) {
+	var m map[string]any
+	if err := json.NewDecoder(body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidActivity, err)
+	}
+
+	t, err := streams.ToType(ctx, m)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidActivity, err)
+	}
+
+	activity, ok := t.(Activity)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not an activity", ErrInvalidActivity, t.GetTypeName())
+	}
+	return activity, nil
+}
+
+// ActorId returns the ID of the first actor of activity.
+func ActorId(activity Activity) *url.URL {
+	p := activity.GetActivityStreamsActor()
+	if p == nil || p.Len() == 0 {
+		return nil
+	}
+
+	it := p.Begin()
+	if it.IsIRI() {
+		return it.GetIRI()
+	}
+	if t := it.GetType(); t != nil {
+		return conversions.GetId(t)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package federation
+
+const (
+	NodeInfoPath = "/.well-known/nodeinfo"
+	// NodeInfo21Path is where the NodeInfo 2.1 document of the wiki is served.
+	NodeInfo21Path = "/nodeinfo/2.1"
+	// NodeInfoSchema identifies version 2.1 of NodeInfo, the only one we serve, both as the rel of its link
+	// in the discovery document and as the profile of its content type.
+	NodeInfoSchema      = "http://nodeinfo.diaspora.software/ns/schema/2.1"
+	NodeInfoContentType = `application/json; profile="` + NodeInfoSchema + `#"`
+
+	SoftwareName       = "gowiki"
+	SoftwareVersion    = "0.1.0"
+	SoftwareRepository = "https://github.com/sidereusnuntius/gowiki"
+)
+
+// NodeInfoLinks is the document served at NodeInfoPath, which links to the NodeInfo documents of the schema
+// versions we support.
+type NodeInfoLinks struct {
+	Links []Link `json:"links"`
+}
+
+// NodeInfo is a NodeInfo 2.1 document, which describes the software of the server, its usage and whether it
+// accepts new users, so that crawlers can list it.
+type NodeInfo struct {
+	Version           string         `json:"version"`
+	Software          Software       `json:"software"`
+	Protocols         []string       `json:"protocols"`
+	Services          Services       `json:"services"`
+	OpenRegistrations bool           `json:"openRegistrations"`
+	Usage             Usage          `json:"usage"`
+	Metadata          map[string]any `json:"metadata"`
+}
+
+type Software struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Repository string `json:"repository,omitempty"`
+	Homepage   string `json:"homepage,omitempty"`
+}
+
+// Services lists the third party sites the server can retrieve messages from or publish messages to.
+type Services struct {
+	Inbound  []string `json:"inbound"`
+	Outbound []string `json:"outbound"`
+}
+
+type Usage struct {
+	Users         UsageUsers `json:"users"`
+	LocalPosts    int64      `json:"localPosts"`
+	LocalComments int64      `json:"localComments"`
+}
+
+type UsageUsers struct {
+	Total          int64 `json:"total"`
+	ActiveMonth    int64 `json:"activeMonth"`
+	ActiveHalfyear int64 `json:"activeHalfyear"`
+}
@@ -0,0 +1,43 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
+)
+
+// ErrSuspended is returned when a request is made to or received from a suspended server.
+var ErrSuspended = errors.New("instance is suspended")
+
+// Policy returns how we federate with the server with the given hostname: the policy an admin chose for it or,
+// if none was chosen, PolicySuspend when only allowed instances are federated with and PolicyAllow otherwise.
+func (f *FedProto) Policy(ctx context.Context, host string) (domain.Policy, error) {
+	if validate.NormalizeHost(host) == validate.NormalizeHost(f.Config.Domain) {
+		return domain.PolicyAllow, nil
+	}
+
+	policy, err := f.DB.GetInstancePolicy(ctx, host)
+	if err != nil || policy != "" {
+		return policy, err
+	}
+
+	if f.Config.Allowlist {
+		return domain.PolicySuspend, nil
+	}
+	return domain.PolicyAllow, nil
+}
+
+// checkHost returns ErrSuspended if we do not federate with the server with the given hostname.
+func (f *FedProto) checkHost(ctx context.Context, host string) error {
+	policy, err := f.Policy(ctx, host)
+	if err != nil {
+		return err
+	}
+	if policy == domain.PolicySuspend {
+		return fmt.Errorf("%w: %s", ErrSuspended, host)
+	}
+	return nil
+}
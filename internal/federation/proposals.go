@@ -0,0 +1,164 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// ProposalInbox returns the inbox to which edits of a foreign article must be proposed: the inbox of the first of
+// its authors who belongs to the server hosting it. The article is refreshed in the process.
+func (f *FedProto) ProposalInbox(ctx context.Context, article *url.URL) (*url.URL, error) {
+	remote, err := f.FetchArticle(ctx, article)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, author := range remote.AttributedTo {
+		if author.Host != article.Host {
+			continue
+		}
+
+		user, err := f.FetchActor(ctx, author)
+		if err != nil {
+			return nil, err
+		}
+		if user.Inbox != nil {
+			return user.Inbox, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s has no author to review proposals", ErrFetch, article)
+}
+
+// SendProposal delivers an edit proposed by a local user to the inbox of the server hosting the article, as an
+// Update carrying the patch and the revision it was based on.
+func (f *FedProto) SendProposal(ctx context.Context, id, inbox *url.URL) error {
+	revision, err := f.DB.GetRevisionFed(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return f.Deliver(ctx, revision.Author, conversions.RevisionToActivity(revision), []*url.URL{inbox})
+}
+
+func (f *FedProto) accepted(ctx context.Context, inbox *url.URL, activity Activity) error {
+	return f.review(ctx, inbox, activity, true)
+}
+
+func (f *FedProto) rejected(ctx context.Context, inbox *url.URL, activity Activity) error {
+	return f.review(ctx, inbox, activity, false)
+}
+
+// review records the decision of the server hosting an article on an edit proposed by one of our users; when the
+// edit is accepted, our copy of the article is refreshed. Accepts and Rejects of anything else are ignored.
+func (f *FedProto) review(ctx context.Context, inbox *url.URL, activity Activity, accepted bool) error {
+	logActivity(activity, inbox)
+	_, object := Object(activity)
+	if object == nil {
+		return fmt.Errorf("%w: missing object", ErrInvalidActivity)
+	}
+	if object.Host != f.Config.Domain {
+		return nil
+	}
+
+	revision, err := f.DB.GetRevisionFed(ctx, object)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !revision.Proposal || revision.Article.Host == f.Config.Domain {
+		return nil
+	}
+
+	actor := ActorId(activity)
+	if actor.Host != revision.Article.Host {
+		return fmt.Errorf("%w: %s cannot review edits of %s", ErrInvalidActivity, actor, revision.Article)
+	}
+
+	err = f.DB.ReviewRevision(ctx, object, actor, accepted)
+	if errors.Is(err, db.ErrNotFound) {
+		// The proposal was already reviewed.
+		return nil
+	}
+	if err != nil || !accepted {
+		return err
+	}
+
+	if _, err := f.FetchArticle(ctx, revision.Article); err != nil {
+		log.Warn().Err(err).Str("article", revision.Article.String()).Msg("failed to refresh article after accepted proposal")
+	}
+	return nil
+}
+
+// proposed stores an Update of a local article carrying a patch as a proposal awaiting review. Its author is
+// fetched if we do not know them yet.
+func (f *FedProto) proposed(ctx context.Context, activity Activity) error {
+	revision, err := conversions.ActivityToRevision(activity)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidActivity, err)
+	}
+
+	if revision.Initial || revision.Author.String() != ActorId(activity).String() {
+		return fmt.Errorf("%w: actors can only propose edits of their own", ErrInvalidActivity)
+	}
+
+	article, err := f.DB.GetArticleFed(ctx, revision.Article)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return fmt.Errorf("%w: %s is not a local article", ErrInvalidActivity, revision.Article)
+		}
+		return err
+	}
+	if !article.Local {
+		return fmt.Errorf("%w: %s is not a local article", ErrInvalidActivity, revision.Article)
+	}
+
+	if _, err := f.actor(ctx, revision.Author); err != nil {
+		return err
+	}
+
+	revision.Proposal = true
+	return f.DB.InsertIncomingProposal(ctx, revision)
+}
+
+// SendReview answers the foreign author of a proposal with the decision of a local reviewer: an Accept or a
+// Reject of the Update that proposed the edit.
+func (f *FedProto) SendReview(ctx context.Context, reviewer *url.URL, proposal domain.RevisionFed, accepted bool) error {
+	author, err := f.DB.GetForeignUserFed(ctx, proposal.Author)
+	if err != nil {
+		return err
+	}
+
+	var activity interface {
+		vocab.Type
+		SetActivityStreamsActor(vocab.ActivityStreamsActorProperty)
+		SetActivityStreamsObject(vocab.ActivityStreamsObjectProperty)
+	} = streams.NewActivityStreamsReject()
+	if accepted {
+		activity = streams.NewActivityStreamsAccept()
+	}
+
+	id := streams.NewJSONLDIdProperty()
+	id.SetIRI(newActivityId(reviewer))
+	activity.SetJSONLDId(id)
+
+	actor := streams.NewActivityStreamsActorProperty()
+	actor.AppendIRI(reviewer)
+	activity.SetActivityStreamsActor(actor)
+
+	object := streams.NewActivityStreamsObjectProperty()
+	object.AppendType(conversions.RevisionToActivity(proposal))
+	activity.SetActivityStreamsObject(object)
+
+	return f.Deliver(ctx, reviewer, activity, []*url.URL{author.Inbox})
+}
@@ -0,0 +1,151 @@
+package federation
+
+import (
+	"context"
+	"net/url"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+)
+
+// PublishRevision delivers a local revision to the followers of its author, of its article and of the instance
+// actor: as the Create of the article, if it is the initial revision, or as an Update of it carrying the patch.
+func (f *FedProto) PublishRevision(ctx context.Context, id *url.URL) error {
+	revision, err := f.DB.GetRevisionFed(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Accepted proposals of foreign users are published by their reviewer, as we cannot sign for their author.
+	sender := revision.Author
+	if sender.Host != f.Config.Domain && revision.Reviewer != nil {
+		sender = revision.Reviewer
+	}
+
+	author, err := f.DB.GetUserFed(ctx, sender)
+	if err != nil {
+		return err
+	}
+
+	inboxes, err := f.DB.FollowerInboxes(ctx, sender, revision.Article, InstanceActorId(f.Config.Url))
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+
+	activity := conversions.RevisionToActivity(revision)
+	if sender != revision.Author {
+		if a, ok := activity.(interface {
+			SetActivityStreamsActor(vocab.ActivityStreamsActorProperty)
+			SetActivityStreamsAttributedTo(vocab.ActivityStreamsAttributedToProperty)
+		}); ok {
+			actor := streams.NewActivityStreamsActorProperty()
+			actor.AppendIRI(sender)
+			a.SetActivityStreamsActor(actor)
+
+			attributedTo := streams.NewActivityStreamsAttributedToProperty()
+			attributedTo.AppendIRI(revision.Author)
+			a.SetActivityStreamsAttributedTo(attributedTo)
+		}
+	}
+	if a, ok := activity.(interface {
+		SetActivityStreamsCc(vocab.ActivityStreamsCcProperty)
+	}); ok {
+		cc := streams.NewActivityStreamsCcProperty()
+		if author.Followers != nil {
+			cc.AppendIRI(author.Followers)
+		}
+		cc.AppendIRI(conversions.FollowersIRI(revision.Article))
+		a.SetActivityStreamsCc(cc)
+	}
+
+	return f.Deliver(ctx, sender, activity, inboxes)
+}
+
+// DeleteRecipients returns the inboxes a Delete of the local article with the given ID is sent to: those of the
+// followers of the article and of the instance actor. They must be read before the article is deleted, since its
+// follows are deleted with it.
+func (f *FedProto) DeleteRecipients(ctx context.Context, article *url.URL) ([]*url.URL, error) {
+	return f.DB.FollowerInboxes(ctx, article, InstanceActorId(f.Config.Url))
+}
+
+// PublishDelete delivers a Delete of a deleted local object, carrying its Tombstone, to the given inboxes. The
+// Delete is sent by the instance actor, as deleted articles lose their keys.
+func (f *FedProto) PublishDelete(ctx context.Context, id *url.URL, inboxes []*url.URL) error {
+	if len(inboxes) == 0 {
+		return nil
+	}
+
+	tombstone, err := f.DB.GetTombstone(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	actor := InstanceActorId(f.Config.Url)
+	del := streams.NewActivityStreamsDelete()
+
+	idProp := streams.NewJSONLDIdProperty()
+	idProp.SetIRI(newActivityId(actor))
+	del.SetJSONLDId(idProp)
+
+	actorProp := streams.NewActivityStreamsActorProperty()
+	actorProp.AppendIRI(actor)
+	del.SetActivityStreamsActor(actorProp)
+
+	object := streams.NewActivityStreamsObjectProperty()
+	object.AppendActivityStreamsTombstone(conversions.TombstoneToObject(tombstone))
+	del.SetActivityStreamsObject(object)
+
+	to := streams.NewActivityStreamsToProperty()
+	to.AppendIRI(conversions.PublicCollection)
+	del.SetActivityStreamsTo(to)
+
+	cc := streams.NewActivityStreamsCcProperty()
+	cc.AppendIRI(conversions.FollowersIRI(id))
+	cc.AppendIRI(conversions.FollowersIRI(actor))
+	del.SetActivityStreamsCc(cc)
+
+	return f.Deliver(ctx, actor, del, inboxes)
+}
+
+// PublishActorUpdate delivers an Update of a local user, carrying their current actor, to their followers, so
+// that their servers refresh their copy of the user, including its public key.
+func (f *FedProto) PublishActorUpdate(ctx context.Context, id *url.URL) error {
+	user, err := f.DB.GetUserFed(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	inboxes, err := f.DB.FollowerInboxes(ctx, id)
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+
+	update := streams.NewActivityStreamsUpdate()
+
+	idProp := streams.NewJSONLDIdProperty()
+	idProp.SetIRI(newActivityId(id))
+	update.SetJSONLDId(idProp)
+
+	actorProp := streams.NewActivityStreamsActorProperty()
+	actorProp.AppendIRI(id)
+	update.SetActivityStreamsActor(actorProp)
+
+	object := streams.NewActivityStreamsObjectProperty()
+	if err = object.AppendType(conversions.UserToActor(user)); err != nil {
+		return err
+	}
+	update.SetActivityStreamsObject(object)
+
+	to := streams.NewActivityStreamsToProperty()
+	to.AppendIRI(conversions.PublicCollection)
+	update.SetActivityStreamsTo(to)
+
+	if user.Followers != nil {
+		cc := streams.NewActivityStreamsCcProperty()
+		cc.AppendIRI(user.Followers)
+		update.SetActivityStreamsCc(cc)
+	}
+
+	return f.Deliver(ctx, id, update, inboxes)
+}
@@ -0,0 +1,96 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+)
+
+// like records the Like of a local article. Likes of anything else, such as revisions, are ignored.
+func (f *FedProto) like(ctx context.Context, inbox *url.URL, activity Activity) error {
+	logActivity(activity, inbox)
+	return f.react(ctx, activity, f.DB.AddLike)
+}
+
+// announce records the Announce, or boost, of a local article. Announces of anything else are ignored.
+func (f *FedProto) announce(ctx context.Context, inbox *url.URL, activity Activity) error {
+	logActivity(activity, inbox)
+	return f.react(ctx, activity, f.DB.AddAnnounce)
+}
+
+func (f *FedProto) unlike(ctx context.Context, inbox *url.URL, activity Activity) error {
+	return f.unreact(ctx, activity, f.DB.RemoveLike)
+}
+
+func (f *FedProto) unannounce(ctx context.Context, inbox *url.URL, activity Activity) error {
+	return f.unreact(ctx, activity, f.DB.RemoveAnnounce)
+}
+
+func (f *FedProto) react(ctx context.Context, activity Activity, add func(ctx context.Context, id, actor, article *url.URL) error) error {
+	id := conversions.GetId(activity)
+	if id == nil {
+		return fmt.Errorf("%w: missing id", ErrInvalidActivity)
+	}
+
+	article, ok, err := f.reactedArticle(ctx, activity)
+	if err != nil || !ok {
+		return err
+	}
+
+	actor := ActorId(activity)
+	policy, err := f.Policy(ctx, actor.Host)
+	if err != nil {
+		return err
+	}
+	if policy == domain.PolicySilence {
+		log.Debug().Str("type", activity.GetTypeName()).Str("actor", actor.String()).Msg("ignoring reaction from silenced instance")
+		return nil
+	}
+
+	if _, err := f.actor(ctx, actor); err != nil {
+		return err
+	}
+
+	return add(ctx, id, actor, article)
+}
+
+func (f *FedProto) unreact(ctx context.Context, activity Activity, remove func(ctx context.Context, actor, article *url.URL) error) error {
+	article, ok, err := f.reactedArticle(ctx, activity)
+	if err != nil || !ok {
+		return err
+	}
+
+	err = remove(ctx, ActorId(activity), article)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// reactedArticle returns the ID of the local article that is the object of activity; ok is false if the object
+// is anything else.
+func (f *FedProto) reactedArticle(ctx context.Context, activity Activity) (id *url.URL, ok bool, err error) {
+	_, id = Object(activity)
+	if id == nil {
+		return nil, false, fmt.Errorf("%w: missing object", ErrInvalidActivity)
+	}
+	if id.Host != f.Config.Domain {
+		return id, false, nil
+	}
+
+	article, err := f.DB.GetArticleFed(ctx, id)
+	if errors.Is(err, db.ErrNotFound) {
+		log.Debug().Str("type", activity.GetTypeName()).Str("object", id.String()).Msg("ignoring reaction to something other than an article")
+		return id, false, nil
+	}
+	if err != nil {
+		return id, false, err
+	}
+	return id, article.Local, nil
+}
@@ -0,0 +1,249 @@
+package federation
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-fed/httpsig"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+)
+
+// MaxClockSkew is how far the Date header of a signed request may be from our own clock.
+const MaxClockSkew = time.Hour
+
+var (
+	ErrUnsigned     = errors.New("request is not signed")
+	ErrBadSignature = errors.New("invalid signature")
+	ErrBadDigest    = errors.New("digest does not match body")
+	ErrStaleDate    = errors.New("date header missing or out of range")
+	ErrBadKey       = errors.New("invalid key")
+)
+
+// verifyRequest checks the draft-cavage HTTP signature of r, returning the ID of the actor that owns the
+// signing key. If body is not nil, the signature must cover the Digest header and the digest must match body.
+// Since actors may rotate their keys, a signature that fails against the cached key is checked again against a
+// freshly fetched one.
+func (f *FedProto) verifyRequest(ctx context.Context, r *http.Request, body []byte) (owner *url.URL, err error) {
+	if err = checkDate(r.Header.Get("Date"), time.Now()); err != nil {
+		return
+	}
+
+	verifier, err := httpsig.NewVerifier(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsigned, err)
+	}
+
+	required := []string{httpsig.RequestTarget, "host", "date"}
+	if body != nil {
+		required = append(required, "digest")
+		if err = checkDigest(r.Header.Get("Digest"), body); err != nil {
+			return
+		}
+	}
+
+	signed := signedHeaders(r.Header)
+	for _, h := range required {
+		if !slices.Contains(signed, h) {
+			return nil, fmt.Errorf("%w: %s header is not signed", ErrBadSignature, h)
+		}
+	}
+
+	keyId, err := url.Parse(verifier.KeyId())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadSignature, err)
+	}
+
+	// Requests signed by suspended servers are refused without fetching their keys.
+	if err = f.checkHost(ctx, keyId.Host); err != nil {
+		return
+	}
+
+	key, owner, err := f.publicKey(ctx, keyId, false)
+	if err != nil {
+		return
+	}
+
+	if err = verifier.Verify(key, httpsig.RSA_SHA256); err == nil {
+		return
+	}
+
+	log.Debug().Err(err).Str("key", keyId.String()).Msg("signature check failed, refetching key")
+	key, owner, err = f.publicKey(ctx, keyId, true)
+	if err != nil {
+		return
+	}
+
+	if err = verifier.Verify(key, httpsig.RSA_SHA256); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadSignature, err)
+	}
+	return
+}
+
+func checkDate(date string, now time.Time) error {
+	if date == "" {
+		return ErrStaleDate
+	}
+
+	t, err := http.ParseTime(date)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrStaleDate, err)
+	}
+
+	if skew := now.Sub(t); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return ErrStaleDate
+	}
+	return nil
+}
+
+// checkDigest verifies a RFC 3230 Digest header, which may list several digests separated by commas; every
+// digest using a supported algorithm must match body, and at least one must be present.
+func checkDigest(header string, body []byte) error {
+	var checked bool
+	for _, d := range strings.Split(header, ",") {
+		algo, value, ok := strings.Cut(strings.TrimSpace(d), "=")
+		if !ok {
+			continue
+		}
+
+		var h hash.Hash
+		switch strings.ToUpper(algo) {
+		case "SHA-256":
+			h = sha256.New()
+		case "SHA-512":
+			h = sha512.New()
+		default:
+			continue
+		}
+
+		h.Write(body)
+		if base64.StdEncoding.EncodeToString(h.Sum(nil)) != value {
+			return ErrBadDigest
+		}
+		checked = true
+	}
+
+	if !checked {
+		return ErrBadDigest
+	}
+	return nil
+}
+
+// signedHeaders returns the lowercased names of the headers covered by the request's signature.
+func signedHeaders(h http.Header) []string {
+	sig := h.Get("Signature")
+	if sig == "" {
+		sig = strings.TrimPrefix(h.Get("Authorization"), "Signature ")
+	}
+
+	for _, param := range strings.Split(sig, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && k == "headers" {
+			return strings.Fields(strings.ToLower(strings.Trim(v, `"`)))
+		}
+	}
+	// As per the specification, only the Date header is signed if the parameter is absent.
+	return []string{"date"}
+}
+
+// ParsePublicKey decodes a PEM encoded RSA public key, either in the PKIX or in the PKCS #1 format.
+func ParsePublicKey(key string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, ErrBadKey
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBadKey, err)
+		}
+		return k, nil
+	case "RSA PUBLIC KEY":
+		k, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBadKey, err)
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected block type %s", ErrBadKey, block.Type)
+	}
+}
+
+// ParsePrivateKey decodes a PEM encoded RSA private key, either in the PKCS #8 or in the PKCS #1 format.
+func ParsePrivateKey(key string) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, ErrBadKey
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBadKey, err)
+		}
+		return k, nil
+	case "RSA PRIVATE KEY":
+		k, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrBadKey, err)
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected block type %s", ErrBadKey, block.Type)
+	}
+}
+
+// signRequest sets the Date and Host headers of r and signs it with the key of actor. The signature covers the
+// request target, host and date and, if body is not nil, the digest of the body, which is added to r.
+func (f *FedProto) signRequest(ctx context.Context, r *http.Request, actor *url.URL, body []byte) error {
+	keyId, pemKey, err := f.DB.GetSigningKey(ctx, actor)
+	if err != nil {
+		return err
+	}
+	if keyId == nil {
+		keyId = conversions.KeyID(actor)
+	}
+
+	key, err := ParsePrivateKey(pemKey)
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	r.Header.Set("Host", r.URL.Host)
+
+	headers := []string{httpsig.RequestTarget, "host", "date"}
+	if body != nil {
+		headers = append(headers, "digest")
+	}
+
+	// Signers are not safe for concurrent use, so one is created for each request.
+	signer, _, err := httpsig.NewSigner(
+		[]httpsig.Algorithm{httpsig.RSA_SHA256},
+		httpsig.DigestSha256,
+		headers,
+		httpsig.Signature,
+		0,
+	)
+	if err != nil {
+		return err
+	}
+
+	return signer.SignRequest(key, keyId.String(), r, body)
+}
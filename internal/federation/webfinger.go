@@ -0,0 +1,46 @@
+package federation
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	WebfingerPath  = "/.well-known/webfinger"
+	JRDContentType = "application/jrd+json"
+	ActivityJSON   = "application/activity+json"
+
+	RelSelf        = "self"
+	RelProfilePage = "http://webfinger.net/rel/profile-page"
+)
+
+var ErrInvalidResource = errors.New("invalid webfinger resource")
+
+// JRD is a JSON Resource Descriptor, as defined by RFC 7033; it is the document returned by the WebFinger
+// endpoint, which remote servers use to find the ActivityPub ID of an account from its handle.
+type JRD struct {
+	Subject string   `json:"subject"`
+	Aliases []string `json:"aliases,omitempty"`
+	Links   []Link   `json:"links"`
+}
+
+type Link struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href,omitempty"`
+}
+
+// ParseAcct splits a resource of the form acct:name@host, where the acct: scheme and a leading @ are optional,
+// into the name and the host.
+func ParseAcct(resource string) (name, host string, err error) {
+	resource = strings.TrimPrefix(resource, "acct:")
+	resource = strings.TrimPrefix(resource, "@")
+
+	i := strings.LastIndex(resource, "@")
+	if i <= 0 || i == len(resource)-1 {
+		err = ErrInvalidResource
+		return
+	}
+
+	return resource[:i], resource[i+1:], nil
+}
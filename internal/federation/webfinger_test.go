@@ -0,0 +1,36 @@
+package federation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAcct(t *testing.T) {
+	cases := []struct {
+		Casename string
+		Resource string
+		Name     string
+		Host     string
+		Err      error
+	}{
+		{"acct uri", "acct:alice@wiki.example", "alice", "wiki.example", nil},
+		{"bare handle", "alice@wiki.example", "alice", "wiki.example", nil},
+		{"leading at sign", "@alice@wiki.example:8080", "alice", "wiki.example:8080", nil},
+		{"missing host", "acct:alice@", "", "", ErrInvalidResource},
+		{"missing name", "acct:@wiki.example", "", "", ErrInvalidResource},
+		{"no at sign", "acct:alice", "", "", ErrInvalidResource},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Casename, func(t *testing.T) {
+			name, host, err := ParseAcct(c.Resource)
+			if !errors.Is(err, c.Err) {
+				t.Fatalf("expected error %v, got %v", c.Err, err)
+			}
+
+			if name != c.Name || host != c.Host {
+				t.Errorf("expected (%s, %s), got (%s, %s)", c.Name, c.Host, name, host)
+			}
+		})
+	}
+}
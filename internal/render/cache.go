@@ -0,0 +1,76 @@
+package render
+
+import (
+	"container/list"
+	"sync"
+)
+
+// cache keeps the HTML of the most recently rendered revisions, discarding the least recently used one when full.
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[int64]*list.Element
+}
+
+type cacheEntry struct {
+	revision int64
+	// digest identifies the source the HTML was rendered from.
+	digest [32]byte
+	html   string
+}
+
+func newCache(size int) *cache {
+	return &cache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[int64]*list.Element),
+	}
+}
+
+func (c *cache) get(revision int64, digest [32]byte) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[revision]
+	if !ok {
+		return "", false
+	}
+	entry := e.Value.(*cacheEntry)
+	if entry.digest != digest {
+		return "", false
+	}
+	c.order.MoveToFront(e)
+	return entry.html, true
+}
+
+func (c *cache) put(revision int64, digest [32]byte, html string) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[revision]; ok {
+		e.Value = &cacheEntry{revision, digest, html}
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[revision] = c.order.PushFront(&cacheEntry{revision, digest, html})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).revision)
+	}
+}
+
+// clear discards every entry.
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+}
@@ -0,0 +1,221 @@
+// Package render converts the content of articles to the HTML shown to readers.
+package render
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"html"
+	"mime"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/config"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// CacheSize is the number of rendered revisions kept in memory.
+const CacheSize = 256
+
+// Renderer converts Markdown and plain text to HTML, and sanitizes all HTML, whatever its source, with an
+// allowlist of elements and attributes, so that no one who can edit an article may run scripts in the browsers of
+// its readers. The HTML of the current revisions of articles is cached.
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+	cache    *cache
+
+	mu sync.RWMutex
+	// interwiki maps the interwiki prefixes to the hostnames of the wikis they stand for.
+	interwiki map[string]string
+}
+
+func New(cacheSize int) *Renderer {
+	return &Renderer{
+		markdown: goldmark.New(
+			goldmark.WithExtensions(extension.GFM, wikiLinks{}),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+			// Authors may use HTML in Markdown, which is sanitized along with the rest of the output.
+			goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+		),
+		policy: Policy(),
+		cache:  newCache(cacheSize),
+	}
+}
+
+// Policy returns the allowlist the rendered HTML is sanitized with: text formatting, headings, lists, tables,
+// links and images, without scripts, styles, forms or embedded frames.
+func Policy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowStandardAttributes()
+	p.AllowStandardURLs()
+	// Titles are escaped like any other attribute; interwiki links have the title of the remote article in them.
+	p.AllowAttrs("href", "title").OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(wikilink( new)?|interwiki)$`)).OnElements("a")
+	p.AllowAttrs("cite").OnElements("blockquote", "q")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w-]+$`)).OnElements("code")
+	p.AllowElements(
+		"article", "aside", "figure", "figcaption", "section", "details", "summary", "h1", "h2", "h3", "h4", "h5",
+		"h6", "hgroup", "br", "div", "hr", "p", "span", "wbr", "abbr", "blockquote", "cite", "code", "dfn", "em",
+		"mark", "q", "s", "samp", "strong", "sub", "sup", "var", "b", "i", "kbd", "pre", "small", "u", "del", "ins",
+	)
+	p.AllowImages()
+	p.AllowLists()
+	p.AllowTables()
+	return p
+}
+
+// Render converts content of the given media type to sanitized HTML. Markdown and plain text are converted, and
+// their wiki links to the local articles whose titles are in missing are rendered as red links; content of any
+// other type is taken to be HTML.
+func (r *Renderer) Render(mediaType, content string, missing []string) string {
+	isMissing := make(map[string]bool, len(missing))
+	for _, title := range missing {
+		isMissing[title] = true
+	}
+	return r.render(mediaType, content, isMissing, "")
+}
+
+// RenderForeign is like Render, but for the content of an article hosted by another wiki, whose wiki links to
+// articles of its own point to the copies of them we show.
+func (r *Renderer) RenderForeign(host, mediaType, content string) string {
+	return r.render(mediaType, content, nil, host)
+}
+
+// render converts content to sanitized HTML. The wiki links of content that are not interwiki links point to the
+// articles of home, or to local articles if home is empty.
+func (r *Renderer) render(mediaType, content string, isMissing map[string]bool, home string) string {
+	switch baseType(mediaType) {
+	case config.Markdown:
+		source := []byte(content)
+		doc := r.markdown.Parser().Parse(text.NewReader(source))
+		ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if l, ok := n.(*wikiLink); ok && entering {
+				l.Target, l.Host = r.resolve(l.Target, home)
+				l.Missing = l.Host == "" && isMissing[l.Target]
+			}
+			return ast.WalkContinue, nil
+		})
+
+		var buf bytes.Buffer
+		if err := r.markdown.Renderer().Render(&buf, source, doc); err != nil {
+			log.Error().Err(err).Msg("failed to convert Markdown")
+			return r.policy.Sanitize(r.plainText(content, isMissing, home))
+		}
+		content = buf.String()
+	case config.Text:
+		content = r.plainText(content, isMissing, home)
+	}
+	return r.policy.Sanitize(content)
+}
+
+// RenderRevision is like Render, but caches the HTML of the revision with the given ID; the content and the
+// missing articles are still compared with those cached, so that HTML rendered from another content, or whose
+// red links changed color, is not served.
+func (r *Renderer) RenderRevision(revision int64, mediaType, content string, missing []string) string {
+	digest := sha256.Sum256([]byte(mediaType + "\x00" + content + "\x00" + strings.Join(missing, "\x00")))
+	if html, ok := r.cache.get(revision, digest); ok {
+		return html
+	}
+
+	html := r.Render(mediaType, content, missing)
+	r.cache.put(revision, digest, html)
+	return html
+}
+
+// Links returns the titles of the local articles linked by the wiki links of content, without duplicates. Only
+// Markdown and plain text may have wiki links.
+func (r *Renderer) Links(mediaType, content string) (titles []string) {
+	seen := make(map[string]bool)
+	r.eachLink(mediaType, content, func(title, host string) {
+		if host == "" && !seen[title] {
+			seen[title] = true
+			titles = append(titles, title)
+		}
+	})
+	return
+}
+
+// RemoteLinks is like Links, but returns the articles of other wikis linked by the interwiki links of content.
+func (r *Renderer) RemoteLinks(mediaType, content string) (links []RemoteLink) {
+	seen := make(map[RemoteLink]bool)
+	r.eachLink(mediaType, content, func(title, host string) {
+		if l := (RemoteLink{title, host}); host != "" && !seen[l] {
+			seen[l] = true
+			links = append(links, l)
+		}
+	})
+	return
+}
+
+// eachLink calls f with the title and the host, which is empty for local articles, of every wiki link of content.
+func (r *Renderer) eachLink(mediaType, content string, f func(title, host string)) {
+	switch baseType(mediaType) {
+	case config.Markdown:
+		source := []byte(content)
+		// Brackets in code are not links, which only the parser can tell.
+		doc := r.markdown.Parser().Parse(text.NewReader(source))
+		ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if l, ok := n.(*wikiLink); ok && entering {
+				f(r.resolve(l.Target, ""))
+			}
+			return ast.WalkContinue, nil
+		})
+	case config.Text:
+		for _, m := range wikiLinkPattern.FindAllStringSubmatch(content, -1) {
+			if target, _ := parseWikiLink(m); target != "" {
+				f(r.resolve(target, ""))
+			}
+		}
+	}
+}
+
+// baseType returns mediaType without its parameters.
+func baseType(mediaType string) string {
+	if t, _, err := mime.ParseMediaType(mediaType); err == nil {
+		return t
+	}
+	return mediaType
+}
+
+// plainText converts text into HTML paragraphs, which are separated by blank lines, keeping its line breaks and
+// rendering its wiki links.
+func (r *Renderer) plainText(text string, missing map[string]bool, home string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var b strings.Builder
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+
+		b.WriteString("<p>")
+		last := 0
+		for _, loc := range wikiLinkPattern.FindAllStringSubmatchIndex(paragraph, -1) {
+			target, label := parseWikiLink(submatches(paragraph, loc))
+			if target == "" {
+				continue
+			}
+			title, host := r.resolve(target, home)
+			b.WriteString(escapeLines(paragraph[last:loc[0]]))
+			b.WriteString(wikiLinkHTML(title, label, host, host == "" && missing[title]))
+			last = loc[1]
+		}
+		b.WriteString(escapeLines(paragraph[last:]))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// escapeLines escapes text, keeping its line breaks.
+func escapeLines(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>\n")
+}
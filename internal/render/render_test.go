@@ -0,0 +1,166 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sidereusnuntius/gowiki/internal/config"
+)
+
+func TestRender(t *testing.T) {
+	r := New(CacheSize)
+	r.SetInterwiki(map[string]string{"IBIS": "ibis.example"})
+
+	cases := []struct {
+		Casename  string
+		MediaType string
+		Content   string
+		// Contains and Excludes are fragments that must, and must not, be in the output.
+		Contains []string
+		Excludes []string
+		Missing  []string
+	}{
+		{
+			"markdown", config.Markdown, "# Sidereus\n\n*Nuntius* and [moons](https://example.org)\n\n| a | b |\n|---|---|\n| 1 | 2 |\n",
+			[]string{`<h1 id="sidereus">Sidereus</h1>`, "<em>Nuntius</em>", `href="https://example.org"`, "<table>"},
+			nil,
+			nil,
+		},
+		{
+			"markdown with html", config.Markdown, "Jupiter<sup>4</sup><script>alert(1)</script>\n\n<img src=x onerror=alert(1)>",
+			[]string{"<sup>4</sup>", `<img src="x">`},
+			[]string{"<script", "onerror", "alert"},
+			nil,
+		},
+		{
+			"markdown javascript link", config.Markdown + "; charset=utf-8", "[moons](javascript:alert(1))",
+			[]string{"moons"},
+			[]string{"javascript:"},
+			nil,
+		},
+		{
+			"plain text", config.Text, "Eppur si muove\n<b>not bold</b>\n\nSecond",
+			[]string{"<p>Eppur si muove<br>\n&lt;b&gt;not bold&lt;/b&gt;</p>", "<p>Second</p>"},
+			[]string{"<b>"},
+			nil,
+		},
+		{
+			"html", "text/html", `<p style="color: red" onclick="alert(1)">Comets</p><iframe src="https://example.org"></iframe>`,
+			[]string{"<p>Comets</p>"},
+			[]string{"style", "onclick", "iframe"},
+			nil,
+		},
+		{
+			"markdown wiki links", config.Markdown, "See [[Galileo  Galilei]], [[Moons of Jupiter|the moons]] and `[[code]]`.",
+			[]string{
+				`<a href="/a/Galileo%20Galilei" class="wikilink" rel="nofollow">Galileo Galilei</a>`,
+				`<a href="/a/Moons%20of%20Jupiter/edit" class="wikilink new" rel="nofollow">the moons</a>`,
+				"<code>[[code]]</code>",
+			},
+			nil,
+			[]string{"Moons of Jupiter"},
+		},
+		{
+			"plain text wiki links", config.Text, "[[Venus]] <b>and</b>\n[[Mars|<i>red</i>]]",
+			[]string{
+				`<p><a href="/a/Venus/edit" class="wikilink new" rel="nofollow">Venus</a> &lt;b&gt;and&lt;/b&gt;<br>`,
+				`<a href="/a/Mars" class="wikilink" rel="nofollow">&lt;i&gt;red&lt;/i&gt;</a></p>`,
+			},
+			[]string{"<i>"},
+			[]string{"Venus"},
+		},
+		{
+			"interwiki links", config.Markdown, "[[Io@Wiki.Example]], [[ibis:Europa|Europa]], [[e-mail: me@home]], [[Callisto@localhost:8080]] and [[nope:Titan]]",
+			[]string{
+				`<a href="/a/Io@wiki.example" class="interwiki" title="Io on wiki.example" rel="nofollow">Io@Wiki.Example</a>`,
+				`<a href="/a/Europa@ibis.example" class="interwiki" title="Europa on ibis.example" rel="nofollow">Europa</a>`,
+				`<a href="/a/e-mail:%20me@home/edit" class="wikilink new" rel="nofollow">`,
+				`<a href="/a/Callisto@localhost:8080/edit" class="wikilink new" rel="nofollow">`,
+				`<a href="/a/nope:Titan/edit" class="wikilink new" rel="nofollow">nope:Titan</a>`,
+			},
+			nil,
+			[]string{"Io", "Europa", "e-mail: me@home", "Callisto@localhost:8080", "nope:Titan"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Casename, func(t *testing.T) {
+			html := r.Render(c.MediaType, c.Content, c.Missing)
+			for _, s := range c.Contains {
+				if !strings.Contains(html, s) {
+					t.Errorf("expected %q in %q", s, html)
+				}
+			}
+			for _, s := range c.Excludes {
+				if strings.Contains(html, s) {
+					t.Errorf("expected no %q in %q", s, html)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderRevision(t *testing.T) {
+	r := New(1)
+
+	if html := r.RenderRevision(1, config.Markdown, "*one*", nil); html != "<p><em>one</em></p>\n" {
+		t.Fatalf("unexpected HTML %q", html)
+	}
+	// The cached HTML is not served for a different content.
+	if html := r.RenderRevision(1, config.Markdown, "*two*", nil); !strings.Contains(html, "two") {
+		t.Errorf("expected the new content to be rendered, got %q", html)
+	}
+
+	r.RenderRevision(2, config.Markdown, "*three*", nil)
+	if _, ok := r.cache.entries[1]; ok || r.cache.order.Len() != 1 {
+		t.Errorf("expected the least recently used revision to be evicted, got %d entries", r.cache.order.Len())
+	}
+}
+
+func TestLinks(t *testing.T) {
+	r := New(CacheSize)
+
+	links := r.Links(config.Markdown, "[[Io]], [[Europa|moon]], [[ Io ]], [[a/b]] and `[[Callisto]]`\n\n    [[Ganymede]]\n")
+	if strings.Join(links, ",") != "Io,Europa" {
+		t.Errorf("unexpected Markdown links %q", links)
+	}
+	links = r.Links(config.Text, "[[Io]]\n[[Europa|moon]] [[Io]]")
+	if strings.Join(links, ",") != "Io,Europa" {
+		t.Errorf("unexpected plain text links %q", links)
+	}
+	if links = r.Links("text/html", "[[Io]]"); links != nil {
+		t.Errorf("expected no links in HTML, got %q", links)
+	}
+}
+
+func TestRemoteLinks(t *testing.T) {
+	r := New(CacheSize)
+	r.SetInterwiki(map[string]string{"ibis": "ibis.example"})
+
+	content := "[[Io@wiki.example]], [[ibis:Io]], [[Ibis: Io|again]], [[Io]] and [[other:Io]]"
+	if links := r.Links(config.Markdown, content); strings.Join(links, ",") != "Io,other:Io" {
+		t.Errorf("unexpected local links %q", links)
+	}
+	links := r.RemoteLinks(config.Markdown, content)
+	if len(links) != 2 || links[0] != (RemoteLink{"Io", "wiki.example"}) || links[1] != (RemoteLink{"Io", "ibis.example"}) {
+		t.Errorf("unexpected remote links %v", links)
+	}
+
+	// The cached HTML is discarded when the prefixes change.
+	r.RenderRevision(1, config.Markdown, "[[ibis:Io]]", nil)
+	r.SetInterwiki(nil)
+	if html := r.RenderRevision(1, config.Markdown, "[[ibis:Io]]", nil); !strings.Contains(html, `href="/a/ibis:Io"`) {
+		t.Errorf("expected a local link once the prefix is removed, got %q", html)
+	}
+}
+
+func TestRenderForeign(t *testing.T) {
+	r := New(CacheSize)
+
+	html := r.RenderForeign("wiki.example", config.Markdown, "[[Io]] and [[Europa@ibis.example]]")
+	for _, s := range []string{`href="/a/Io@wiki.example" class="interwiki"`, `href="/a/Europa@ibis.example" class="interwiki"`} {
+		if !strings.Contains(html, s) {
+			t.Errorf("expected %q in %q", s, html)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package render
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sidereusnuntius/gowiki/internal/validate"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// wikiLinkPattern matches the links to other articles of the wiki, written as [[Title]] or [[Title|label]].
+// Titles may not span lines nor contain slashes, which would not fit in the path of the article.
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\[\]|/\n]+)(?:\|([^\[\]\n]+))?\]\]`)
+
+// KindWikiLink is the kind of the nodes of wiki links in the syntax tree of Markdown content.
+var KindWikiLink = ast.NewNodeKind("WikiLink")
+
+// wikiLink is a link to another article of the wiki, or to an article of another wiki if Host is set. Host and
+// Missing, which tells whether a local article does not exist, are set before rendering.
+type wikiLink struct {
+	ast.BaseInline
+	Target  string
+	Label   string
+	Host    string
+	Missing bool
+}
+
+func (n *wikiLink) Kind() ast.NodeKind {
+	return KindWikiLink
+}
+
+func (n *wikiLink) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Target": n.Target, "Label": n.Label, "Host": n.Host}, nil)
+}
+
+// RemoteLink is an interwiki link to the article with the given title hosted by another wiki.
+type RemoteLink struct {
+	Title string
+	Host  string
+}
+
+// SetInterwiki replaces the map of the interwiki prefixes to the hostnames of the wikis they stand for, as in
+// [[prefix:Title]]. The cached HTML, whose links may have changed, is discarded.
+func (r *Renderer) SetInterwiki(prefixes map[string]string) {
+	interwiki := make(map[string]string, len(prefixes))
+	for prefix, host := range prefixes {
+		interwiki[strings.ToLower(prefix)] = host
+	}
+
+	r.mu.Lock()
+	r.interwiki = interwiki
+	r.mu.Unlock()
+	r.cache.clear()
+}
+
+// resolve returns the title and the host of the article a wiki link points to; the host of the links that are not
+// interwiki links is home, which is empty for local articles. A target is only taken to be an interwiki link if it
+// ends with the hostname of a public server, as in [[Title@wiki.example]], or starts with a known prefix, since
+// titles may contain at signs and colons; the at sign of a title such as "me@home" is not taken for one.
+func (r *Renderer) resolve(target, home string) (title, host string) {
+	if i := strings.LastIndex(target, "@"); i > 0 && validate.Host(target[i+1:]) == nil {
+		if title = normalizeTitle(target[:i]); title != "" {
+			return title, strings.ToLower(target[i+1:])
+		}
+	}
+
+	if prefix, rest, ok := strings.Cut(target, ":"); ok {
+		r.mu.RLock()
+		host = r.interwiki[strings.ToLower(strings.TrimSpace(prefix))]
+		r.mu.RUnlock()
+		if title = normalizeTitle(rest); host != "" && title != "" {
+			return title, host
+		}
+	}
+	return target, home
+}
+
+// ArticlePath returns the path of the local article with the given title.
+func ArticlePath(title string) string {
+	return "/a/" + url.PathEscape(title)
+}
+
+// normalizeTitle collapses the spaces of a link target, as is done with the titles of articles.
+func normalizeTitle(title string) string {
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// submatches returns the submatches of wikiLinkPattern in s whose indices are in loc.
+func submatches(s string, loc []int) []string {
+	m := make([]string, 3)
+	for i := range m {
+		if loc[2*i] >= 0 {
+			m[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return m
+}
+
+// parseWikiLink returns the normalized target and the label of a wiki link matched by wikiLinkPattern, or an
+// empty target if it is not a valid link.
+func parseWikiLink(m []string) (target, label string) {
+	target = normalizeTitle(m[1])
+	label = strings.TrimSpace(m[2])
+	if label == "" {
+		label = target
+	}
+	return
+}
+
+// wikiLinkHTML renders a link to the article with the given title; links to missing articles lead to the editor,
+// where they may be created, and are styled as red links. Links to the articles of other wikis lead to our copy
+// of them, which is fetched if needed, and name the wiki in their tooltip.
+func wikiLinkHTML(title, label, host string, missing bool) string {
+	if host != "" {
+		return `<a href="` + html.EscapeString(ArticlePath(title+"@"+host)) + `" class="interwiki" title="` +
+			html.EscapeString(title+" on "+host) + `">` + html.EscapeString(label) + `</a>`
+	}
+
+	href, class := ArticlePath(title), "wikilink"
+	if missing {
+		href, class = href+"/edit", "wikilink new"
+	}
+	return `<a href="` + html.EscapeString(href) + `" class="` + class + `">` + html.EscapeString(label) + `</a>`
+}
+
+// wikiLinks is the goldmark extension that parses and renders wiki links.
+type wikiLinks struct{}
+
+func (wikiLinks) Extend(m goldmark.Markdown) {
+	// Wiki links are parsed before the standard links, which also begin with a bracket.
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(wikiLinkParser{}, 199)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(wikiLinkRenderer{}, 199)))
+}
+
+type wikiLinkParser struct{}
+
+func (wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	loc := wikiLinkPattern.FindSubmatchIndex(line)
+	if loc == nil || loc[0] != 0 {
+		return nil
+	}
+
+	target, label := parseWikiLink(submatches(string(line), loc))
+	if target == "" {
+		return nil
+	}
+
+	block.Advance(loc[1])
+	return &wikiLink{Target: target, Label: label}
+}
+
+type wikiLinkRenderer struct{}
+
+func (r wikiLinkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindWikiLink, r.render)
+}
+
+func (wikiLinkRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*wikiLink)
+		if _, err := w.WriteString(wikiLinkHTML(n.Target, n.Label, n.Host, n.Missing)); err != nil {
+			return ast.WalkStop, err
+		}
+	}
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
+)
+
+type FederationService interface {
+	// Webfinger resolves a WebFinger resource, either an acct: URI or the URL of a local user or article,
+	// returning the JRD that describes it.
+	Webfinger(ctx context.Context, resource string) (federation.JRD, error)
+	// NodeInfoLinks returns the NodeInfo discovery document, which links to NodeInfo.
+	NodeInfoLinks(ctx context.Context) federation.NodeInfoLinks
+	// NodeInfo describes the wiki, its usage and whether it is open to new users.
+	NodeInfo(ctx context.Context) (federation.NodeInfo, error)
+	// GetUserActor returns the ActivityPub actor of the local user with the given username.
+	GetUserActor(ctx context.Context, username string) (vocab.Type, error)
+	// GetArticleObject returns the ActivityStreams Article of the local article with the given title.
+	GetArticleObject(ctx context.Context, title string) (vocab.Type, error)
+	// GetRevisionActivity returns the Create or Update that carries a revision of a local article.
+	GetRevisionActivity(ctx context.Context, title, id string) (vocab.Type, error)
+	// GetCommentNote returns the Note of a comment of a local user on the discussion page of a local article.
+	GetCommentNote(ctx context.Context, title, id string) (vocab.Type, error)
+	// GetOutbox returns the outbox of a local user: the collection itself if page is 0, or one of its pages,
+	// numbered from 1.
+	GetOutbox(ctx context.Context, username string, page int) (vocab.Type, error)
+	// GetFollowers returns the followers collection of a local user, or one of its pages, like GetOutbox.
+	GetFollowers(ctx context.Context, username string, page int) (vocab.Type, error)
+	// GetLikes returns the collection of the Likes of a local article, or one of its pages, like GetOutbox.
+	GetLikes(ctx context.Context, title string, page int) (vocab.Type, error)
+	// GetShares returns the collection of the Announces of a local article, or one of its pages, like GetOutbox.
+	GetShares(ctx context.Context, title string, page int) (vocab.Type, error)
+	// GetArticleOutbox returns the outbox of a local article, which lists its revisions, or one of its pages,
+	// like GetOutbox.
+	GetArticleOutbox(ctx context.Context, title string, page int) (vocab.Type, error)
+	// GetArticleFollowers returns the followers collection of a local article, or one of its pages, like GetOutbox.
+	GetArticleFollowers(ctx context.Context, title string, page int) (vocab.Type, error)
+	// GetInstanceActor returns the Service actor that represents the wiki itself.
+	GetInstanceActor(ctx context.Context) (vocab.Type, error)
+	// GetInstanceOutbox returns the outbox of the instance actor, which lists the revisions of every local
+	// article, or one of its pages, like GetOutbox.
+	GetInstanceOutbox(ctx context.Context, page int) (vocab.Type, error)
+	// GetInstanceFollowers returns the followers collection of the instance actor, or one of its pages.
+	GetInstanceFollowers(ctx context.Context, page int) (vocab.Type, error)
+}
@@ -78,17 +78,7 @@ func (s *AppService) populateUser(username, name, summary string) (user domain.U
 	apId := s.Config.Url.JoinPath("/u/" + username)
 	_ = s.Config.Url.JoinPath("@" + username)
 
-	key, err := rsa.GenerateKey(rand.Reader, RsaKeySize)
-	if err != nil {
-		return
-	}
-
-	priv, err := privateKeyPem(key)
-	if err != nil {
-		return
-	}
-
-	pub, err := publicKeyPem(&key.PublicKey)
+	pub, priv, err := s.newKeyPair()
 	if err != nil {
 		return
 	}
@@ -114,6 +104,26 @@ func (s *AppService) populateUser(username, name, summary string) (user domain.U
 	return
 }
 
+// newKeyPair generates the PEM encoded key pair an actor signs its activities with, of the size set by the
+// configuration or of RsaKeySize bits if it sets none.
+func (s *AppService) newKeyPair() (public, private string, err error) {
+	size := s.Config.RsaKeySize
+	if size <= 0 {
+		size = RsaKeySize
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, size)
+	if err != nil {
+		return
+	}
+
+	if private, err = privateKeyPem(key); err != nil {
+		return
+	}
+	public, err = publicKeyPem(&key.PublicKey)
+	return
+}
+
 func privateKeyPem(key *rsa.PrivateKey) (string, error) {
 	der, err := x509.MarshalPKCS8PrivateKey(key)
 	if err != nil {
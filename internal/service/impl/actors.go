@@ -0,0 +1,232 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
+)
+
+func (s *AppService) Setup(ctx context.Context) error {
+	if err := s.createInstanceActor(ctx); err != nil {
+		return err
+	}
+
+	// Whether a link is an interwiki link depends on the prefixes, which are needed to extract the links.
+	if err := s.loadInterwiki(ctx); err != nil {
+		return err
+	}
+	return s.linkArticles(ctx)
+}
+
+// createInstanceActor stores the Service actor that represents the wiki, if it does not exist yet.
+func (s *AppService) createInstanceActor(ctx context.Context) error {
+	apId := federation.InstanceActorId(s.Config.Url)
+	exists, err := s.DB.UserExists(ctx, apId)
+	if err != nil || exists {
+		return err
+	}
+
+	pub, priv, err := s.newKeyPair()
+	if err != nil {
+		return err
+	}
+
+	name := s.Config.Name
+	if name == "" {
+		name = s.Config.Domain
+	}
+
+	log.Info().Str("actor", apId.String()).Msg("creating instance actor")
+	return s.DB.CreateInstanceActor(ctx, domain.UserFedInternal{
+		UserFed: domain.UserFed{
+			UserCore: domain.UserCore{
+				Username: s.Config.Domain,
+				Name:     name,
+				URL:      s.Config.Url,
+			},
+			ApId:      apId,
+			Inbox:     conversions.InboxIRI(apId),
+			Outbox:    conversions.OutboxIRI(apId),
+			Followers: conversions.FollowersIRI(apId),
+			PublicKey: pub,
+			Bot:       true,
+		},
+		PrivateKey: priv,
+	})
+}
+
+// KeyRetryInterval is how long RunKeyGeneration waits before trying again after it failed to generate a key.
+const KeyRetryInterval = time.Minute
+
+// RunKeyGeneration generates the keys of the local articles that have none until ctx is canceled: first those of
+// the articles created before articles could be followed, then those of the articles created since. Generating a
+// key pair is slow, so neither startup nor the creation of an article waits for it; an article can be followed
+// once it has its keys.
+func (s *AppService) RunKeyGeneration(ctx context.Context) {
+	for {
+		var retry <-chan time.Time
+		if err := s.createMissingArticleKeys(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to create article keys")
+			retry = time.After(KeyRetryInterval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.keysWake:
+		case <-retry:
+		}
+	}
+}
+
+// wakeKeyGeneration tells RunKeyGeneration that an article without keys was created.
+func (s *AppService) wakeKeyGeneration() {
+	select {
+	case s.keysWake <- struct{}{}:
+	default:
+	}
+}
+
+// createMissingArticleKeys generates the keys of every local article that has none. The articles whose keys
+// could not be generated are left for the next attempt.
+func (s *AppService) createMissingArticleKeys(ctx context.Context) error {
+	articles, err := s.DB.ListArticlesWithoutKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, article := range articles {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err = s.createArticleKeys(ctx, article); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", article, err))
+		}
+	}
+	if created := len(articles) - len(errs); created > 0 {
+		log.Info().Int("articles", created).Msg("created article keys")
+	}
+	return errors.Join(errs...)
+}
+
+// createArticleKeys generates the key pair a local article signs its activities with, as an actor.
+func (s *AppService) createArticleKeys(ctx context.Context, article *url.URL) error {
+	pub, priv, err := s.newKeyPair()
+	if err != nil {
+		return err
+	}
+	return s.DB.SetArticleKeys(ctx, article, pub, priv)
+}
+
+// KeyGracePeriod is how long the key replaced by a rotation is still published, so that the activities signed
+// with it before the rotation can be verified.
+const KeyGracePeriod = 7 * 24 * time.Hour
+
+// RotateKey replaces the key pair of a local user with a new one, which is given a new ID so that other servers
+// do not verify it against their cached copy of the old key, and sends an Update of the user to their followers.
+// Users may rotate their own keys, and admins those of anyone.
+func (s *AppService) RotateKey(ctx context.Context, username string, userId int64) error {
+	user, err := s.DB.GetUser(ctx, strings.ToLower(strings.TrimSpace(username)), "")
+	if err != nil {
+		return err
+	}
+	if user.ID != userId {
+		if err = s.checkAdmin(ctx, userId); err != nil {
+			return err
+		}
+	}
+
+	pub, priv, err := s.newKeyPair()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	apId := s.Config.Url.JoinPath("u", user.Username)
+	keyId := *apId
+	keyId.Fragment = "key-" + strconv.FormatInt(now.Unix(), 10)
+	if err = s.DB.RotateUserKey(ctx, apId, &keyId, pub, priv, now.Add(KeyGracePeriod)); err != nil {
+		return err
+	}
+	log.Info().Str("user", apId.String()).Str("key", keyId.String()).Int64("by", userId).Msg("rotated key")
+
+	if s.Fed != nil {
+		if err = s.Fed.PublishActorUpdate(ctx, apId); err != nil {
+			log.Error().Err(err).Str("user", apId.String()).Msg("failed to federate key rotation")
+		}
+	}
+	return nil
+}
+
+func (s *AppService) GetInstanceActor(ctx context.Context) (vocab.Type, error) {
+	actor, err := s.DB.GetUserFed(ctx, federation.InstanceActorId(s.Config.Url))
+	if err != nil {
+		return nil, err
+	}
+	return conversions.UserToActor(actor), nil
+}
+
+func (s *AppService) GetInstanceOutbox(ctx context.Context, page int) (vocab.Type, error) {
+	return s.revisionOutbox(ctx, conversions.OutboxIRI(federation.InstanceActorId(s.Config.Url)), nil, page)
+}
+
+func (s *AppService) GetInstanceFollowers(ctx context.Context, page int) (vocab.Type, error) {
+	apId := federation.InstanceActorId(s.Config.Url)
+	total, err := s.DB.CountFollowers(ctx, apId)
+	if err != nil {
+		return nil, err
+	}
+
+	id := conversions.FollowersIRI(apId)
+	if page == 0 {
+		return conversions.OrderedCollection(id, total), nil
+	}
+
+	followers, err := s.DB.ListFollowers(ctx, apId, conversions.CollectionPageSize, (page-1)*conversions.CollectionPageSize)
+	if err != nil {
+		return nil, err
+	}
+	return conversions.OrderedCollectionPage(id, page, total, conversions.IRIItems(followers)), nil
+}
+
+func (s *AppService) GetArticleOutbox(ctx context.Context, title string, page int) (vocab.Type, error) {
+	article, err := s.localArticle(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	return s.revisionOutbox(ctx, conversions.OutboxIRI(article.ApID), article.ApID, page)
+}
+
+func (s *AppService) GetArticleFollowers(ctx context.Context, title string, page int) (vocab.Type, error) {
+	return s.articleCollection(ctx, title, page, conversions.FollowersIRI, s.DB.CountFollowers, s.DB.ListFollowers)
+}
+
+// revisionOutbox returns the outbox with the given ID, which lists the revisions of a local article, or of every
+// local article if article is nil, or one of its pages.
+func (s *AppService) revisionOutbox(ctx context.Context, id, article *url.URL, page int) (vocab.Type, error) {
+	total, err := s.DB.CountLocalRevisions(ctx, article)
+	if err != nil {
+		return nil, err
+	}
+
+	if page == 0 {
+		return conversions.OrderedCollection(id, total), nil
+	}
+
+	revisions, err := s.DB.ListLocalRevisions(ctx, article, conversions.CollectionPageSize, (page-1)*conversions.CollectionPageSize)
+	if err != nil {
+		return nil, err
+	}
+	return conversions.OrderedCollectionPage(id, page, total, conversions.RevisionItems(revisions)), nil
+}
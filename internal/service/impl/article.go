@@ -3,11 +3,16 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/rs/zerolog/log"
 	"github.com/sidereusnuntius/gowiki/internal/db"
 	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/service"
 	"github.com/sidereusnuntius/gowiki/internal/validate"
 )
 
@@ -19,7 +24,17 @@ func (s *AppService) AlterArticle(ctx context.Context, title, summary, content s
 	//TODO: check if user has permission to edit the wiki and the article in question.
 	articleId, ap, prev, err := s.DB.GetLastRevisionID(ctx, title)
 	if err == nil {
-		return ap, s.DB.UpdateArticle(ctx, prev, articleId, userId, summary, content)
+		article, err := s.DB.GetLocalArticle(ctx, title)
+		if err != nil {
+			return ap, err
+		}
+
+		revision, err := s.DB.UpdateArticle(ctx, prev, articleId, userId, summary, content)
+		if err == nil {
+			s.updateLinks(ctx, ap, article.MediaType, content)
+			s.publish(ctx, revision)
+		}
+		return ap, err
 	}
 
 	if errors.Is(err, db.ErrNotFound) {
@@ -29,7 +44,26 @@ func (s *AppService) AlterArticle(ctx context.Context, title, summary, content s
 
 }
 
+// GetLocalArticle returns the local article with the given title, with its content rendered as sanitized HTML.
+// Its wiki links to articles that do not exist are rendered as red links.
 func (s *AppService) GetLocalArticle(ctx context.Context, title string) (article domain.ArticleCore, err error) {
+	article, err = s.GetLocalArticleSource(ctx, title)
+	if err != nil {
+		return
+	}
+
+	missing, err := s.DB.MissingLinks(ctx, s.Config.Url.JoinPath("a", article.Title))
+	if err != nil {
+		return
+	}
+
+	article.Content = s.Renderer.RenderRevision(article.Revision, article.MediaType, article.Content, missing)
+	return
+}
+
+// GetLocalArticleSource returns the local article with the given title, whose content is left as its authors
+// wrote it.
+func (s *AppService) GetLocalArticleSource(ctx context.Context, title string) (article domain.ArticleCore, err error) {
 	title = RemoveDuplicateSpaces(title)
 	err = validate.Title(title)
 	if err != nil {
@@ -37,9 +71,205 @@ func (s *AppService) GetLocalArticle(ctx context.Context, title string) (article
 	}
 
 	article, err = s.DB.GetLocalArticle(ctx, title)
+	switch {
+	case err == nil:
+		article.License = s.Config.License
+	case errors.Is(err, db.ErrNotFound):
+		err = s.gone(ctx, s.Config.Url.JoinPath("a", title), err)
+	}
 	return
 }
 
+// gone returns service.ErrGone if the local object with the given ID was deleted, and err otherwise.
+func (s *AppService) gone(ctx context.Context, id *url.URL, err error) error {
+	if _, tErr := s.DB.GetTombstone(ctx, id); tErr == nil {
+		return fmt.Errorf("%w: %s was deleted", service.ErrGone, id)
+	}
+	return err
+}
+
+// DeleteArticle deletes a local article. The Delete is sent by the instance actor to the followers of the article
+// and of the instance actor, which are read before the article, and its follows, are deleted. A failure to
+// federate the deletion is only logged.
+func (s *AppService) DeleteArticle(ctx context.Context, title string, userId int64) error {
+	if err := s.checkAdmin(ctx, userId); err != nil {
+		return err
+	}
+
+	article, err := s.localArticle(ctx, title)
+	if err != nil {
+		return err
+	}
+
+	var inboxes []*url.URL
+	if s.Fed != nil {
+		if inboxes, err = s.Fed.DeleteRecipients(ctx, article.ApID); err != nil {
+			return err
+		}
+	}
+
+	if err = s.DB.DeleteLocalArticle(ctx, article.ApID); err != nil {
+		return err
+	}
+	log.Info().Str("article", article.ApID.String()).Int64("user", userId).Msg("deleted article")
+
+	if s.Fed != nil {
+		if err = s.Fed.PublishDelete(ctx, article.ApID, inboxes); err != nil {
+			log.Error().Err(err).Str("article", article.ApID.String()).Msg("failed to federate deletion")
+		}
+	}
+	return nil
+}
+
+// ForeignArticleTTL is how long the copy of a foreign article is shown before being refreshed from its home server.
+const ForeignArticleTTL = 24 * time.Hour
+
+// foreignContentPolicy sanitizes the content of foreign articles, which is controlled by other servers.
+var foreignContentPolicy = bluemonday.UGCPolicy()
+
+// foreignTextPolicy is like foreignContentPolicy, but removes images; it sanitizes the content of the servers whose
+// media we reject.
+var foreignTextPolicy = func() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowStandardAttributes()
+	p.AllowStandardURLs()
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("cite").OnElements("blockquote", "q")
+	p.AllowElements(
+		"article", "aside", "figure", "figcaption", "section", "summary", "h1", "h2", "h3", "h4", "h5", "h6",
+		"hgroup", "br", "div", "hr", "p", "span", "wbr", "abbr", "acronym", "cite", "code", "dfn", "em", "mark",
+		"s", "samp", "strong", "sub", "sup", "var", "b", "i", "pre", "small", "strike", "tt", "u", "del", "ins",
+	)
+	p.AllowLists()
+	p.AllowTables()
+	return p
+}()
+
+// sanitizeForeign sanitizes content from the server with the given hostname, removing its images if we reject
+// its media or do not federate with it at all.
+func (s *AppService) sanitizeForeign(ctx context.Context, host, content string) string {
+	if s.Fed != nil && host != "" {
+		policy, err := s.Fed.Policy(ctx, host)
+		if err == nil && (policy == domain.PolicyRejectMedia || policy == domain.PolicySuspend) {
+			return foreignTextPolicy.Sanitize(content)
+		}
+	}
+	return foreignContentPolicy.Sanitize(content)
+}
+
+// GetForeignArticle returns the article with the given title hosted by another wiki, with its content sanitized.
+// Articles we have no copy of are only fetched for authenticated users, whose ID is not zero.
+func (s *AppService) GetForeignArticle(ctx context.Context, title, host string, userId int64) (article domain.ArticleFed, err error) {
+	article, err = s.foreignArticle(ctx, title, host, userId != 0)
+	if err != nil {
+		return
+	}
+
+	// Markdown and plain text are converted to HTML before the policy of the host is applied.
+	html := s.Renderer.RenderForeign(article.Host, article.MediaType, article.Content)
+	article.Content = s.sanitizeForeign(ctx, article.Host, html)
+	return
+}
+
+// Preview renders content as the HTML of an article of the given media type would be, or of the media type of new
+// articles if empty. If the articles it links to cannot be looked up, its wiki links are all rendered as links to
+// existing articles.
+func (s *AppService) Preview(ctx context.Context, mediaType, content string) string {
+	if mediaType == "" {
+		mediaType = s.Config.MediaType
+	}
+
+	missing, err := s.missingLinks(ctx, mediaType, content)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to look up the articles linked to by a preview")
+	}
+	return s.Renderer.Render(mediaType, content, missing)
+}
+
+// GetForeignArticleSource returns the article with the given title hosted by another wiki. The article is fetched
+// from its home server, whose WebFinger endpoint resolves its title, if we have no copy of it or if our copy is
+// older than ForeignArticleTTL; a stale copy is still returned if the home server cannot be reached.
+func (s *AppService) GetForeignArticleSource(ctx context.Context, title, host string) (article domain.ArticleFed, err error) {
+	return s.foreignArticle(ctx, title, host, true)
+}
+
+// foreignArticle is like GetForeignArticleSource, but only fetches an article we have no copy of if fetch is
+// true; otherwise it returns service.ErrForbidden.
+func (s *AppService) foreignArticle(ctx context.Context, title, host string, fetch bool) (article domain.ArticleFed, err error) {
+	title = RemoveDuplicateSpaces(title)
+	if err = validate.Title(title); err != nil {
+		return
+	}
+	if host, err = s.foreignHostname(host); err != nil {
+		return
+	}
+	if s.Fed == nil {
+		err = fmt.Errorf("%w: federation is disabled", service.ErrForbidden)
+		return
+	}
+
+	article, err = s.DB.GetForeignArticle(ctx, title, host)
+	switch {
+	case err == nil && time.Since(article.LastFetched) < ForeignArticleTTL:
+	case err == nil:
+		refreshed, err := s.Fed.FetchArticle(ctx, article.ApID)
+		if err != nil {
+			log.Warn().Err(err).Str("article", article.ApID.String()).Msg("failed to refresh foreign article")
+			break
+		}
+		article = refreshed
+	case errors.Is(err, db.ErrNotFound) && !fetch:
+		err = fmt.Errorf("%w: log in to fetch %s from %s", service.ErrForbidden, title, host)
+		return
+	case errors.Is(err, db.ErrNotFound):
+		var id *url.URL
+		id, err = s.Fed.Finger(ctx, strings.ReplaceAll(title, " ", "_"), host)
+		if err != nil {
+			return
+		}
+		if article, err = s.Fed.FetchArticle(ctx, id); err != nil {
+			return
+		}
+	default:
+		return
+	}
+
+	return article, nil
+}
+
+// ProposeEdit sends an edit of a foreign article to the server hosting it, which decides whether to accept it.
+// The patch is made against our copy of the article, which is the content the user edited. It returns the ID of
+// the proposal.
+func (s *AppService) ProposeEdit(ctx context.Context, title, host, summary, content string, userId int64) (*url.URL, error) {
+	article, err := s.GetForeignArticleSource(ctx, title, host)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := s.FindDiff(article.Content, content)
+	if diff == "" {
+		return nil, fmt.Errorf("%w: the edit changes nothing", service.ErrInvalidInput)
+	}
+
+	inbox, err := s.Fed.ProposalInbox(ctx, article.ApID)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.DB.InsertProposal(ctx, article.ApID, userId, RemoveDuplicateSpaces(summary), diff)
+	if err != nil {
+		return nil, err
+	}
+
+	return id, s.Fed.SendProposal(ctx, id, inbox)
+}
+
+func (s *AppService) GetForeignRevisionList(ctx context.Context, title, host string) ([]domain.Revision, error) {
+	title = RemoveDuplicateSpaces(title)
+	host = strings.ToLower(strings.TrimSpace(host))
+	return s.DB.GetForeignRevisionList(ctx, title, host)
+}
+
 func (s *AppService) CreateArticle(ctx context.Context, title, summary, content string, userId int64) (*url.URL, error) {
 	// TODO: validate title.
 	title = RemoveDuplicateSpaces(title)
@@ -68,7 +298,29 @@ func (s *AppService) CreateArticle(ctx context.Context, title, summary, content
 		Diff:     diffs,
 		Reviewed: false,
 	}
-	return article.ApID, s.DB.CreateLocalArticle(ctx, userId, article, revision)
+	id, err := s.DB.CreateLocalArticle(ctx, userId, article, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	// The article can be followed once RunKeyGeneration has generated its keys.
+	s.wakeKeyGeneration()
+
+	s.updateLinks(ctx, article.ApID, article.MediaType, content)
+	s.publish(ctx, id)
+	return article.ApID, nil
+}
+
+// publish federates a new revision. The revision is already stored, so a failure only means that other servers
+// will not learn about it, and is logged instead of returned.
+func (s *AppService) publish(ctx context.Context, revision *url.URL) {
+	if s.Fed == nil {
+		return
+	}
+
+	if err := s.Fed.PublishRevision(ctx, revision); err != nil {
+		log.Error().Err(err).Str("revision", revision.String()).Msg("failed to federate revision")
+	}
 }
 
 func (s *AppService) GetRevisionList(ctx context.Context, title string) ([]domain.Revision, error) {
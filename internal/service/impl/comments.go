@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/service"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
+)
+
+// MaxCommentLength is the maximum length of a comment, in bytes.
+const MaxCommentLength = 5000
+
+// GetDiscussion returns the comment threads of a local article. Admins may moderate them, and see the content of
+// hidden comments; for everyone else, it is removed.
+func (s *AppService) GetDiscussion(ctx context.Context, title string, userId int64) (comments []domain.Comment, moderator bool, err error) {
+	article, err := s.discussedArticle(ctx, title)
+	if err != nil {
+		return
+	}
+
+	if userId != 0 {
+		if moderator, err = s.DB.IsUserAdmin(ctx, userId); err != nil {
+			return
+		}
+	}
+
+	comments, err = s.DB.ListComments(ctx, article)
+	if err != nil {
+		return
+	}
+	s.prepareComments(ctx, comments, moderator)
+	return
+}
+
+func (s *AppService) prepareComments(ctx context.Context, comments []domain.Comment, moderator bool) {
+	for i := range comments {
+		c := &comments[i]
+		if c.Hidden && !moderator {
+			c.Content = ""
+		} else {
+			// Notes from other servers may contain any HTML.
+			c.Content = s.sanitizeForeign(ctx, c.Domain, c.Content)
+		}
+		s.prepareComments(ctx, c.Replies, moderator)
+	}
+}
+
+// PostComment publishes a comment of a local user on the discussion page of a local article, in reply to the
+// comment with the given ID, or to the article itself if replyTo is 0. The comment is plain text, where blank
+// lines separate paragraphs.
+func (s *AppService) PostComment(ctx context.Context, title, content string, replyTo, userId int64) (*url.URL, error) {
+	article, err := s.discussedArticle(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" || len(content) > MaxCommentLength {
+		return nil, fmt.Errorf("%w: comments must have between 1 and %d characters", service.ErrInvalidInput, MaxCommentLength)
+	}
+
+	id, err := s.DB.InsertLocalComment(ctx, article, userId, replyTo, textToHTML(content))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Fed != nil {
+		if err := s.Fed.PublishComment(ctx, id); err != nil {
+			log.Error().Err(err).Str("comment", id.String()).Msg("failed to federate comment")
+		}
+	}
+	return id, nil
+}
+
+// HideComment hides or reveals a comment. Only admins may moderate comments.
+func (s *AppService) HideComment(ctx context.Context, id, userId int64, hidden bool) error {
+	if err := s.checkAdmin(ctx, userId); err != nil {
+		return err
+	}
+	return s.DB.SetCommentHidden(ctx, id, hidden)
+}
+
+// DeleteComment removes a comment from our server. Only admins may moderate comments.
+func (s *AppService) DeleteComment(ctx context.Context, id, userId int64) error {
+	if err := s.checkAdmin(ctx, userId); err != nil {
+		return err
+	}
+	return s.DB.DeleteComment(ctx, id)
+}
+
+func (s *AppService) checkAdmin(ctx context.Context, userId int64) error {
+	admin, err := s.DB.IsUserAdmin(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return fmt.Errorf("%w: only admins may moderate the wiki", service.ErrForbidden)
+	}
+	return nil
+}
+
+// discussedArticle returns the ID of the local article with the given title.
+func (s *AppService) discussedArticle(ctx context.Context, title string) (*url.URL, error) {
+	title = RemoveDuplicateSpaces(title)
+	if err := validate.Title(title); err != nil {
+		return nil, err
+	}
+
+	article, err := s.DB.GetArticleFed(ctx, s.Config.Url.JoinPath("a", title))
+	if err != nil {
+		return nil, err
+	}
+	if !article.Local {
+		return nil, db.ErrNotFound
+	}
+	return article.ApID, nil
+}
+
+// textToHTML escapes plain text and turns its blank-line separated paragraphs into HTML paragraphs, keeping
+// single line breaks.
+func textToHTML(text string) string {
+	var b strings.Builder
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(p), "\n", "<br>"))
+		b.WriteString("</p>")
+	}
+	return b.String()
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/sidereusnuntius/gowiki/internal/conversions"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
+)
+
+func (s *AppService) GetUserActor(ctx context.Context, username string) (vocab.Type, error) {
+	user, err := s.localUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.URL == nil {
+		user.URL = s.Config.Url.JoinPath("@" + user.Username)
+	}
+
+	return conversions.UserToActor(user), nil
+}
+
+func (s *AppService) GetArticleObject(ctx context.Context, title string) (vocab.Type, error) {
+	title = RemoveDuplicateSpaces(title)
+	if err := validate.Title(title); err != nil {
+		return nil, err
+	}
+
+	id := s.Config.Url.JoinPath("a", title)
+	article, err := s.DB.GetArticleFed(ctx, id)
+	if errors.Is(err, db.ErrNotFound) {
+		// Deleted articles are served as their Tombstone.
+		if tombstone, tErr := s.DB.GetTombstone(ctx, id); tErr == nil {
+			return conversions.TombstoneToObject(tombstone), nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if article.Url == nil {
+		article.Url = article.ApID
+	}
+	article.License = s.Config.License
+	return conversions.ArticleToObject(article), nil
+}
+
+func (s *AppService) GetRevisionActivity(ctx context.Context, title, id string) (vocab.Type, error) {
+	title = RemoveDuplicateSpaces(title)
+	if err := validate.Title(title); err != nil {
+		return nil, err
+	}
+
+	revision, err := s.DB.GetRevisionFed(ctx, s.Config.Url.JoinPath("a", title, "history", id))
+	if err != nil {
+		return nil, err
+	}
+	return conversions.RevisionToActivity(revision), nil
+}
+
+func (s *AppService) GetOutbox(ctx context.Context, username string, page int) (vocab.Type, error) {
+	user, err := s.localUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.DB.CountUserRevisions(ctx, user.ApId)
+	if err != nil {
+		return nil, err
+	}
+
+	if page == 0 {
+		return conversions.OrderedCollection(user.Outbox, total), nil
+	}
+
+	revisions, err := s.DB.ListUserRevisions(ctx, user.ApId, conversions.CollectionPageSize, (page-1)*conversions.CollectionPageSize)
+	if err != nil {
+		return nil, err
+	}
+	return conversions.OrderedCollectionPage(user.Outbox, page, total, conversions.RevisionItems(revisions)), nil
+}
+
+func (s *AppService) GetFollowers(ctx context.Context, username string, page int) (vocab.Type, error) {
+	user, err := s.localUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.DB.CountFollowers(ctx, user.ApId)
+	if err != nil {
+		return nil, err
+	}
+
+	if page == 0 {
+		return conversions.OrderedCollection(user.Followers, total), nil
+	}
+
+	followers, err := s.DB.ListFollowers(ctx, user.ApId, conversions.CollectionPageSize, (page-1)*conversions.CollectionPageSize)
+	if err != nil {
+		return nil, err
+	}
+	return conversions.OrderedCollectionPage(user.Followers, page, total, conversions.IRIItems(followers)), nil
+}
+
+func (s *AppService) GetCommentNote(ctx context.Context, title, id string) (vocab.Type, error) {
+	title = RemoveDuplicateSpaces(title)
+	if err := validate.Title(title); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.DB.GetCommentFed(ctx, s.Config.Url.JoinPath("a", title, "discussion", id))
+	if err != nil {
+		return nil, err
+	}
+	if comment.Hidden {
+		return nil, db.ErrNotFound
+	}
+	return conversions.CommentToNote(comment), nil
+}
+
+func (s *AppService) GetLikes(ctx context.Context, title string, page int) (vocab.Type, error) {
+	return s.articleCollection(ctx, title, page, conversions.LikesIRI, s.DB.CountLikes, s.DB.ListLikes)
+}
+
+func (s *AppService) GetShares(ctx context.Context, title string, page int) (vocab.Type, error) {
+	return s.articleCollection(ctx, title, page, conversions.SharesIRI, s.DB.CountAnnounces, s.DB.ListAnnounces)
+}
+
+// articleCollection returns a collection of the activities concerning a local article, or one of its pages.
+func (s *AppService) articleCollection(
+	ctx context.Context,
+	title string,
+	page int,
+	iri func(*url.URL) *url.URL,
+	count func(context.Context, *url.URL) (int64, error),
+	list func(context.Context, *url.URL, int, int) ([]*url.URL, error),
+) (vocab.Type, error) {
+	article, err := s.localArticle(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := count(ctx, article.ApID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := iri(article.ApID)
+	if page == 0 {
+		return conversions.OrderedCollection(id, total), nil
+	}
+
+	ids, err := list(ctx, article.ApID, conversions.CollectionPageSize, (page-1)*conversions.CollectionPageSize)
+	if err != nil {
+		return nil, err
+	}
+	return conversions.OrderedCollectionPage(id, page, total, conversions.IRIItems(ids)), nil
+}
+
+// localArticle returns the local article with the given title.
+func (s *AppService) localArticle(ctx context.Context, title string) (domain.ArticleFed, error) {
+	title = RemoveDuplicateSpaces(title)
+	if err := validate.Title(title); err != nil {
+		return domain.ArticleFed{}, err
+	}
+
+	article, err := s.DB.GetArticleFed(ctx, s.Config.Url.JoinPath("a", title))
+	if err != nil {
+		return article, err
+	}
+	if !article.Local {
+		return article, db.ErrNotFound
+	}
+	return article, nil
+}
+
+func (s *AppService) localUser(ctx context.Context, username string) (domain.UserFed, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	return s.DB.GetUserFed(ctx, s.Config.Url.JoinPath("u", username))
+}
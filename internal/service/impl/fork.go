@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/service"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
+)
+
+// ForkArticle creates a local article, named newTitle or after the source if newTitle is empty, seeded with the
+// content of an article hosted by another wiki. The revisions of the source we know of are imported as the history
+// of the fork when they rebuild it from scratch; the fork itself is recorded as a revision by the user, which
+// carries whatever the history does not account for. It returns the ID of the new article.
+func (s *AppService) ForkArticle(ctx context.Context, title, host, newTitle string, userId int64) (*url.URL, error) {
+	source, err := s.GetForeignArticleSource(ctx, title, host)
+	if err != nil {
+		return nil, err
+	}
+
+	newTitle = RemoveDuplicateSpaces(newTitle)
+	if newTitle == "" {
+		newTitle = source.Title
+	}
+	if err = validate.Title(newTitle); err != nil {
+		return nil, err
+	}
+
+	switch _, err = s.DB.GetLocalArticle(ctx, newTitle); {
+	case err == nil:
+		return nil, fmt.Errorf("%w: article %s already exists", service.ErrConflict, newTitle)
+	case !errors.Is(err, db.ErrNotFound):
+		return nil, err
+	}
+
+	revisions, err := s.DB.ListArticleRevisions(ctx, source.ApID)
+	if err != nil {
+		return nil, err
+	}
+	history, text := s.replay(revisions)
+	if len(history) == 0 && len(revisions) > 0 {
+		log.Warn().Str("article", source.ApID.String()).Msg("known revisions do not rebuild the article, forking without history")
+	}
+
+	// The content was written for another server, which may not sanitize it as we do.
+	content := s.sanitizeForeign(ctx, source.Host, source.Content)
+	language := source.Language
+	if language == "" {
+		language = s.Config.Language
+	}
+
+	apId := s.Config.Url.JoinPath("a", newTitle)
+	article := domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{
+			Title:      newTitle,
+			Content:    content,
+			Language:   language,
+			MediaType:  source.MediaType,
+			ForkedFrom: source.ApID,
+		},
+		ApID: apId,
+		Url:  apId,
+	}
+	revision := domain.Revision{
+		Summary: "Forked from " + source.ApID.String(),
+		Diff:    s.FindDiff(text, content),
+	}
+
+	id, err := s.DB.ForkArticle(ctx, userId, article, history, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	s.wakeKeyGeneration()
+
+	s.updateLinks(ctx, article.ApID, article.MediaType, content)
+	s.publish(ctx, id)
+	return article.ApID, nil
+}
+
+// replay applies the patches of revisions in order, starting from an empty article, and returns them along with
+// the resulting text. The history is only usable if it starts with the creation of the article and every patch
+// applies; otherwise replay returns neither.
+func (s *AppService) replay(revisions []domain.RevisionFed) ([]domain.RevisionFed, string) {
+	if len(revisions) == 0 || !revisions[0].Initial {
+		return nil, ""
+	}
+
+	var text string
+	for _, r := range revisions {
+		patches, err := s.DMP.PatchFromText(r.Diff)
+		if err != nil {
+			return nil, ""
+		}
+
+		var applied []bool
+		text, applied = s.DMP.PatchApply(patches, text)
+		for _, ok := range applied {
+			if !ok {
+				return nil, ""
+			}
+		}
+	}
+	return revisions, text
+}
@@ -4,12 +4,15 @@ import (
 	"github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/sidereusnuntius/gowiki/internal/config"
 	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
+	"github.com/sidereusnuntius/gowiki/internal/render"
 	"github.com/sidereusnuntius/gowiki/internal/service"
 	"github.com/sidereusnuntius/gowiki/internal/state"
 	"github.com/sidereusnuntius/gowiki/internal/storage/filestore"
 )
 
 const (
+	// RsaKeySize is the size of the keys generated when the configuration does not set one.
 	RsaKeySize = 2048
 	BcryptCost = 10
 )
@@ -19,9 +22,17 @@ type AppService struct {
 	Config config.Configuration
 	DB     db.DB
 	DMP    *diffmatchpatch.DiffMatchPatch
+	Fed    *federation.FedProto
+	// Renderer converts the content of articles to sanitized HTML.
+	Renderer *render.Renderer
+	edits    *remoteEditsCache
+	// prefetches holds the articles of other wikis to be fetched because local articles link to them.
+	prefetches *prefetchQueue
+	// keysWake wakes RunKeyGeneration up when an article is created.
+	keysWake chan struct{}
 }
 
-func New(state *state.State) (service.Service, error) {
+func New(state *state.State, fed *federation.FedProto) (service.Service, error) {
 	dmp := diffmatchpatch.New()
 	store, err := filestore.New(state.Config.FsRoot)
 	return &AppService{
@@ -29,5 +40,10 @@ func New(state *state.State) (service.Service, error) {
 		Config: state.Config,
 		DB:     state.DB,
 		DMP:    dmp,
+		Fed:    fed,
+		Renderer: render.New(render.CacheSize),
+		edits:    &remoteEditsCache{entries: map[string]remoteEditsEntry{}},
+		prefetches: newPrefetchQueue(),
+		keysWake:   make(chan struct{}, 1),
 	}, err
 }
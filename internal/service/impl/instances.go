@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/service"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
+)
+
+// ListInstances returns the other servers we know of and the policy chosen for each of them. Only admins may
+// see them.
+func (s *AppService) ListInstances(ctx context.Context, userId int64) ([]domain.Instance, error) {
+	if err := s.checkAdmin(ctx, userId); err != nil {
+		return nil, err
+	}
+	return s.DB.ListInstances(ctx)
+}
+
+// SetInstancePolicy chooses how we federate with the server with the given hostname, which need not be known
+// yet; an empty policy restores the default one. Only admins may change policies.
+func (s *AppService) SetInstancePolicy(ctx context.Context, hostname string, policy domain.Policy, userId int64) error {
+	if err := s.checkAdmin(ctx, userId); err != nil {
+		return err
+	}
+
+	hostname, err := s.foreignHostname(hostname)
+	if err != nil {
+		return err
+	}
+	if policy != "" && !slices.Contains(domain.Policies, policy) {
+		return fmt.Errorf("%w: unknown policy %q", service.ErrInvalidInput, policy)
+	}
+
+	return s.DB.SetInstancePolicy(ctx, hostname, policy)
+}
+
+// interwikiPrefixPattern matches the interwiki prefixes admins may choose.
+var interwikiPrefixPattern = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+// SetInterwikiPrefix chooses the prefix of the interwiki links to the articles of the server with the given
+// hostname, which need not be known yet; an empty prefix removes it. Each prefix stands for a single server, and
+// only admins may choose them.
+func (s *AppService) SetInterwikiPrefix(ctx context.Context, hostname, prefix string, userId int64) error {
+	if err := s.checkAdmin(ctx, userId); err != nil {
+		return err
+	}
+
+	hostname, err := s.foreignHostname(hostname)
+	if err != nil {
+		return err
+	}
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix != "" && !interwikiPrefixPattern.MatchString(prefix) {
+		return fmt.Errorf("%w: invalid interwiki prefix %q", service.ErrInvalidInput, prefix)
+	}
+
+	prefixes, err := s.DB.InterwikiPrefixes(ctx)
+	if err != nil {
+		return err
+	}
+	if host, ok := prefixes[prefix]; ok && host != hostname {
+		return fmt.Errorf("%w: the interwiki prefix %q is already used by %s", service.ErrConflict, prefix, host)
+	}
+
+	if err = s.DB.SetInterwikiPrefix(ctx, hostname, prefix); err != nil {
+		return err
+	}
+	return s.loadInterwiki(ctx)
+}
+
+// foreignHostname normalizes the hostname of another server, which must not be our own, and must name a public
+// server unless Config.AllowPrivateAddresses is set.
+func (s *AppService) foreignHostname(hostname string) (string, error) {
+	hostname = validate.NormalizeHost(hostname)
+	if hostname == "" || hostname == validate.NormalizeHost(s.Config.Domain) || strings.ContainsAny(hostname, "/@ ") {
+		return "", fmt.Errorf("%w: invalid hostname %q", service.ErrInvalidInput, hostname)
+	}
+	if !s.Config.AllowPrivateAddresses {
+		if err := validate.Host(hostname); err != nil {
+			return "", fmt.Errorf("%w: %s", service.ErrInvalidInput, err)
+		}
+	}
+	return hostname, nil
+}
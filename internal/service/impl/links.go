@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/render"
+	"github.com/sidereusnuntius/gowiki/internal/validate"
+)
+
+const (
+	// PrefetchTimeout bounds the time spent fetching an article linked to by an interwiki link.
+	PrefetchTimeout = time.Minute
+	// MaxPrefetchLinks is the number of interwiki links of an article whose articles are fetched when it is saved.
+	MaxPrefetchLinks = 10
+	// PrefetchQueueSize is the number of articles waiting to be fetched; links to more are not prefetched.
+	PrefetchQueueSize = 64
+)
+
+// prefetchQueue holds the articles of other wikis waiting to be fetched by a single worker, each at most once.
+type prefetchQueue struct {
+	once    sync.Once
+	links   chan render.RemoteLink
+	mu      sync.Mutex
+	pending map[render.RemoteLink]bool
+}
+
+func newPrefetchQueue() *prefetchQueue {
+	return &prefetchQueue{
+		links:   make(chan render.RemoteLink, PrefetchQueueSize),
+		pending: map[render.RemoteLink]bool{},
+	}
+}
+
+// updateLinks stores the wiki links of a local article that was just saved. The article is already stored, so a
+// failure only leaves its red links and backlinks outdated until it is saved again, and is logged instead of
+// returned. The articles of other wikis it links to are fetched in the background, if enabled.
+func (s *AppService) updateLinks(ctx context.Context, article *url.URL, mediaType, content string) {
+	if err := s.DB.SetArticleLinks(ctx, article, s.Renderer.Links(mediaType, content)); err != nil {
+		log.Error().Err(err).Str("article", article.String()).Msg("failed to store article links")
+	}
+
+	if s.Config.PrefetchInterwiki && s.Fed != nil {
+		s.prefetch(ctx, s.Renderer.RemoteLinks(mediaType, content))
+	}
+}
+
+// prefetch queues the articles of the first MaxPrefetchLinks links to be fetched in the background. Only the
+// articles of public servers that are already in the instances table, and that are not suspended, are fetched,
+// and articles already queued or whose copies are recent enough are not fetched again.
+func (s *AppService) prefetch(ctx context.Context, links []render.RemoteLink) {
+	q := s.prefetches
+	q.once.Do(func() { go s.prefetchWorker() })
+
+	for _, l := range links[:min(len(links), MaxPrefetchLinks)] {
+		if _, err := s.foreignHostname(l.Host); err != nil {
+			continue
+		}
+		if known, err := s.DB.InstanceExists(ctx, l.Host); err != nil || !known {
+			continue
+		}
+		if policy, err := s.Fed.Policy(ctx, l.Host); err != nil || policy == domain.PolicySuspend {
+			continue
+		}
+
+		q.mu.Lock()
+		if !q.pending[l] {
+			select {
+			case q.links <- l:
+				q.pending[l] = true
+			default:
+				log.Debug().Str("title", l.Title).Str("host", l.Host).Msg("prefetch queue is full")
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+// prefetchWorker fetches the queued articles one at a time, outliving the requests that queued them.
+func (s *AppService) prefetchWorker() {
+	q := s.prefetches
+	for l := range q.links {
+		ctx, cancel := context.WithTimeout(context.Background(), PrefetchTimeout)
+		if _, err := s.GetForeignArticleSource(ctx, l.Title, l.Host); err != nil {
+			log.Warn().Err(err).Str("title", l.Title).Str("host", l.Host).Msg("failed to prefetch foreign article")
+		}
+		cancel()
+
+		q.mu.Lock()
+		delete(q.pending, l)
+		q.mu.Unlock()
+	}
+}
+
+// loadInterwiki hands the interwiki prefixes chosen by admins to the renderer.
+func (s *AppService) loadInterwiki(ctx context.Context) error {
+	prefixes, err := s.DB.InterwikiPrefixes(ctx)
+	if err != nil {
+		return err
+	}
+	s.Renderer.SetInterwiki(prefixes)
+	return nil
+}
+
+// linkArticles stores the wiki links of the local articles whose links were never stored.
+func (s *AppService) linkArticles(ctx context.Context) error {
+	articles, err := s.DB.ListUnlinkedArticles(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, article := range articles {
+		// Articles without links are stored too, so that they are not parsed again on the next start.
+		links := s.Renderer.Links(article.MediaType, article.Content)
+		if err = s.DB.SetArticleLinks(ctx, article.ApID, links); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingLinks returns the titles of the local articles linked to by content that do not exist.
+func (s *AppService) missingLinks(ctx context.Context, mediaType, content string) ([]string, error) {
+	links := s.Renderer.Links(mediaType, content)
+	existing, err := s.DB.ExistingArticles(ctx, links)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(links, func(title string) bool {
+		return slices.Contains(existing, title)
+	}), nil
+}
+
+// Backlinks returns the titles of the local articles that link to the article with the given title.
+func (s *AppService) Backlinks(ctx context.Context, title string) ([]string, error) {
+	title = RemoveDuplicateSpaces(title)
+	if err := validate.Title(title); err != nil {
+		return nil, err
+	}
+	return s.DB.Backlinks(ctx, title)
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/sidereusnuntius/gowiki/internal/federation"
+)
+
+func (s *AppService) NodeInfoLinks(ctx context.Context) federation.NodeInfoLinks {
+	return federation.NodeInfoLinks{
+		Links: []federation.Link{{
+			Rel:  federation.NodeInfoSchema,
+			Href: s.Config.Url.JoinPath(federation.NodeInfo21Path).String(),
+		}},
+	}
+}
+
+// NodeInfo counts revisions as the posts of the wiki, since they are what its users publish; the comments on
+// discussion pages are counted separately. Registrations are open only if they need neither an invitation nor
+// an admin's approval.
+func (s *AppService) NodeInfo(ctx context.Context) (info federation.NodeInfo, err error) {
+	now := time.Now()
+	stats, err := s.DB.GetStats(ctx, now.AddDate(0, 0, -30), now.AddDate(0, 0, -180))
+	if err != nil {
+		return
+	}
+
+	name := s.Config.Name
+	if name == "" {
+		name = s.Config.Domain
+	}
+
+	return federation.NodeInfo{
+		Version: "2.1",
+		Software: federation.Software{
+			Name:       federation.SoftwareName,
+			Version:    federation.SoftwareVersion,
+			Repository: federation.SoftwareRepository,
+			Homepage:   federation.SoftwareRepository,
+		},
+		Protocols: []string{"activitypub"},
+		Services: federation.Services{
+			Inbound:  []string{},
+			Outbound: []string{},
+		},
+		OpenRegistrations: !s.Config.InvitationRequired && !s.Config.ApprovalRequired,
+		Usage: federation.Usage{
+			Users: federation.UsageUsers{
+				Total:          stats.Users,
+				ActiveMonth:    stats.ActiveMonth,
+				ActiveHalfyear: stats.ActiveHalfyear,
+			},
+			LocalPosts:    stats.Revisions,
+			LocalComments: stats.Comments,
+		},
+		Metadata: map[string]any{
+			"nodeName":           name,
+			"language":           s.Config.Language,
+			"license":            s.Config.License,
+			"articles":           stats.Articles,
+			"invitationRequired": s.Config.InvitationRequired,
+			"approvalRequired":   s.Config.ApprovalRequired,
+		},
+	}, nil
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/service"
+)
+
+func (s *AppService) ListProposals(ctx context.Context, userId int64) ([]domain.Revision, error) {
+	if err := s.checkTrusted(ctx, userId); err != nil {
+		return nil, err
+	}
+	return s.DB.ListPendingProposals(ctx)
+}
+
+// ReviewProposal accepts or rejects an edit of a local article proposed by a foreign user, and informs its author
+// of the decision. An accepted proposal is applied to the current content of the article, and becomes its latest
+// revision; it conflicts with the article if any of its hunks no longer applies.
+func (s *AppService) ReviewProposal(ctx context.Context, id, userId int64, username string, accept bool) error {
+	if err := s.checkTrusted(ctx, userId); err != nil {
+		return err
+	}
+
+	apId, err := s.DB.GetPendingProposal(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	proposal, err := s.DB.GetRevisionFed(ctx, apId)
+	if err != nil {
+		return err
+	}
+
+	reviewer := s.Config.Url.JoinPath("u", username)
+	if accept {
+		article, err := s.DB.GetArticleFed(ctx, proposal.Article)
+		if err != nil {
+			return err
+		}
+
+		patches, err := s.DMP.PatchFromText(proposal.Diff)
+		if err != nil {
+			return fmt.Errorf("%w: malformed patch: %s", service.ErrInvalidInput, err)
+		}
+
+		content, applied := s.DMP.PatchApply(patches, article.Content)
+		for _, ok := range applied {
+			if !ok {
+				return fmt.Errorf("%w: the proposal no longer applies to %s", service.ErrConflict, article.Title)
+			}
+		}
+
+		revision, err := s.DB.AcceptProposal(ctx, id, userId, s.FindDiff(article.Content, content), content)
+		if err != nil {
+			return err
+		}
+		s.updateLinks(ctx, article.ApID, article.MediaType, content)
+		s.publish(ctx, revision)
+	} else if err = s.DB.ReviewRevision(ctx, apId, reviewer, false); err != nil {
+		return err
+	}
+
+	// The author is informed of the proposal they sent, under its original ID.
+	if err = s.Fed.SendReview(ctx, reviewer, proposal, accept); err != nil {
+		log.Error().Err(err).Str("proposal", apId.String()).Msg("failed to send review of proposal")
+	}
+	return nil
+}
+
+func (s *AppService) checkTrusted(ctx context.Context, userId int64) error {
+	trusted, err := s.DB.IsUserTrusted(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !trusted {
+		return fmt.Errorf("%w: only trusted users may review proposals", service.ErrForbidden)
+	}
+	return nil
+}
@@ -2,15 +2,128 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/db"
 	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/service"
 )
 
-func (s *AppService) GetUserProfile(ctx context.Context, username, domain string) (p domain.Profile, err error) {
-	username = strings.TrimSpace(username)
-	domain = strings.TrimSpace(domain)
+// ForeignUserTTL is how long our copy of a foreign user is shown before being refreshed from their server.
+const ForeignUserTTL = 24 * time.Hour
+
+// GetUserProfile returns the profile of a local user if domain is empty or our own, or of a foreign user
+// otherwise. Foreign users we do not know are resolved through the WebFinger endpoint of their server and fetched,
+// but only for authenticated users, whose ID is not zero; users whose copy is older than ForeignUserTTL are
+// refreshed, and a stale copy is still returned if their server cannot be reached. The profile of a foreign user
+// also lists the revisions in their outbox, which are fetched at most once per ForeignUserTTL.
+func (s *AppService) GetUserProfile(ctx context.Context, username, domain string, userId int64) (p domain.Profile, err error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain == s.Config.Domain {
+		domain = ""
+	}
+	if domain != "" {
+		if domain, err = s.foreignHostname(domain); err != nil {
+			return
+		}
+	}
 
 	p, err = s.DB.GetProfile(ctx, username, domain)
-	return
+	if domain == "" || s.Fed == nil {
+		return
+	}
+
+	// Foreign users deleted by their server are kept while their edits or comments are, but no longer shown.
+	if err == nil {
+		if _, tErr := s.DB.GetTombstone(ctx, p.ApId); tErr == nil {
+			return p, fmt.Errorf("%w: %s was deleted", service.ErrGone, p.ApId)
+		}
+	}
+
+	switch {
+	case err == nil && time.Since(p.LastFetched) < ForeignUserTTL:
+	case err == nil:
+		if _, err := s.Fed.FetchActor(ctx, p.ApId); err != nil {
+			log.Warn().Err(err).Str("user", p.ApId.String()).Msg("failed to refresh foreign user")
+			break
+		}
+		if p, err = s.DB.GetProfile(ctx, username, domain); err != nil {
+			return
+		}
+	case errors.Is(err, db.ErrNotFound) && userId == 0:
+		err = fmt.Errorf("%w: log in to fetch %s@%s", service.ErrForbidden, username, domain)
+		return
+	case errors.Is(err, db.ErrNotFound):
+		if strings.ContainsAny(username+domain, "/@ ") {
+			err = fmt.Errorf("%w: invalid handle %s@%s", service.ErrInvalidInput, username, domain)
+			return
+		}
+
+		id, err := s.Fed.Finger(ctx, username, domain)
+		if err != nil {
+			return p, err
+		}
+		user, err := s.Fed.FetchActor(ctx, id)
+		if err != nil {
+			return p, err
+		}
+		// The handle may belong to a server other than the one hosting the actor.
+		if p, err = s.DB.GetProfile(ctx, user.Username, user.Domain); err != nil {
+			return p, err
+		}
+	default:
+		return
+	}
+
+	if p.Outbox != nil {
+		p.RemoteEdits = s.remoteEdits(ctx, p.Outbox)
+	}
+	return p, nil
+}
+
+// remoteEditsCache keeps the revisions listed in the outboxes of foreign users, so that their profiles can be
+// shown without fetching the outbox each time.
+type remoteEditsCache struct {
+	mu      sync.Mutex
+	entries map[string]remoteEditsEntry
+}
+
+type remoteEditsEntry struct {
+	fetched   time.Time
+	revisions []domain.RevisionFed
+}
+
+// remoteEdits returns the revisions in the outbox of a foreign user, which are fetched again once the cached ones
+// are older than ForeignUserTTL. A failure to fetch them is cached as well, so that an unreachable server is not
+// asked again on every view.
+func (s *AppService) remoteEdits(ctx context.Context, outbox *url.URL) []domain.RevisionFed {
+	c := s.edits
+	c.mu.Lock()
+	e, ok := c.entries[outbox.String()]
+	c.mu.Unlock()
+	if ok && time.Since(e.fetched) < ForeignUserTTL {
+		return e.revisions
+	}
+
+	revisions, err := s.Fed.FetchRevisions(ctx, outbox)
+	if err != nil {
+		log.Warn().Err(err).Str("outbox", outbox.String()).Msg("failed to fetch revisions of foreign user")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if time.Since(e.fetched) >= ForeignUserTTL {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[outbox.String()] = remoteEditsEntry{time.Now(), revisions}
+	return revisions
 }
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
+	"github.com/sidereusnuntius/gowiki/internal/service"
+)
+
+// Webfinger resolves resource against the local users and, if no user is found, the local articles. resource
+// may be an acct: URI, such as acct:alice@wiki.example, or the URL of the user or article on this instance.
+// Articles are looked up by their title, with underscores standing for spaces.
+func (s *AppService) Webfinger(ctx context.Context, resource string) (jrd federation.JRD, err error) {
+	resource = strings.TrimSpace(resource)
+
+	var name, host string
+	if strings.HasPrefix(resource, "http://") || strings.HasPrefix(resource, "https://") {
+		var u *url.URL
+		u, err = url.Parse(resource)
+		if err != nil {
+			err = fmt.Errorf("%w: %s", service.ErrInvalidInput, err)
+			return
+		}
+
+		if u.Host != s.Config.Domain {
+			err = db.ErrNotFound
+			return
+		}
+
+		switch p := u.Path; {
+		case strings.HasPrefix(p, "/u/"):
+			return s.userJRD(ctx, strings.TrimPrefix(p, "/u/"))
+		case strings.HasPrefix(p, "/@"):
+			return s.userJRD(ctx, strings.TrimPrefix(p, "/@"))
+		case strings.HasPrefix(p, "/a/"):
+			return s.articleJRD(ctx, strings.TrimPrefix(p, "/a/"))
+		default:
+			err = db.ErrNotFound
+			return
+		}
+	}
+
+	name, host, err = federation.ParseAcct(resource)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", service.ErrInvalidInput, err)
+		return
+	}
+
+	if host != s.Config.Domain {
+		err = db.ErrNotFound
+		return
+	}
+
+	jrd, err = s.userJRD(ctx, name)
+	if err == db.ErrNotFound {
+		jrd, err = s.articleJRD(ctx, strings.ReplaceAll(name, "_", " "))
+	}
+	return
+}
+
+func (s *AppService) userJRD(ctx context.Context, username string) (jrd federation.JRD, err error) {
+	username = strings.ToLower(username)
+	user, err := s.DB.GetUserFed(ctx, s.Config.Url.JoinPath("u", username))
+	if err != nil {
+		return
+	}
+
+	profile := s.Config.Url.JoinPath("@" + user.Username).String()
+	jrd = federation.JRD{
+		Subject: "acct:" + user.Username + "@" + s.Config.Domain,
+		Aliases: []string{user.ApId.String(), profile},
+		Links: []federation.Link{
+			{
+				Rel:  federation.RelSelf,
+				Type: federation.ActivityJSON,
+				Href: user.ApId.String(),
+			},
+			{
+				Rel:  federation.RelProfilePage,
+				Type: "text/html",
+				Href: profile,
+			},
+		},
+	}
+	return
+}
+
+func (s *AppService) articleJRD(ctx context.Context, title string) (jrd federation.JRD, err error) {
+	title = RemoveDuplicateSpaces(title)
+	_, apId, _, err := s.DB.GetLastRevisionID(ctx, title)
+	if err != nil {
+		return
+	}
+
+	if apId.Host != s.Config.Domain {
+		err = db.ErrNotFound
+		return
+	}
+
+	jrd = federation.JRD{
+		Subject: "acct:" + strings.ReplaceAll(title, " ", "_") + "@" + s.Config.Domain,
+		Aliases: []string{apId.String()},
+		Links: []federation.Link{
+			{
+				Rel:  federation.RelSelf,
+				Type: federation.ActivityJSON,
+				Href: apId.String(),
+			},
+			{
+				Rel:  federation.RelProfilePage,
+				Type: "text/html",
+				Href: apId.String(),
+			},
+		},
+	}
+	return
+}
@@ -11,11 +11,19 @@ import (
 var (
 	ErrConflict = errors.New("conflict")
 	ErrInvalidInput = errors.New("invalid")
+	ErrForbidden = errors.New("forbidden")
+	// ErrGone is returned when the requested object was deleted.
+	ErrGone = errors.New("gone")
 )
 
 // Remove the use of sqlc generated and db-defined structs.
 type Service interface {
 	FileService
+	FederationService
+	// Setup prepares what the service relies on before it starts serving requests, such as the instance actor.
+	Setup(ctx context.Context) error
+	// RunKeyGeneration generates the keys that local articles need to be followed until ctx is canceled.
+	RunKeyGeneration(ctx context.Context)
 	// AuthenticateUser takes the user's identifier, which may be their username of email address, and password
 	// and verifies if these credentials are correct. If authentication fails, authenticated is false and
 	// err is nil; a non nil error indicates that an internal, unexpected error has occured.
@@ -26,8 +34,59 @@ type Service interface {
 	// AlterArticle creates the article if it does not exists; otherwise it will modify the article,
 	// recording the edit in the article's history.
 	AlterArticle(ctx context.Context, title, summary, content string, userId int64) (*url.URL, error)
+	// GetLocalArticle returns a local article with its content rendered as sanitized HTML, ready to be shown.
 	GetLocalArticle(ctx context.Context, title string) (article domain.ArticleCore, err error)
+	// GetLocalArticleSource is like GetLocalArticle, but returns the content of the article as written, as needed
+	// to edit it.
+	GetLocalArticleSource(ctx context.Context, title string) (article domain.ArticleCore, err error)
+	// Preview renders the content of an edit as it would be shown once saved.
+	Preview(ctx context.Context, mediaType, content string) string
+	// Backlinks returns the titles of the local articles that link to the article with the given title.
+	Backlinks(ctx context.Context, title string) ([]string, error)
+	// GetForeignArticle returns the article with the given title hosted by another server, fetching it if we do
+	// not have a recent copy. Articles we have no copy of are only fetched for authenticated users; userId is zero
+	// for anonymous readers.
+	GetForeignArticle(ctx context.Context, title, host string, userId int64) (article domain.ArticleFed, err error)
+	// GetForeignArticleSource is like GetForeignArticle, but returns the content of the article unsanitized, as
+	// needed to edit it.
+	GetForeignArticleSource(ctx context.Context, title, host string) (article domain.ArticleFed, err error)
+	// ProposeEdit sends an edit of a foreign article to the server hosting it for review, returning the ID of
+	// the proposal.
+	ProposeEdit(ctx context.Context, title, host, summary, content string, userId int64) (*url.URL, error)
+	// ForkArticle creates a local article from an article hosted by another wiki, importing the history we know of
+	// it, and returns the ID of the new article. The fork is named newTitle, or after its source if empty.
+	ForkArticle(ctx context.Context, title, host, newTitle string, userId int64) (*url.URL, error)
+	// GetForeignRevisionList returns the revisions we know of a foreign article, including the edits proposed
+	// by local users.
+	GetForeignRevisionList(ctx context.Context, title, host string) ([]domain.Revision, error)
+	// ListProposals returns the pending edits of local articles proposed by foreign users. Only trusted users
+	// may review them.
+	ListProposals(ctx context.Context, userId int64) ([]domain.Revision, error)
+	// ReviewProposal accepts or rejects a pending proposal, informing its author of the decision.
+	ReviewProposal(ctx context.Context, id, userId int64, username string, accept bool) error
+	// GetDiscussion returns the comment threads on the discussion page of a local article, and whether the user
+	// may moderate them; userId is 0 for anonymous readers.
+	GetDiscussion(ctx context.Context, title string, userId int64) (comments []domain.Comment, moderator bool, err error)
+	// PostComment adds a comment to the discussion of a local article, in reply to the comment with the given ID
+	// or to the article itself if replyTo is 0, and federates it as a Note.
+	PostComment(ctx context.Context, title, content string, replyTo, userId int64) (*url.URL, error)
+	HideComment(ctx context.Context, id, userId int64, hidden bool) error
+	DeleteComment(ctx context.Context, id, userId int64) error
+	// ListInstances returns the other servers we know of along with their federation policies; only admins may
+	// see them.
+	ListInstances(ctx context.Context, userId int64) ([]domain.Instance, error)
+	// SetInstancePolicy chooses how we federate with a server, or restores the default policy if policy is empty.
+	SetInstancePolicy(ctx context.Context, hostname string, policy domain.Policy, userId int64) error
+	// SetInterwikiPrefix chooses the prefix of the interwiki links to the articles of a server, as in
+	// [[prefix:Title]], or removes it if prefix is empty.
+	SetInterwikiPrefix(ctx context.Context, hostname, prefix string, userId int64) error
 	CreateArticle(ctx context.Context, title, summary, content string, userId int64) (*url.URL, error)
-	GetUserProfile(ctx context.Context, username, domain string) (p domain.Profile, err error)
+	// DeleteArticle deletes a local article, leaving a tombstone in its place, and federates its deletion. Only
+	// admins may delete articles.
+	DeleteArticle(ctx context.Context, title string, userId int64) error
+	// RotateKey replaces the key pair of a local user and federates their new public key. Users may rotate their
+	// own keys, and admins those of any user.
+	RotateKey(ctx context.Context, username string, userId int64) error
+	GetUserProfile(ctx context.Context, username, domain string, userId int64) (p domain.Profile, err error)
 	GetRevisionList(ctx context.Context, title string) ([]domain.Revision, error)
 }
@@ -0,0 +1,104 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInternalAddress is returned when a connection to an address that is not public is refused.
+var ErrInternalAddress = errors.New("not a public address")
+
+// hostnamePattern matches the domain names of public servers, which have at least two labels.
+var hostnamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// internalSuffixes are the domains that only name hosts of local networks.
+var internalSuffixes = []string{".localhost", ".local", ".internal", ".lan", ".home.arpa", ".localdomain"}
+
+// reservedPrefixes are the address ranges that are neither private nor loopback, but are not routed on the
+// internet either: "this network", shared address space, IETF protocol assignments, benchmarking, the reserved
+// class E and the IPv6 to IPv4 translation prefix, which may embed any IPv4 address.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// Host checks that host, with an optional port, names a public server: either a domain name outside of the
+// domains of local networks, or an address accepted by PublicAddr. It keeps our requests to the servers named by
+// users or other servers from reaching the services of our own network.
+func Host(host string) error {
+	name := host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return fmt.Errorf("invalid port in host %q", host)
+		}
+		name = h
+	}
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(name, "["), "]"))
+
+	if addr, err := netip.ParseAddr(name); err == nil {
+		if !PublicAddr(addr) {
+			return fmt.Errorf("%s is not a public address", name)
+		}
+		return nil
+	}
+
+	if !hostnamePattern.MatchString(name) {
+		return fmt.Errorf("invalid hostname %q", name)
+	}
+	for _, suffix := range internalSuffixes {
+		if strings.HasSuffix("."+name, suffix) {
+			return fmt.Errorf("%s is not a public hostname", name)
+		}
+	}
+	return nil
+}
+
+// NormalizeHost returns the form of host, with an optional port, under which what we know of a server is stored
+// and looked up: in lower case, in its ASCII form, without a trailing dot and without the default ports of HTTP
+// and HTTPS. Otherwise "Wiki.Example" or "wiki.example:443" would escape the policy chosen for "wiki.example".
+func NormalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, ""
+	}
+
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if ascii, err := idna.ToASCII(name); err == nil {
+		name = ascii
+	}
+
+	if port != "" && port != "80" && port != "443" {
+		return net.JoinHostPort(name, port)
+	}
+	if strings.Contains(name, ":") && !strings.HasPrefix(name, "[") {
+		return "[" + name + "]"
+	}
+	return name
+}
+
+// PublicAddr reports whether addr may be the address of a public server, that is, whether it is a global unicast
+// address that is neither loopback, private, link-local nor otherwise reserved.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package validate
+
+import "testing"
+
+func TestHost(t *testing.T) {
+	for _, host := range []string{"wiki.example", "Wiki.Example:8443", "93.184.216.34", "[2606:2800:220:1::1]:443"} {
+		if err := Host(host); err != nil {
+			t.Errorf("expected %s to be public, got %s", host, err)
+		}
+	}
+
+	for _, host := range []string{
+		"", "localhost", "localhost:8080", "wiki", "db.internal", "printer.local", "wiki.example:0", "a b.example",
+		"127.0.0.1:8080", "10.0.0.5:8080", "192.168.1.1", "169.254.169.254", "0.0.0.0", "100.64.0.1", "[::1]:6379",
+		"[fe80::1]", "[fd00::1]", "[::ffff:127.0.0.1]", "[64:ff9b::a00:5]",
+	} {
+		if err := Host(host); err == nil {
+			t.Errorf("expected %q to be rejected", host)
+		}
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	for host, want := range map[string]string{
+		"wiki.example":        "wiki.example",
+		"Wiki.EXAMPLE":        "wiki.example",
+		"wiki.example.":       "wiki.example",
+		"wiki.example:443":    "wiki.example",
+		"WIKI.example:80":     "wiki.example",
+		"wiki.example:8443":   "wiki.example:8443",
+		"Bücher.Example":      "xn--bcher-kva.example",
+		"[2606:2800::1]:443":  "[2606:2800::1]",
+		"[2606:2800::1]:8080": "[2606:2800::1]:8080",
+		"localhost:8080":      "localhost:8080",
+	} {
+		if got := NormalizeHost(host); got != want {
+			t.Errorf("expected %q to be normalized to %q, got %q", host, want, got)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code.superseriousbusiness.org/activity/streams"
+	"code.superseriousbusiness.org/activity/streams/vocab"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
+)
+
+const activityStreamsProfile = "https://www.w3.org/ns/activitystreams"
+
+// IsActivityPubRequest reports whether the client asked for an ActivityStreams document, through either the
+// application/activity+json or the application/ld+json media types, instead of an HTML page.
+func IsActivityPubRequest(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case federation.ActivityJSON:
+			return true
+		case "application/ld+json":
+			profile, ok := params["profile"]
+			if !ok || strings.Contains(profile, activityStreamsProfile) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteActivity serializes t and writes it to w as an ActivityStreams document. Tombstones, which stand for
+// deleted objects, are served with 410 Gone.
+func WriteActivity(w http.ResponseWriter, t vocab.Type) {
+	m, err := streams.Serialize(t)
+	if err != nil {
+		log.Error().Err(err).Str("type", t.GetTypeName()).Msg("failed to serialize activitystreams object")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(m)
+	if err != nil {
+		log.Error().Err(err).Str("type", t.GetTypeName()).Msg("failed to encode activitystreams object")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", federation.ActivityJSON)
+	w.Header().Set("Vary", "Accept")
+	if t.GetTypeName() == "Tombstone" {
+		w.WriteHeader(http.StatusGone)
+	}
+	w.Write(body)
+}
+
+// authorizeFetch answers with 401 Unauthorized and returns false if the wiki requires authorized fetch and r is
+// not signed by an actor of a server we federate with.
+func authorizeFetch(h *Handler, w http.ResponseWriter, r *http.Request) bool {
+	_, authenticated, err := h.Federation.AuthenticateGet(r.Context(), w, r)
+	if err != nil || !authenticated {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// Actor serves the ActivityPub actor of a local user. Browsers are redirected to the user's profile page.
+func Actor(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := chi.URLParam(r, "username")
+		if !IsActivityPubRequest(r) {
+			http.Redirect(w, r, "/@"+username, http.StatusFound)
+			return
+		}
+
+		serveActor(h, w, r, username)
+	}
+}
+
+func serveActor(h *Handler, w http.ResponseWriter, r *http.Request, username string) {
+	if !authorizeFetch(h, w, r) {
+		return
+	}
+
+	actor, err := h.service.GetUserActor(r.Context(), username)
+	if err != nil {
+		code := GetCode(w, err)
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+
+	WriteActivity(w, actor)
+}
+
+// Outbox serves the outbox of a local user, or one of its pages if the page query parameter is given.
+func Outbox(h *Handler) http.HandlerFunc {
+	return serveCollection(h, "username", h.service.GetOutbox)
+}
+
+// Followers serves the followers collection of a local user, or one of its pages.
+func Followers(h *Handler) http.HandlerFunc {
+	return serveCollection(h, "username", h.service.GetFollowers)
+}
+
+// Likes serves the collection of the Likes of a local article, or one of its pages.
+func Likes(h *Handler) http.HandlerFunc {
+	return serveCollection(h, "title", h.service.GetLikes)
+}
+
+// Shares serves the collection of the Announces of a local article, or one of its pages.
+func Shares(h *Handler) http.HandlerFunc {
+	return serveCollection(h, "title", h.service.GetShares)
+}
+
+// ArticleOutbox serves the outbox of a local article, which lists its revisions, or one of its pages.
+func ArticleOutbox(h *Handler) http.HandlerFunc {
+	return serveCollection(h, "title", h.service.GetArticleOutbox)
+}
+
+// ArticleFollowers serves the followers collection of a local article, or one of its pages.
+func ArticleFollowers(h *Handler) http.HandlerFunc {
+	return serveCollection(h, "title", h.service.GetArticleFollowers)
+}
+
+// InstanceActor serves the Service actor that represents the wiki. Browsers are redirected to the main page. It
+// is served even without a signature, since its key is needed to verify the fetches it signs.
+func InstanceActor(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !IsActivityPubRequest(r) {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+
+		actor, err := h.service.GetInstanceActor(r.Context())
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		WriteActivity(w, actor)
+	}
+}
+
+// InstanceOutbox serves the outbox of the instance actor, or one of its pages.
+func InstanceOutbox(h *Handler) http.HandlerFunc {
+	return serveCollection(h, "", func(ctx context.Context, _ string, page int) (vocab.Type, error) {
+		return h.service.GetInstanceOutbox(ctx, page)
+	})
+}
+
+// InstanceFollowers serves the followers collection of the instance actor, or one of its pages.
+func InstanceFollowers(h *Handler) http.HandlerFunc {
+	return serveCollection(h, "", func(ctx context.Context, _ string, page int) (vocab.Type, error) {
+		return h.service.GetInstanceFollowers(ctx, page)
+	})
+}
+
+// serveCollection serves the collection returned by get for the owner named by the given URL parameter.
+func serveCollection(h *Handler, param string, get func(ctx context.Context, owner string, page int) (vocab.Type, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeFetch(h, w, r) {
+			return
+		}
+
+		var page int
+		if p := r.URL.Query().Get("page"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 {
+				http.Error(w, "invalid page", http.StatusBadRequest)
+				return
+			}
+			page = n
+		}
+
+		collection, err := get(r.Context(), chi.URLParam(r, param), page)
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		WriteActivity(w, collection)
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/service"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
 	"github.com/sidereusnuntius/gowiki/templates"
 )
 
@@ -20,7 +23,14 @@ func ArticleHistory(handler *Handler) http.HandlerFunc {
 		u, ok := GetSession(ctx)
 		title := chi.URLParam(r, "title")
 		//page := r.PathValue.Get("after")
-		list, err := handler.service.GetRevisionList(ctx, title)
+		var list []domain.Revision
+		var err error
+		name, host, foreign := splitForeignTitle(title, handler.Config.Domain)
+		if foreign {
+			list, err = handler.service.GetForeignRevisionList(ctx, name, host)
+		} else {
+			list, err = handler.service.GetRevisionList(ctx, title)
+		}
 		if err != nil {
 			//TODO: handle error
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -30,6 +40,15 @@ func ArticleHistory(handler *Handler) http.HandlerFunc {
 		history := r.URL.String()
 		// TODO: store article URL in database, use it to generate paths.
 		path, _ := url.Parse("/a/" + title)
+		hrefs := map[templates.Place]string{
+			templates.Read:    path.String(),
+			templates.Edit:    path.JoinPath("edit").String(),
+			templates.History: history,
+		}
+		// Only local articles have a discussion page.
+		if !foreign {
+			hrefs[templates.Discussion] = path.JoinPath("discussion").String()
+		}
 		templates.Layout(templates.PageData{
 			Authenticated: ok,
 			Username:      u.Username,
@@ -37,13 +56,40 @@ func ArticleHistory(handler *Handler) http.HandlerFunc {
 			PageTitle:     "Revision history",
 			Place:         templates.History,
 			Path:          r.URL,
+			Hrefs:         hrefs,
+			IsArticle:     false,
+			Child:     templates.Revisions(title, list),
+		}).Render(ctx, w)
+	}
+}
+
+// Backlinks renders the list of the local articles that link to an article, which may not exist yet.
+func Backlinks(handler *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		u, ok := GetSession(ctx)
+		title := chi.URLParam(r, "title")
+		titles, err := handler.service.Backlinks(ctx, title)
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		path, _ := url.Parse("/a/" + title)
+		templates.Layout(templates.PageData{
+			Authenticated: ok,
+			Username:      u.Username,
+			ProfilePath:   "TODO",
+			PageTitle:     "Links to " + title,
+			Place:         templates.Backlinks,
+			Path:          r.URL,
 			Hrefs: map[templates.Place]string{
-				templates.Read:    path.String(),
-				templates.Edit:    path.JoinPath("edit").String(),
-				templates.History: history,
+				templates.Read:      path.String(),
+				templates.History:   path.JoinPath("history").String(),
+				templates.Backlinks: r.URL.String(),
 			},
-			IsArticle: false,
-			Child:     templates.Revisions(title, list),
+			Child: templates.BacklinkList(title, titles),
 		}).Render(ctx, w)
 	}
 }
@@ -63,9 +109,23 @@ func EditArticle(handler *Handler) http.HandlerFunc {
 		
 		var newarticle bool
 		title := chi.URLParam(r, "title")
-		article, err := handler.service.GetLocalArticle(ctx, title)
+		var article domain.ArticleCore
+		var err error
+		if name, host, foreign := splitForeignTitle(title, handler.Config.Domain); foreign {
+			// Edits of foreign articles are proposed to their home server, so the article must already exist.
+			var a domain.ArticleFed
+			if a, err = handler.service.GetForeignArticleSource(ctx, name, host); err != nil {
+				code := GetCode(w, err)
+				http.Error(w, http.StatusText(code), code)
+				return
+			}
+			article = a.ArticleCore
+		} else {
+			article, err = handler.service.GetLocalArticleSource(ctx, title)
+		}
 		if err != nil {
-			if errors.Is(err, db.ErrNotFound) {
+			// Deleted articles may be created again.
+			if errors.Is(err, db.ErrNotFound) || errors.Is(err, service.ErrGone) {
 				newarticle = true
 			} else {
 				http.Error(w, "internal error", http.StatusInternalServerError)
@@ -83,24 +143,28 @@ func EditArticle(handler *Handler) http.HandlerFunc {
 
 		var preview string
 		if content != "" {
-			preview = content
+			preview = handler.service.Preview(ctx, article.MediaType, content)
 		}
 
 		if content == "" {
 			content = article.Content
 		}
 
-		//TODO: parse content to produce preview
-
 		edit := r.URL.String()
 		// TODO: store article URL in database, use it to generate paths.
 		path, _ := url.Parse("/a/" + title)
 		hrefs := map[templates.Place]string{
 			templates.Edit:    edit,
 		}
+		var deleteRoute string
 		if !newarticle {
 			hrefs[templates.Read] = path.String()
 			hrefs[templates.History] = path.JoinPath("history").String()
+			// Only admins may delete articles, which the service checks, and foreign articles are deleted by
+			// their own server.
+			if _, _, foreign := splitForeignTitle(title, handler.Config.Domain); !foreign {
+				deleteRoute = path.JoinPath("delete").String()
+			}
 		}
 
 		templates.Layout(templates.PageData{
@@ -112,7 +176,7 @@ func EditArticle(handler *Handler) http.HandlerFunc {
 			Path:          r.URL,
 			Hrefs: hrefs,
 			IsArticle: false,
-			Child:     templates.Editor(path.String(), edit, title, summary, preview, content),
+			Child:     templates.Editor(path.String(), edit, deleteRoute, title, summary, preview, content),
 		}).Render(ctx, w)
 	}
 }
@@ -122,12 +186,30 @@ func GetArticle(handler *Handler) http.HandlerFunc {
 		ctx := r.Context()
 		u, ok := GetSession(ctx)
 		title := chi.URLParam(r, "title")
+		// The same URL serves the page and the ActivityPub object, so caches must tell them apart.
+		w.Header().Set("Vary", "Accept")
+		// Our own domain may be given explicitly, as in title@domain.
+		title = strings.TrimSuffix(title, "@"+handler.Config.Domain)
+		if name, host, ok := splitForeignTitle(title, handler.Config.Domain); ok {
+			foreignArticle(handler, w, r, name, host)
+			return
+		}
+
+		if IsActivityPubRequest(r) {
+			serveArticleObject(handler, w, r, title)
+			return
+		}
+
 		article, err := handler.service.GetLocalArticle(ctx, title)
 
 		// TODO: deal with the case in which the article has not been created, which should redirect to the editor.
 		if err != nil {
 			// TODO: render template
-			if errors.Is(err, db.ErrNotFound) {
+			if deleted := errors.Is(err, service.ErrGone); deleted || errors.Is(err, db.ErrNotFound) {
+				if deleted {
+					w.Header().Set("Content-Type", "text/html; charset=utf-8")
+					w.WriteHeader(http.StatusGone)
+				}
 				templates.Layout(templates.PageData{
 					Authenticated: ok,
 					Username:      u.Username,
@@ -136,7 +218,7 @@ func GetArticle(handler *Handler) http.HandlerFunc {
 					Place:         templates.Read,
 					Path:          r.URL,
 					IsArticle: false,
-					Child: templates.NonexistingArticle(r.URL.JoinPath("edit").String(), title),
+					Child: templates.NonexistingArticle(r.URL.JoinPath("edit").String(), title, deleted),
 					}).Render(ctx, w)
 			} else {
 				w.WriteHeader(http.StatusBadRequest)
@@ -145,7 +227,6 @@ func GetArticle(handler *Handler) http.HandlerFunc {
 			return
 		}
 
-		// Sanitize content!
 		templates.Layout(templates.PageData{
 			Authenticated: ok,
 			Username:      u.Username,
@@ -154,9 +235,11 @@ func GetArticle(handler *Handler) http.HandlerFunc {
 			Place:         templates.Read,
 			Path:          r.URL,
 			Hrefs: map[templates.Place]string{
-				templates.Read:    r.URL.String(),
-				templates.Edit:    r.URL.JoinPath("edit").String(),
-				templates.History: r.URL.JoinPath("history").String(),
+				templates.Read:       r.URL.String(),
+				templates.Edit:       r.URL.JoinPath("edit").String(),
+				templates.History:    r.URL.JoinPath("history").String(),
+				templates.Discussion: r.URL.JoinPath("discussion").String(),
+				templates.Backlinks:  r.URL.JoinPath("backlinks").String(),
 			},
 			IsArticle: true,
 			Article: templates.ArticleData{
@@ -165,12 +248,160 @@ func GetArticle(handler *Handler) http.HandlerFunc {
 				URL:      r.URL,
 				Content:  article.Content,
 				Language: article.Language,
-				License:  "", //TODO
+				License:  article.License,
+				Likes:      article.Likes,
+				Shares:     article.Shares,
+				ForkedFrom: article.ForkedFrom,
 			},
 		}).Render(ctx, w)
 	}
 }
 
+// splitForeignTitle splits a title of the form title@host, which refers to an article hosted by another wiki.
+func splitForeignTitle(title, domain string) (name, host string, ok bool) {
+	i := strings.LastIndex(title, "@")
+	if i <= 0 || i == len(title)-1 {
+		return
+	}
+
+	name, host = title[:i], title[i+1:]
+	if strings.ContainsAny(host, " /") || strings.EqualFold(host, domain) {
+		return "", "", false
+	}
+	return name, host, true
+}
+
+// foreignArticle renders our copy of an article hosted by another wiki, fetching it if needed. ActivityPub clients
+// are redirected to the article's home server.
+func foreignArticle(h *Handler, w http.ResponseWriter, r *http.Request, title, host string) {
+	ctx := r.Context()
+	u, ok := GetSession(ctx)
+	article, err := h.service.GetForeignArticle(ctx, title, host, u.UserID)
+	if err != nil {
+		// Only users may have articles of other wikis fetched.
+		if !ok && errors.Is(err, service.ErrForbidden) {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		code := GetCode(w, err)
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+
+	if IsActivityPubRequest(r) {
+		http.Redirect(w, r, article.ApID.String(), http.StatusFound)
+		return
+	}
+
+	source := article.Url
+	if source == nil {
+		source = article.ApID
+	}
+
+	var fork string
+	if ok {
+		fork = r.URL.JoinPath("fork").String()
+	}
+
+	templates.Layout(templates.PageData{
+		Authenticated: ok,
+		Username:      u.Username,
+		ProfilePath:   "TODO",
+		PageTitle:     article.Title,
+		Place:         templates.Read,
+		Path:          r.URL,
+		Hrefs: map[templates.Place]string{
+			templates.Read: r.URL.String(),
+		},
+		IsArticle: true,
+		Article: templates.ArticleData{
+			Title:    article.Title,
+			Domain:   article.Host,
+			URL:      source,
+			Content:  article.Content,
+			Language:   article.Language,
+			License:    article.License,
+			ForkedFrom: article.ForkedFrom,
+			ForkRoute:  fork,
+		},
+	}).Render(ctx, w)
+}
+
+// Revision serves the activity of a revision of an article. Browsers are redirected to the article's history.
+func Revision(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		title := chi.URLParam(r, "title")
+		if !IsActivityPubRequest(r) {
+			http.Redirect(w, r, (&url.URL{Path: "/a/" + title + "/history"}).String(), http.StatusFound)
+			return
+		}
+
+		if !authorizeFetch(h, w, r) {
+			return
+		}
+
+		activity, err := h.service.GetRevisionActivity(r.Context(), title, chi.URLParam(r, "id"))
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		WriteActivity(w, activity)
+	}
+}
+
+// DeleteArticle deletes a local article and redirects to the main page.
+func DeleteArticle(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, _ := GetSession(r.Context())
+		if err := h.service.DeleteArticle(r.Context(), chi.URLParam(r, "title"), s.UserID); err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// ForkArticle forks a foreign article into a local one, named after the title form value if given, and redirects
+// to the new article.
+func ForkArticle(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, host, foreign := splitForeignTitle(chi.URLParam(r, "title"), h.Config.Domain)
+		if !foreign {
+			http.Error(w, "only articles hosted by other wikis can be forked", http.StatusBadRequest)
+			return
+		}
+
+		s, _ := GetSession(r.Context())
+		id, err := h.service.ForkArticle(r.Context(), name, host, r.FormValue("title"), s.UserID)
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		http.Redirect(w, r, id.String(), http.StatusSeeOther)
+	}
+}
+
+func serveArticleObject(h *Handler, w http.ResponseWriter, r *http.Request, title string) {
+	if !authorizeFetch(h, w, r) {
+		return
+	}
+
+	article, err := h.service.GetArticleObject(r.Context(), title)
+	if err != nil {
+		code := GetCode(w, err)
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+
+	WriteActivity(w, article)
+}
+
 func PostArticle(handler *Handler) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// TODO: implement a payload limit.
@@ -188,6 +419,17 @@ func PostArticle(handler *Handler) http.HandlerFunc {
 
 		summary := r.Form.Get("summary")
 		content := r.Form.Get("content")
+		if name, host, foreign := splitForeignTitle(title, handler.Config.Domain); foreign {
+			_, err = handler.service.ProposeEdit(ctx, name, host, summary, content, session.UserID)
+			if err != nil {
+				code := GetCode(w, err)
+				http.Error(w, err.Error(), code)
+				return
+			}
+			http.Redirect(w, r, (&url.URL{Path: "/a/" + title + "/history"}).String(), http.StatusSeeOther)
+			return
+		}
+
 		//prev := r.Form.Get("")
 		id, err := handler.service.AlterArticle(ctx, title, summary, content, session.UserID)
 		if err == nil {
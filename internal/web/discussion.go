@@ -0,0 +1,132 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sidereusnuntius/gowiki/templates"
+)
+
+// Discussion renders the comment threads of a local article, with a form to comment for authenticated users.
+func Discussion(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		s, ok := GetSession(ctx)
+		title := chi.URLParam(r, "title")
+		comments, moderator, err := h.service.GetDiscussion(ctx, title, s.UserID)
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		path := discussionPath(title)
+		templates.Layout(templates.PageData{
+			Authenticated: ok,
+			Username:      s.Username,
+			ProfilePath:   "TODO",
+			PageTitle:     "Discussion: " + title,
+			Place:         templates.Discussion,
+			Path:          r.URL,
+			Hrefs: map[templates.Place]string{
+				templates.Read:       "/a/" + title,
+				templates.Edit:       "/a/" + title + "/edit",
+				templates.History:    "/a/" + title + "/history",
+				templates.Discussion: path.String(),
+			},
+			Child: templates.Comments(title, comments, ok, moderator),
+		}).Render(ctx, w)
+	}
+}
+
+// PostComment adds a comment to the discussion of an article, in reply to the comment given by the reply_to field,
+// if any.
+func PostComment(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		s, _ := GetSession(ctx)
+		title := chi.URLParam(r, "title")
+
+		var replyTo int64
+		if v := r.FormValue("reply_to"); v != "" {
+			var err error
+			if replyTo, err = strconv.ParseInt(v, 10, 64); err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if _, err := h.service.PostComment(ctx, title, r.FormValue("content"), replyTo, s.UserID); err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		http.Redirect(w, r, discussionPath(title).String(), http.StatusSeeOther)
+	}
+}
+
+// Comment serves the Note of a comment. Browsers are redirected to the comment on the discussion page.
+func Comment(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		title, id := chi.URLParam(r, "title"), chi.URLParam(r, "id")
+		if !IsActivityPubRequest(r) {
+			path := discussionPath(title)
+			path.Fragment = "comment-" + id
+			http.Redirect(w, r, path.String(), http.StatusFound)
+			return
+		}
+
+		if !authorizeFetch(h, w, r) {
+			return
+		}
+
+		note, err := h.service.GetCommentNote(r.Context(), title, id)
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		WriteActivity(w, note)
+	}
+}
+
+// ModerateComment hides, shows or deletes a comment, according to the action field of the form.
+func ModerateComment(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		s, _ := GetSession(ctx)
+		title := chi.URLParam(r, "title")
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		switch r.FormValue("action") {
+		case "hide":
+			err = h.service.HideComment(ctx, id, s.UserID, true)
+		case "show":
+			err = h.service.HideComment(ctx, id, s.UserID, false)
+		case "delete":
+			err = h.service.DeleteComment(ctx, id, s.UserID)
+		default:
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		http.Redirect(w, r, discussionPath(title).String(), http.StatusSeeOther)
+	}
+}
+
+func discussionPath(title string) *url.URL {
+	return &url.URL{Path: "/a/" + title + "/discussion"}
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/templates"
+)
+
+// Instances lists the other servers we know of, for admins to choose how we federate with each of them.
+func Instances(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		s, ok := GetSession(ctx)
+		instances, err := h.service.ListInstances(ctx, s.UserID)
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		templates.Layout(templates.PageData{
+			Authenticated: ok,
+			Username:      s.Username,
+			PageTitle:     "Federation",
+			Place:         templates.PlaceFederation,
+			Child:         templates.Instances(instances, h.Config.Allowlist),
+			Path:          r.URL,
+		}).Render(ctx, w)
+	}
+}
+
+// SetInstancePolicy changes the policy of the instance named by the hostname field of the form.
+func SetInstancePolicy(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		s, _ := GetSession(ctx)
+		policy := domain.Policy(r.FormValue("policy"))
+		if err := h.service.SetInstancePolicy(ctx, r.FormValue("hostname"), policy, s.UserID); err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		http.Redirect(w, r, "/federation", http.StatusSeeOther)
+	}
+}
+
+// SetInterwikiPrefix changes the interwiki prefix of the instance named by the hostname field of the form.
+func SetInterwikiPrefix(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		s, _ := GetSession(ctx)
+		if err := h.service.SetInterwikiPrefix(ctx, r.FormValue("hostname"), r.FormValue("prefix"), s.UserID); err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		http.Redirect(w, r, "/federation", http.StatusSeeOther)
+	}
+}
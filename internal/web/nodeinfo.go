@@ -0,0 +1,38 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
+)
+
+// NodeInfoLinks serves the NodeInfo discovery document, through which crawlers find the NodeInfo of the wiki.
+func NodeInfoLinks(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if err := json.NewEncoder(w).Encode(h.service.NodeInfoLinks(r.Context())); err != nil {
+			log.Error().Err(err).Msg("failed to write nodeinfo links")
+		}
+	}
+}
+
+// NodeInfo serves the NodeInfo 2.1 document of the wiki.
+func NodeInfo(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, err := h.service.NodeInfo(r.Context())
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", federation.NodeInfoContentType)
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if err = json.NewEncoder(w).Encode(info); err != nil {
+			log.Error().Err(err).Msg("failed to write nodeinfo")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/golang-migrate/migrate"
+	"github.com/golang-migrate/migrate/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/source/file"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sidereusnuntius/gowiki/internal/config"
+	dbimpl "github.com/sidereusnuntius/gowiki/internal/db/impl"
+	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
+	core "github.com/sidereusnuntius/gowiki/internal/service/impl"
+	"github.com/sidereusnuntius/gowiki/internal/state"
+)
+
+var (
+	h        Handler
+	st       *state.State
+	database *sql.DB
+	ctx      = context.Background()
+)
+
+func TestMain(m *testing.M) {
+	d, err := sql.Open("sqlite3", "file:web?mode=memory")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open connection: %s", err)
+		return
+	}
+
+	driver, err := sqlite3.WithInstance(d, &sqlite3.Config{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create driver: %s", err)
+		return
+	}
+
+	mig, err := migrate.NewWithDatabaseInstance("file://../../migrations", "web", driver)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create database object: %s", err)
+		return
+	}
+	if err = mig.Up(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to run migrations: %s", err)
+		return
+	}
+
+	root, err := os.MkdirTemp("", "gowiki")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create file root: %s", err)
+		return
+	}
+	defer os.RemoveAll(root)
+
+	u, _ := url.Parse("http://test.wiki")
+	conf := config.Configuration{
+		Domain:           "test.wiki",
+		Url:              u,
+		FsRoot:           root,
+		Name:             "Test Wiki",
+		Language:         "it",
+		License:          "CC BY-SA 4.0",
+		ApprovalRequired: true,
+	}
+	database = d
+	st = &state.State{DB: dbimpl.New(conf, d), Config: conf}
+	fed := federation.New(st)
+	service, err := core.New(st, fed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create service: %s", err)
+		return
+	}
+	h = New(&st.Config, service, nil, fed)
+
+	m.Run()
+	d.Close()
+}
+
+func get(t *testing.T, handler http.HandlerFunc, path string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "http://test.wiki"+path, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d for %s, got %d", http.StatusOK, path, w.Code)
+	}
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatalf("failed to decode %s: %s", path, err)
+	}
+	return w
+}
+
+func TestNodeInfo(t *testing.T) {
+	var links federation.NodeInfoLinks
+	w := get(t, NodeInfoLinks(&h), federation.NodeInfoPath, &links)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("expected the discovery document to be served to any origin")
+	}
+	if len(links.Links) != 1 || links.Links[0].Rel != federation.NodeInfoSchema ||
+		links.Links[0].Href != "http://test.wiki"+federation.NodeInfo21Path {
+		t.Fatalf("expected a single link to the NodeInfo 2.1 document, got %+v", links.Links)
+	}
+
+	// A local user who created an article, and a foreign user, who is not counted.
+	alice := st.Config.Url.JoinPath("u", "alice")
+	err := st.DB.InsertUser(ctx, domain.UserFedInternal{
+		UserFed: domain.UserFed{
+			UserCore: domain.UserCore{Username: "alice"},
+			ApId:     alice,
+			Inbox:    alice.JoinPath("inbox"),
+		},
+	}, domain.Account{Email: "alice@test.wiki"}, "", "")
+	if err != nil {
+		t.Fatalf("failed to create user: %s", err)
+	}
+	var aliceId int64
+	if err = database.QueryRow("SELECT id FROM users WHERE ap_id = ?", alice.String()).Scan(&aliceId); err != nil {
+		t.Fatalf("failed to get user ID: %s", err)
+	}
+	_, err = st.DB.CreateLocalArticle(ctx, aliceId, domain.ArticleFed{
+		ArticleCore: domain.ArticleCore{Title: "Sidereus Nuncius", Content: "Moons", Language: "la", MediaType: "text/plain"},
+		ApID:        st.Config.Url.JoinPath("a", "Sidereus Nuncius"),
+	}, domain.Revision{Diff: "@@ -0,0 +1,5 @@\n+Moons\n"})
+	if err != nil {
+		t.Fatalf("failed to create article: %s", err)
+	}
+	bob, _ := url.Parse("https://other.wiki/u/bob")
+	_, err = st.DB.UpsertForeignUser(ctx, domain.UserFed{
+		UserCore: domain.UserCore{Username: "bob", Domain: bob.Host},
+		ApId:     bob,
+		Inbox:    bob.JoinPath("inbox"),
+	})
+	if err != nil {
+		t.Fatalf("failed to store foreign user: %s", err)
+	}
+
+	var info federation.NodeInfo
+	w = get(t, NodeInfo(&h), federation.NodeInfo21Path, &info)
+	if ct := w.Header().Get("Content-Type"); ct != federation.NodeInfoContentType {
+		t.Errorf("expected content type %q, got %q", federation.NodeInfoContentType, ct)
+	}
+
+	if info.Version != "2.1" || info.Software.Name != federation.SoftwareName || info.Software.Version != federation.SoftwareVersion {
+		t.Errorf("unexpected version or software: %s %+v", info.Version, info.Software)
+	}
+	if len(info.Protocols) != 1 || info.Protocols[0] != "activitypub" {
+		t.Errorf("expected activitypub to be the only protocol, got %v", info.Protocols)
+	}
+	if info.OpenRegistrations {
+		t.Errorf("expected registrations that need approval not to be open")
+	}
+
+	want := federation.Usage{
+		Users:      federation.UsageUsers{Total: 1, ActiveMonth: 1, ActiveHalfyear: 1},
+		LocalPosts: 1,
+	}
+	if info.Usage != want {
+		t.Errorf("expected usage %+v, got %+v", want, info.Usage)
+	}
+
+	for key, value := range map[string]any{
+		"nodeName":         "Test Wiki",
+		"language":         "it",
+		"license":          "CC BY-SA 4.0",
+		"articles":         float64(1),
+		"approvalRequired": true,
+	} {
+		if info.Metadata[key] != value {
+			t.Errorf("expected metadata %s to be %v, got %v", key, value, info.Metadata[key])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sidereusnuntius/gowiki/templates"
+)
+
+// Proposals lists the edits of local articles proposed by users of other servers, for trusted users to review.
+func Proposals(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		s, ok := GetSession(ctx)
+		proposals, err := h.service.ListProposals(ctx, s.UserID)
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		templates.Layout(templates.PageData{
+			Authenticated: ok,
+			Username:      s.Username,
+			PageTitle:     "Proposed edits",
+			Place:         templates.PlaceProposals,
+			Child:         templates.Proposals(proposals),
+			Path:          r.URL,
+		}).Render(ctx, w)
+	}
+}
+
+// ReviewProposal accepts or rejects a proposal according to the decision field of the form.
+func ReviewProposal(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		s, _ := GetSession(ctx)
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		var accept bool
+		switch r.FormValue("decision") {
+		case "accept":
+			accept = true
+		case "reject":
+		default:
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		if err = h.service.ReviewProposal(ctx, id, s.UserID, s.Username, accept); err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		http.Redirect(w, r, "/proposals", http.StatusSeeOther)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
 )
 
 func (h *Handler) Mount(r chi.Router) {
@@ -26,16 +27,56 @@ func (h *Handler) Mount(r chi.Router) {
 	// 	ServeHTTP(w, r)
 	// })
 
+	r.Get(WebfingerRoute, Webfinger(h))
+	r.Get(federation.NodeInfoPath, NodeInfoLinks(h))
+	r.Get(federation.NodeInfo21Path, NodeInfo(h))
+	r.Post("/inbox", h.Federation.PostInbox)
+
 	r.Get("/@{username}", Profile(h))
 	r.Get("/@{username}@{domain}", Profile(h))
 
+	r.Route("/u/{username}", func(r chi.Router) {
+		r.Get("/", Actor(h))
+		r.Post("/inbox", h.Federation.PostInbox)
+		r.Get("/outbox", Outbox(h))
+		r.Get("/followers", Followers(h))
+		r.Post("/rotate-key", authenticated(RotateKey(h)))
+	})
+
 	r.Route("/a/{title}", func(r chi.Router) {
-		r.Post("/", PostArticle(h))
+		r.Post("/", authenticated(PostArticle(h)))
 		r.Get("/", GetArticle(h))
 		r.Handle("/edit", authenticated(EditArticle(h)))
+		r.Post("/delete", authenticated(DeleteArticle(h)))
+		r.Post("/fork", authenticated(ForkArticle(h)))
 		r.Get("/history", ArticleHistory(h))
+		r.Get("/history/{id}", Revision(h))
+		r.Get("/backlinks", Backlinks(h))
+		r.Get("/discussion", Discussion(h))
+		r.Post("/discussion", authenticated(PostComment(h)))
+		r.Get("/discussion/{id}", Comment(h))
+		r.Post("/discussion/{id}/moderate", authenticated(ModerateComment(h)))
+		r.Get("/likes", Likes(h))
+		r.Get("/shares", Shares(h))
+		r.Post("/inbox", h.Federation.PostInbox)
+		r.Get("/outbox", ArticleOutbox(h))
+		r.Get("/followers", ArticleFollowers(h))
 	})
 
+	r.Route("/actor", func(r chi.Router) {
+		r.Get("/", InstanceActor(h))
+		r.Post("/inbox", h.Federation.PostInbox)
+		r.Get("/outbox", InstanceOutbox(h))
+		r.Get("/followers", InstanceFollowers(h))
+	})
+
+	r.Get("/proposals", authenticated(Proposals(h)))
+	r.Post("/proposals/{id}", authenticated(ReviewProposal(h)))
+
+	r.Get("/federation", authenticated(Instances(h)))
+	r.Post("/federation", authenticated(SetInstancePolicy(h)))
+	r.Post("/federation/interwiki", authenticated(SetInterwikiPrefix(h)))
+
 	r.Route("/f", func(r chi.Router) {
 		r.Get("/upload", authenticated(UploadView(h)))
 		r.Post("/upload", authenticated(Upload(h)))
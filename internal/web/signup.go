@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/sidereusnuntius/gowiki/internal/db"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
 	"github.com/sidereusnuntius/gowiki/internal/service"
 	"github.com/sidereusnuntius/gowiki/templates"
 )
@@ -58,6 +59,14 @@ func GetCode(w http.ResponseWriter, err error) int {
 		return http.StatusNotFound
 	case errors.Is(err, service.ErrInvalidInput):
 		return http.StatusBadRequest
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrGone):
+		return http.StatusGone
+	case errors.Is(err, federation.ErrFetch):
+		return http.StatusBadGateway
 	default:
 		return http.StatusInternalServerError
 	}
@@ -1,10 +1,13 @@
 package web
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sidereusnuntius/gowiki/internal/service"
 	"github.com/sidereusnuntius/gowiki/templates"
 )
 
@@ -15,33 +18,69 @@ func Profile(h *Handler) http.HandlerFunc {
 		username := chi.URLParam(r, "username")
 		domain := chi.URLParam(r, "domain")
 
-		p, err := h.service.GetUserProfile(ctx, username, domain)
+		if domain == "" && IsActivityPubRequest(r) {
+			serveActor(h, w, r, username)
+			return
+		}
+
+		p, err := h.service.GetUserProfile(ctx, username, domain, u.UserID)
 		if err != nil {
-			//TODO
-			log.Print(err)
-			http.Error(w, "failed: handle error later", http.StatusInternalServerError)
+			// Only users may have users of other servers fetched.
+			if !ok && errors.Is(err, service.ErrForbidden) {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			code := GetCode(w, err)
+			if code == http.StatusInternalServerError {
+				log.Print(err)
+			}
+			http.Error(w, http.StatusText(code), code)
 			return
 		}
 
+		title := username
+		if domain != "" {
+			title += "@" + domain
+		}
+
 		hrefs := map[templates.Place]string{
 			templates.Read: r.URL.String(),
 		}
 
+		var rotate string
 		if ok {
 			hrefs[templates.Edit] = r.URL.JoinPath("edit").String()
+			if domain == "" && u.Username == p.Username {
+				rotate = (&url.URL{Path: "/u/" + p.Username + "/rotate-key"}).String()
+			}
 		}
 		templates.Layout(templates.PageData{
 			Authenticated: ok,
 			Username:      u.Username,
 			ProfilePath:   "TODO",
-			PageTitle:     username,
+			PageTitle:     title,
 			Place:         templates.PlaceProfile,
 			Hrefs:         hrefs,
 			IsArticle:     false,
-			Child:         templates.Profile(p),
+			Child:         templates.Profile(p, rotate),
 			FixedArticles: nil, // TODO
 			Path:          r.URL,
 			Err:           nil,
 		}).Render(ctx, w)
 	}
 }
+
+// RotateKey rotates the key pair of a local user and redirects to their profile.
+func RotateKey(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, _ := GetSession(r.Context())
+		username := chi.URLParam(r, "username")
+		if err := h.service.RotateKey(r.Context(), username, s.UserID); err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		http.Redirect(w, r, (&url.URL{Path: "/@" + username}).String(), http.StatusSeeOther)
+	}
+}
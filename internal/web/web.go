@@ -3,6 +3,7 @@ package web
 import (
 	"github.com/alexedwards/scs"
 	"github.com/sidereusnuntius/gowiki/internal/config"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
 	"github.com/sidereusnuntius/gowiki/internal/service"
 )
 
@@ -16,12 +17,14 @@ type Handler struct {
 	Config         *config.Configuration
 	service        service.Service
 	SessionManager *scs.Manager
+	Federation     *federation.FedProto
 }
 
-func New(config *config.Configuration, service service.Service, manager *scs.Manager) Handler {
+func New(config *config.Configuration, service service.Service, manager *scs.Manager, fed *federation.FedProto) Handler {
 	return Handler{
 		Config:         config,
 		service:        service,
 		SessionManager: manager,
+		Federation:     fed,
 	}
 }
@@ -0,0 +1,36 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
+)
+
+const WebfingerRoute = federation.WebfingerPath
+
+// Webfinger answers WebFinger queries, which remote servers use to discover the ActivityPub ID of our users and
+// articles from handles such as alice@wiki.example.
+func Webfinger(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := r.URL.Query().Get("resource")
+		if resource == "" {
+			http.Error(w, "missing resource parameter", http.StatusBadRequest)
+			return
+		}
+
+		jrd, err := h.service.Webfinger(r.Context(), resource)
+		if err != nil {
+			code := GetCode(w, err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", federation.JRDContentType)
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if err = json.NewEncoder(w).Encode(jrd); err != nil {
+			log.Error().Err(err).Str("resource", resource).Msg("failed to write webfinger response")
+		}
+	}
+}
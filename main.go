@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/gob"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/alexedwards/scs"
 	"github.com/go-chi/chi/v5"
@@ -15,6 +18,7 @@ import (
 	"github.com/sidereusnuntius/gowiki/internal/config"
 	db "github.com/sidereusnuntius/gowiki/internal/db/impl"
 	"github.com/sidereusnuntius/gowiki/internal/domain"
+	"github.com/sidereusnuntius/gowiki/internal/federation"
 	service "github.com/sidereusnuntius/gowiki/internal/service/impl"
 	"github.com/sidereusnuntius/gowiki/internal/state"
 	"github.com/sidereusnuntius/gowiki/internal/web"
@@ -39,6 +43,7 @@ func main() {
 	config := config.Configuration{
 		FsRoot: "./files",
 		StaticDir:          "/static/",
+		MediaType:          config.Markdown,
 		RsaKeySize:         2048,
 		InvitationRequired: false,
 		ApprovalRequired:   false,
@@ -56,11 +61,24 @@ func main() {
 		Config: config,
 	}
 
-	service, err := service.New(&state)
+	// The background workers stop, and the server shuts down, on an interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fed := federation.New(&state)
+	go fed.RunDelivery(ctx)
+
+	service, err := service.New(&state, fed)
 	if err != nil {
 		log.Fatal(err)
 	}
-	handler := web.New(&config, service, manager)
+
+	if err = service.Setup(ctx); err != nil {
+		log.Fatal(err)
+	}
+	go service.RunKeyGeneration(ctx)
+
+	handler := web.New(&config, service, manager, fed)
 	r := chi.NewRouter()
 	handler.Mount(r)
 	if config.Debug {
@@ -72,8 +90,13 @@ func main() {
 		Handler: r,
 	}
 
+	go func() {
+		<-ctx.Done()
+		s.Shutdown(context.Background())
+	}()
+
 	err = s.ListenAndServe()
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,2 @@
+DROP INDEX deliveries_due;
+DROP TABLE deliveries;
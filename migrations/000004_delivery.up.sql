@@ -0,0 +1,16 @@
+-- Activities waiting to be delivered to remote inboxes. A row is removed as soon as its delivery succeeds;
+-- failed deliveries are retried with exponential backoff until they are marked as dead.
+CREATE TABLE deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    -- ID of the local actor on whose behalf the activity is sent; their key signs the request.
+    actor VARCHAR(255) NOT NULL,
+    inbox VARCHAR(255) NOT NULL,
+    activity TEXT NOT NULL,
+    attempts INTEGER DEFAULT 0 NOT NULL,
+    next_attempt INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+    last_error TEXT,
+    dead BOOLEAN DEFAULT FALSE NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL
+);
+
+CREATE INDEX deliveries_due ON deliveries (dead, next_attempt);
@@ -0,0 +1,24 @@
+CREATE TABLE files_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    digest CHAR(64) NOT NULL,
+    path VARCHAR(255),
+    ap_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255),
+    filename VARCHAR(255),
+    type VARCHAR(32) NOT NULL DEFAULT 'Document',
+    mime_type VARCHAR(128) NOT NULL,
+    size_bytes INTEGER,
+    local BOOLEAN DEFAULT FALSE NOT NULL,
+    uploaded_by INTEGER,
+    url VARCHAR(255) NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (digest),
+    UNIQUE (ap_id),
+    FOREIGN KEY (uploaded_by) REFERENCES users (id)
+);
+
+DELETE FROM article_files WHERE file_id IN (SELECT id FROM files WHERE digest IS NULL);
+INSERT INTO files_old SELECT * FROM files WHERE digest IS NOT NULL;
+DROP TABLE files;
+ALTER TABLE files_old RENAME TO files;
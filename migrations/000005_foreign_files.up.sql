@@ -0,0 +1,25 @@
+-- The digest of a file is only known once its content has been stored, which is not the case for the files of
+-- other servers, so it becomes optional. SQLite cannot alter a column, so the table is rebuilt.
+CREATE TABLE files_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    digest CHAR(64),
+    path VARCHAR(255),
+    ap_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255),
+    filename VARCHAR(255),
+    type VARCHAR(32) NOT NULL DEFAULT 'Document',
+    mime_type VARCHAR(128) NOT NULL,
+    size_bytes INTEGER,
+    local BOOLEAN DEFAULT FALSE NOT NULL,
+    uploaded_by INTEGER,
+    url VARCHAR(255) NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (digest),
+    UNIQUE (ap_id),
+    FOREIGN KEY (uploaded_by) REFERENCES users (id)
+);
+
+INSERT INTO files_new SELECT * FROM files;
+DROP TABLE files;
+ALTER TABLE files_new RENAME TO files;
@@ -0,0 +1,2 @@
+DROP INDEX follows_followee;
+DROP TABLE follows;
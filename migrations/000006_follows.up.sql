@@ -0,0 +1,17 @@
+-- Follow relationships between actors. The follower is always a known user, local or foreign, while the followee
+-- is referenced by its ActivityPub ID, since it may be any actor, not only a user.
+CREATE TABLE follows (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    -- ID of the Follow activity.
+    ap_id VARCHAR(255),
+    follower INTEGER NOT NULL,
+    followee VARCHAR(255) NOT NULL,
+    accepted BOOLEAN DEFAULT FALSE NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id),
+    UNIQUE (follower, followee),
+    FOREIGN KEY (follower) REFERENCES users (id)
+);
+
+CREATE INDEX follows_followee ON follows (followee, accepted);
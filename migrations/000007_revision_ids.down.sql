@@ -0,0 +1,3 @@
+UPDATE revisions
+SET ap_id = NULL
+WHERE article_id IN (SELECT a.id FROM articles a WHERE a.local);
@@ -0,0 +1,5 @@
+-- Local revisions used to be stored without an ActivityPub ID. They are now identified by the history path of
+-- their article, /a/{title}/history/{id}.
+UPDATE revisions
+SET ap_id = (SELECT a.ap_id FROM articles a WHERE a.id = revisions.article_id) || '/history/' || revisions.id
+WHERE ap_id IS NULL AND article_id IN (SELECT a.id FROM articles a WHERE a.local);
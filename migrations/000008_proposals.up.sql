@@ -0,0 +1,4 @@
+-- A proposal is a revision sent to the server that hosts the article for review, instead of being applied directly:
+-- either an edit of a foreign article by a local user, or an edit of a local article received from another server.
+-- It remains unpublished until reviewed, and is published only if accepted.
+ALTER TABLE revisions ADD COLUMN proposal BOOLEAN DEFAULT FALSE NOT NULL;
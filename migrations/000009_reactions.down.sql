@@ -0,0 +1,4 @@
+DROP INDEX announces_article;
+DROP INDEX likes_article;
+DROP TABLE announces;
+DROP TABLE likes;
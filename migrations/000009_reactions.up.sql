@@ -0,0 +1,30 @@
+-- Likes and Announces (boosts) of articles, received from other servers. An actor may like or announce an article
+-- only once; the ID of the activity is kept so its Undo can be matched.
+CREATE TABLE likes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ap_id VARCHAR(255) NOT NULL,
+    user_id INTEGER NOT NULL,
+    article_id INTEGER NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id),
+    UNIQUE (user_id, article_id),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (article_id) REFERENCES articles (id)
+);
+
+CREATE TABLE announces (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ap_id VARCHAR(255) NOT NULL,
+    user_id INTEGER NOT NULL,
+    article_id INTEGER NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id),
+    UNIQUE (user_id, article_id),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (article_id) REFERENCES articles (id)
+);
+
+CREATE INDEX likes_article ON likes (article_id);
+CREATE INDEX announces_article ON announces (article_id);
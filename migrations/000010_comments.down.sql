@@ -0,0 +1,2 @@
+DROP INDEX comments_article;
+DROP TABLE comments;
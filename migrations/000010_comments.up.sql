@@ -0,0 +1,24 @@
+-- Comments on the discussion page of an article: those posted by local users, and the Notes received from other
+-- servers in reply to the article or to one of its comments. Replies to the article itself have no in_reply_to.
+CREATE TABLE comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    -- The ID of a local comment is only known after it is inserted, so it is set right after.
+    ap_id VARCHAR(255),
+    url VARCHAR(255),
+    article_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    in_reply_to INTEGER,
+    -- The HTML content of the comment, sanitized only when rendered.
+    content TEXT NOT NULL,
+    local BOOLEAN DEFAULT FALSE NOT NULL,
+    -- Hidden comments are only shown to admins.
+    hidden BOOLEAN DEFAULT FALSE NOT NULL,
+    created INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id),
+    FOREIGN KEY (article_id) REFERENCES articles (id),
+    FOREIGN KEY (user_id) REFERENCES users (id),
+    FOREIGN KEY (in_reply_to) REFERENCES comments (id)
+);
+
+CREATE INDEX comments_article ON comments (article_id);
@@ -0,0 +1,2 @@
+ALTER TABLE articles DROP COLUMN private_key;
+ALTER TABLE articles DROP COLUMN public_key;
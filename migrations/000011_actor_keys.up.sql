@@ -0,0 +1,3 @@
+-- Local articles are actors that can be followed, and sign the activities they send with their own key.
+ALTER TABLE articles ADD COLUMN public_key TEXT;
+ALTER TABLE articles ADD COLUMN private_key TEXT;
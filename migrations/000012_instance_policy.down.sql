@@ -0,0 +1,2 @@
+DROP INDEX instances_hostname;
+ALTER TABLE instances DROP COLUMN policy;
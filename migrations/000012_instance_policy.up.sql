@@ -0,0 +1,8 @@
+-- The federation policy an admin chose for an instance: allow, reject_media, silence or suspend. Instances without
+-- a policy follow the default of the wiki, which depends on whether it federates only with an allowlist.
+ALTER TABLE instances ADD COLUMN policy VARCHAR(16);
+
+-- Hostnames are stored in lower case, so that a policy cannot be escaped by changing the case of a hostname.
+UPDATE instances SET hostname = LOWER(hostname)
+WHERE NOT EXISTS (SELECT 1 FROM instances i WHERE i.hostname = LOWER(instances.hostname) AND i.id <> instances.id);
+CREATE UNIQUE INDEX instances_hostname ON instances (hostname);
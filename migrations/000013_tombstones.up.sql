@@ -0,0 +1,10 @@
+-- Tombstones remember the IDs of deleted objects: local ones are answered with 410 Gone instead of 404, and the
+-- profiles of deleted foreign users are no longer shown.
+CREATE TABLE tombstones (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ap_id VARCHAR(255) NOT NULL,
+    former_type VARCHAR(32) NOT NULL,
+    deleted INT DEFAULT (cast(strftime('%s','now') as int)) NOT NULL,
+
+    UNIQUE (ap_id)
+);
@@ -0,0 +1,2 @@
+-- The ActivityPub ID of the article a local article was forked from, if any.
+ALTER TABLE articles ADD COLUMN forked_from VARCHAR;
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN previous_key_expires;
+ALTER TABLE users DROP COLUMN previous_public_key;
+ALTER TABLE users DROP COLUMN previous_key_id;
+ALTER TABLE users DROP COLUMN key_id;
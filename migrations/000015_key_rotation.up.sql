@@ -0,0 +1,6 @@
+-- The ID of the current key of a local user, NULL for the default #main-key, and the key replaced by the last
+-- rotation, which is still published until previous_key_expires.
+ALTER TABLE users ADD COLUMN key_id VARCHAR;
+ALTER TABLE users ADD COLUMN previous_key_id VARCHAR;
+ALTER TABLE users ADD COLUMN previous_public_key TEXT;
+ALTER TABLE users ADD COLUMN previous_key_expires INT;
@@ -0,0 +1,2 @@
+-- The inbox shared by the actors of a foreign server, to which activities for several of them may be posted.
+ALTER TABLE users ADD COLUMN shared_inbox VARCHAR;
@@ -0,0 +1,3 @@
+ALTER TABLE articles DROP COLUMN links_indexed;
+DROP INDEX article_links_target;
+DROP TABLE article_links;
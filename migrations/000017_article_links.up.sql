@@ -0,0 +1,16 @@
+-- The wiki links of local articles, extracted whenever an article is saved: the titles of the articles each one
+-- links to, which may not exist yet.
+CREATE TABLE article_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    article_id INTEGER NOT NULL,
+    target VARCHAR(255) NOT NULL,
+
+    UNIQUE (article_id, target),
+    FOREIGN KEY (article_id) REFERENCES articles (id)
+);
+
+CREATE INDEX article_links_target ON article_links (target);
+
+-- Whether the wiki links of a local article were stored, which they may have been even if it has none, so that
+-- the articles saved before links were stored are only indexed once.
+ALTER TABLE articles ADD COLUMN links_indexed BOOLEAN DEFAULT FALSE NOT NULL;
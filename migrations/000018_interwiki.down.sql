@@ -0,0 +1,2 @@
+DROP INDEX instances_interwiki_prefix;
+ALTER TABLE instances DROP COLUMN interwiki_prefix;
@@ -0,0 +1,4 @@
+-- The interwiki prefix an admin chose for an instance, so that its articles may be linked to as [[prefix:Title]].
+ALTER TABLE instances ADD COLUMN interwiki_prefix VARCHAR(32);
+
+CREATE UNIQUE INDEX instances_interwiki_prefix ON instances (interwiki_prefix);